@@ -0,0 +1,296 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/history"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+var certsCommand = &command{
+	name:     "certs",
+	args:     "<report-file|history-id>...",
+	synopsis: "Inventory the TLS certificates in saved scan reports, grouped by certificate and key",
+	setup:    setupCerts,
+}
+
+// certGroup is one certificate and every endpoint that presented it
+type certGroup struct {
+	Fingerprint   string    `json:"fingerprint"`
+	PublicKeyHash string    `json:"public_key_hash,omitempty"`
+	Subject       string    `json:"subject"`
+	Issuer        string    `json:"issuer"`
+	DNSNames      []string  `json:"dns_names,omitempty"`
+	ValidTo       time.Time `json:"valid_to"`
+	DaysLeft      int       `json:"days_left"`
+	Validation    string    `json:"validation,omitempty"`
+	Endpoints     []string  `json:"endpoints"`
+}
+
+// keyGroup is a public key shared by several certificates
+type keyGroup struct {
+	PublicKeyHash string   `json:"public_key_hash"`
+	Certificates  []string `json:"certificates"` // fingerprints
+	Endpoints     []string `json:"endpoints"`
+}
+
+// issuerCount counts the certificates signed by an issuer
+type issuerCount struct {
+	Issuer       string `json:"issuer"`
+	Certificates int    `json:"certificates"`
+}
+
+// certInventory is the certs report
+type certInventory struct {
+	Certificates []*certGroup  `json:"certificates"`
+	SharedKeys   []*keyGroup   `json:"shared_keys"`
+	Issuers      []issuerCount `json:"issuers"`
+}
+
+func setupCerts(fs *flag.FlagSet) func(args []string) error {
+	historyDir := fs.String("history-dir", history.DefaultDir(), "Directory holding saved scan reports")
+	all := fs.Bool("all", false, "Use the latest saved report of every host instead of naming reports")
+	jsonOutput := fs.Bool("json", false, "Print the inventory as JSON")
+	pemDir := fs.String("pem-dir", "", "Write every collected certificate, including chains, as PEM into this directory")
+
+	return func(args []string) error {
+		if len(args) == 0 && !*all {
+			return usagef("expected at least one report, or -all")
+		}
+
+		store := history.NewStore(*historyDir)
+		reports, err := loadCertReports(store, args, *all)
+		if err != nil {
+			return err
+		}
+
+		inventory := buildInventory(reports, time.Now())
+
+		if *pemDir != "" {
+			written, err := writePEMs(*pemDir, reports)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Wrote %d certificates to %s\n", written, *pemDir)
+		}
+
+		if *jsonOutput {
+			return printJSON(inventory)
+		}
+		printInventory(inventory)
+		return nil
+	}
+}
+
+// loadCertReports loads the named reports, or the latest of every host
+func loadCertReports(store *history.Store, refs []string, all bool) ([]*models.ScanReport, error) {
+	var reports []*models.ScanReport
+	for _, ref := range refs {
+		report, err := loadReport(store, ref)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	if all {
+		entries, err := store.List()
+		if err != nil {
+			return nil, err
+		}
+		// Entries are oldest first, so later ones replace earlier ones
+		latest := make(map[string]history.Entry)
+		for _, entry := range entries {
+			latest[entry.Host] = entry
+		}
+		hosts := make([]string, 0, len(latest))
+		for host := range latest {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		for _, host := range hosts {
+			report, err := history.ReadReport(latest[host].Path)
+			if err != nil {
+				return nil, err
+			}
+			reports = append(reports, report)
+		}
+	}
+
+	return reports, nil
+}
+
+// buildInventory groups the certificates of the reports by fingerprint and
+// public key, sorted by days left until expiry
+func buildInventory(reports []*models.ScanReport, now time.Time) *certInventory {
+	byFingerprint := make(map[string]*certGroup)
+	for _, report := range reports {
+		for _, result := range report.OpenResults() {
+			info := result.SSLInfo
+			if info == nil {
+				continue
+			}
+			endpoint := net.JoinHostPort(result.Host, strconv.Itoa(result.Port))
+
+			group, ok := byFingerprint[info.Fingerprint]
+			if !ok {
+				group = &certGroup{
+					Fingerprint:   info.Fingerprint,
+					PublicKeyHash: info.PublicKeyHash,
+					Subject:       info.Subject,
+					Issuer:        info.Issuer,
+					DNSNames:      info.DNSNames,
+					ValidTo:       info.ValidTo,
+					DaysLeft:      int(info.ValidTo.Sub(now).Hours() / 24),
+				}
+				if info.Validation != nil {
+					group.Validation = info.Validation.Verdict
+				}
+				byFingerprint[info.Fingerprint] = group
+			}
+			if !containsString(group.Endpoints, endpoint) {
+				group.Endpoints = append(group.Endpoints, endpoint)
+			}
+		}
+	}
+
+	inventory := &certInventory{Certificates: []*certGroup{}, SharedKeys: []*keyGroup{}, Issuers: []issuerCount{}}
+	for _, group := range byFingerprint {
+		sort.Strings(group.Endpoints)
+		inventory.Certificates = append(inventory.Certificates, group)
+	}
+	sort.Slice(inventory.Certificates, func(i, j int) bool {
+		a, b := inventory.Certificates[i], inventory.Certificates[j]
+		if !a.ValidTo.Equal(b.ValidTo) {
+			return a.ValidTo.Before(b.ValidTo)
+		}
+		return a.Fingerprint < b.Fingerprint
+	})
+
+	byKey := make(map[string]*keyGroup)
+	issuers := make(map[string]int)
+	for _, cert := range inventory.Certificates {
+		issuers[cert.Issuer]++
+		if cert.PublicKeyHash == "" {
+			continue
+		}
+		group, ok := byKey[cert.PublicKeyHash]
+		if !ok {
+			group = &keyGroup{PublicKeyHash: cert.PublicKeyHash}
+			byKey[cert.PublicKeyHash] = group
+		}
+		group.Certificates = append(group.Certificates, cert.Fingerprint)
+		group.Endpoints = append(group.Endpoints, cert.Endpoints...)
+	}
+
+	for _, group := range byKey {
+		if len(group.Certificates) > 1 {
+			sort.Strings(group.Endpoints)
+			inventory.SharedKeys = append(inventory.SharedKeys, group)
+		}
+	}
+	sort.Slice(inventory.SharedKeys, func(i, j int) bool {
+		return inventory.SharedKeys[i].PublicKeyHash < inventory.SharedKeys[j].PublicKeyHash
+	})
+
+	for issuer, count := range issuers {
+		inventory.Issuers = append(inventory.Issuers, issuerCount{Issuer: issuer, Certificates: count})
+	}
+	sort.Slice(inventory.Issuers, func(i, j int) bool {
+		a, b := inventory.Issuers[i], inventory.Issuers[j]
+		if a.Certificates != b.Certificates {
+			return a.Certificates > b.Certificates
+		}
+		return a.Issuer < b.Issuer
+	})
+
+	return inventory
+}
+
+// printInventory prints the inventory as text
+func printInventory(inventory *certInventory) {
+	fmt.Printf("CERTIFICATES (%d, soonest expiry first)\n", len(inventory.Certificates))
+	for _, cert := range inventory.Certificates {
+		fmt.Printf("\n%s\n", cert.Subject)
+		fmt.Printf("  Issuer      : %s\n", cert.Issuer)
+		fmt.Printf("  Expires     : %s (%d days)\n", cert.ValidTo.Format("2006-01-02"), cert.DaysLeft)
+		fmt.Printf("  Fingerprint : %s\n", cert.Fingerprint)
+		if cert.PublicKeyHash != "" {
+			fmt.Printf("  Key SHA-256 : %s\n", cert.PublicKeyHash)
+		}
+		if cert.Validation != "" {
+			fmt.Printf("  Validation  : %s\n", cert.Validation)
+		}
+		fmt.Printf("  Endpoints   : %s\n", strings.Join(cert.Endpoints, ", "))
+	}
+
+	fmt.Printf("\nSHARED KEYS (%d)\n", len(inventory.SharedKeys))
+	for _, key := range inventory.SharedKeys {
+		fmt.Printf("\n%s\n", key.PublicKeyHash)
+		fmt.Printf("  Certificates: %s\n", strings.Join(key.Certificates, ", "))
+		fmt.Printf("  Endpoints   : %s\n", strings.Join(key.Endpoints, ", "))
+	}
+
+	fmt.Printf("\nISSUERS (%d)\n", len(inventory.Issuers))
+	for _, issuer := range inventory.Issuers {
+		fmt.Printf("  %4d  %s\n", issuer.Certificates, issuer.Issuer)
+	}
+}
+
+// writePEMs writes every leaf and chain certificate in the reports to dir,
+// one file per certificate named by the SHA-256 of its DER bytes. The
+// fingerprint recorded in a report is not trusted for the name, since a
+// report file can say anything. Reports saved before certificates were
+// recorded in full are skipped.
+func writePEMs(dir string, reports []*models.ScanReport) (int, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, err
+	}
+
+	written := make(map[string]bool)
+	for _, report := range reports {
+		for _, result := range report.OpenResults() {
+			if result.SSLInfo == nil {
+				continue
+			}
+			for _, cert := range result.SSLInfo.Chain {
+				if len(cert.Raw) == 0 {
+					continue
+				}
+				hash := sha256.Sum256(cert.Raw)
+				fingerprint := hex.EncodeToString(hash[:])
+				if written[fingerprint] {
+					continue
+				}
+				data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+				path := filepath.Join(dir, fingerprint+".pem")
+				if err := os.WriteFile(path, data, 0o644); err != nil {
+					return len(written), err
+				}
+				written[fingerprint] = true
+			}
+		}
+	}
+	return len(written), nil
+}
+
+// containsString reports whether list holds s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// certReports are two hosts' reports. Certificates aa and bb share key k1
+// and an intermediate; cc was saved without its DER bytes.
+func certReports() []*models.ScanReport {
+	december := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	march := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	intermediate := models.ChainCert{Subject: "CN=R3", Raw: []byte("intermediate R3")}
+	certA := &models.SSLCertInfo{
+		Subject: "CN=example.com", Issuer: "CN=R3", DNSNames: []string{"example.com"}, ValidTo: march,
+		Fingerprint: "aa", PublicKeyHash: "k1",
+		Validation: &models.CertValidation{Trusted: true, Verdict: models.CertVerdictOK},
+		Chain:      []models.ChainCert{{Subject: "CN=example.com", Raw: []byte("leaf aa")}, intermediate},
+	}
+	certB := &models.SSLCertInfo{
+		Subject: "CN=mail.example.com", Issuer: "CN=R3", ValidTo: december, Fingerprint: "bb", PublicKeyHash: "k1",
+		Chain: []models.ChainCert{{Subject: "CN=mail.example.com", Raw: []byte("leaf bb")}, intermediate},
+	}
+	certC := &models.SSLCertInfo{
+		Subject: "CN=smtp", Issuer: "CN=smtp", ValidTo: march, Fingerprint: "cc", PublicKeyHash: "k2",
+		Chain: []models.ChainCert{{Subject: "CN=smtp"}},
+	}
+
+	return []*models.ScanReport{
+		{Host: "example.com", Results: []models.ScanResult{
+			{Host: "example.com", Port: 22, Status: "open"},
+			{Host: "example.com", Port: 443, Status: "open", SSLInfo: certA},
+			{Host: "example.com", Port: 8443, Status: "open", SSLInfo: certA},
+			{Host: "example.com", Port: 9443, Status: "closed", SSLInfo: certC},
+		}},
+		{Host: "mail.example.com", Results: []models.ScanResult{
+			{Host: "mail.example.com", Port: 993, Status: "open", SSLInfo: certB},
+			{Host: "mail.example.com", Port: 25, Status: "open", SSLInfo: certC},
+			{Host: "mail.example.com", Port: 443, Status: "open", SSLInfo: certA},
+		}},
+	}
+}
+
+func TestBuildInventory(t *testing.T) {
+	reports := certReports()
+	// A report named twice adds no endpoints
+	reports = append(reports, reports[0])
+	inventory := buildInventory(reports, time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC))
+
+	want := &certInventory{
+		Certificates: []*certGroup{
+			{
+				Fingerprint: "bb", PublicKeyHash: "k1", Subject: "CN=mail.example.com", Issuer: "CN=R3",
+				ValidTo: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), DaysLeft: 30,
+				Endpoints: []string{"mail.example.com:993"},
+			},
+			{
+				Fingerprint: "aa", PublicKeyHash: "k1", Subject: "CN=example.com", Issuer: "CN=R3",
+				DNSNames: []string{"example.com"}, ValidTo: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), DaysLeft: 120,
+				Validation: models.CertVerdictOK,
+				Endpoints:  []string{"example.com:443", "example.com:8443", "mail.example.com:443"},
+			},
+			{
+				Fingerprint: "cc", PublicKeyHash: "k2", Subject: "CN=smtp", Issuer: "CN=smtp",
+				ValidTo: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), DaysLeft: 120,
+				Endpoints: []string{"mail.example.com:25"},
+			},
+		},
+		SharedKeys: []*keyGroup{{
+			PublicKeyHash: "k1",
+			Certificates:  []string{"bb", "aa"},
+			Endpoints:     []string{"example.com:443", "example.com:8443", "mail.example.com:443", "mail.example.com:993"},
+		}},
+		Issuers: []issuerCount{{Issuer: "CN=R3", Certificates: 2}, {Issuer: "CN=smtp", Certificates: 1}},
+	}
+	if !reflect.DeepEqual(inventory, want) {
+		t.Errorf("got %s\nwant %s", jsonString(t, inventory), jsonString(t, want))
+	}
+
+	empty := buildInventory(nil, time.Now())
+	if empty.Certificates == nil || empty.SharedKeys == nil || empty.Issuers == nil {
+		t.Errorf("empty inventory has nil lists, printed as null: %+v", empty)
+	}
+}
+
+func TestWritePEMs(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "pem")
+	written, err := writePEMs(dir, certReports())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Files are named by the hash of the DER bytes; the intermediate
+	// appears once and the certificate without DER bytes not at all
+	var want []string
+	for _, der := range []string{"leaf aa", "leaf bb", "intermediate R3"} {
+		hash := sha256.Sum256([]byte(der))
+		want = append(want, hex.EncodeToString(hash[:])+".pem")
+		data, err := os.ReadFile(filepath.Join(dir, want[len(want)-1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if block, _ := pem.Decode(data); block == nil || block.Type != "CERTIFICATE" || string(block.Bytes) != der {
+			t.Errorf("%s: got %q", der, data)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	sort.Strings(want)
+	if written != 3 || !reflect.DeepEqual(got, want) {
+		t.Errorf("wrote %d: %v, want %v", written, got, want)
+	}
+
+	// The directory cannot be created under a file
+	if _, err := writePEMs(filepath.Join(dir, want[0], "pem"), certReports()); err == nil {
+		t.Error("writing under a file succeeded")
+	}
+}
+
+// jsonString renders v as in the -json output
+func jsonString(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/Sh4Ryuu/go-scan/internal/diff"
+	"github.com/Sh4Ryuu/go-scan/internal/history"
+	"github.com/Sh4Ryuu/go-scan/internal/output"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+var diffCommand = &command{
+	name:     "diff",
+	args:     "<old-report> <new-report>",
+	synopsis: "Compare two scan reports and list what changed",
+	setup:    setupDiff,
+}
+
+func setupDiff(fs *flag.FlagSet) func(args []string) error {
+	historyDir := fs.String("history-dir", history.DefaultDir(), "Directory holding saved scan reports")
+	host := fs.String("host", "", "Compare the two most recent history entries for this host")
+	jsonOutput := fs.Bool("json", false, "Print the changes as JSON")
+
+	return func(args []string) error {
+		store := history.NewStore(*historyDir)
+
+		var before, after *models.ScanReport
+		var err error
+
+		switch {
+		case *host != "" && len(args) == 0:
+			before, after, err = latestPair(store, *host)
+		case *host == "" && len(args) == 2:
+			if before, err = loadReport(store, args[0]); err == nil {
+				after, err = loadReport(store, args[1])
+			}
+		default:
+			return usagef("expected two reports or -host")
+		}
+		if err != nil {
+			return err
+		}
+
+		changes := diff.Compare(before, after)
+		if *jsonOutput {
+			if changes == nil {
+				changes = []diff.Change{}
+			}
+			return printJSON(changes)
+		}
+
+		if len(changes) == 0 {
+			fmt.Printf("%s No changes\n", output.SymInfo)
+			return nil
+		}
+		for _, change := range changes {
+			fmt.Printf("%s %-12s %s\n", changeSymbol(change.Kind), change.Kind, change.Message)
+		}
+		return nil
+	}
+}
+
+// latestPair returns the two most recent history reports for a host
+func latestPair(store *history.Store, host string) (*models.ScanReport, *models.ScanReport, error) {
+	entries, err := store.List()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if entry.Host == host {
+			paths = append(paths, entry.Path)
+		}
+	}
+	if len(paths) < 2 {
+		return nil, nil, fmt.Errorf("need at least two saved reports for %s, found %d", host, len(paths))
+	}
+
+	before, err := history.ReadReport(paths[len(paths)-2])
+	if err != nil {
+		return nil, nil, err
+	}
+	after, err := history.ReadReport(paths[len(paths)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+// changeSymbol picks the output symbol for a change kind
+func changeSymbol(kind string) string {
+	switch kind {
+	case diff.KindNewPort:
+		return output.ColorGreen + output.SymCheck + output.ColorReset
+	case diff.KindClosedPort:
+		return output.ColorRed + output.SymCross + output.ColorReset
+	default:
+		return output.ColorYellow + output.SymWarning + output.ColorReset
+	}
+}
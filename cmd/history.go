@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/Sh4Ryuu/go-scan/internal/history"
+)
+
+var historyCommand = &command{
+	name:     "history",
+	synopsis: "List scan reports saved with 'scan -save'",
+	setup:    setupHistory,
+}
+
+func setupHistory(fs *flag.FlagSet) func(args []string) error {
+	historyDir := fs.String("history-dir", history.DefaultDir(), "Directory holding saved scan reports")
+	host := fs.String("host", "", "Only list reports for this host")
+	remove := fs.String("rm", "", "Delete the history entry with this ID")
+	jsonOutput := fs.Bool("json", false, "Print the entries as JSON")
+
+	return func(args []string) error {
+		if len(args) > 0 {
+			return usagef("unexpected arguments: %v", args)
+		}
+
+		store := history.NewStore(*historyDir)
+
+		if *remove != "" {
+			return store.Delete(*remove)
+		}
+
+		entries, err := store.List()
+		if err != nil {
+			return err
+		}
+
+		filtered := []history.Entry{}
+		for _, entry := range entries {
+			if *host == "" || entry.Host == *host {
+				filtered = append(filtered, entry)
+			}
+		}
+
+		if *jsonOutput {
+			return printJSON(filtered)
+		}
+
+		if len(filtered) == 0 {
+			fmt.Printf("No saved reports in %s\n", store.Dir)
+			return nil
+		}
+		fmt.Printf("%-40s %-25s %-20s %s\n", "ID", "HOST", "STARTED", "OPEN")
+		for _, entry := range filtered {
+			fmt.Printf("%-40s %-25s %-20s %d\n", entry.ID, entry.Host, entry.StartTime.Format("2006-01-02 15:04:05"), entry.OpenPorts)
+		}
+		return nil
+	}
+}
@@ -1,293 +1,183 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+)
 
-	"github.com/Sh4Ryuu/go-scan/internal/nmap"
-	"github.com/Sh4Ryuu/go-scan/internal/output"
-	"github.com/Sh4Ryuu/go-scan/internal/scanner"
+// Exit codes shared by all subcommands
+const (
+	exitOK    = 0 // command completed successfully
+	exitError = 1 // command failed at runtime
+	exitUsage = 2 // invalid flags or arguments
 )
 
-func main() {
-	config := &scanner.Config{}
+// command describes a go-scan subcommand
+type command struct {
+	name     string
+	args     string
+	synopsis string
+	// setup registers the command's flags and returns the function that runs it
+	setup func(fs *flag.FlagSet) func(args []string) error
+}
 
-	// Define command-line flags
-	flag.StringVar(&config.Host, "host", "scanme.nmap.org", "Target host to scan")
-	flag.IntVar(&config.StartPort, "start", 1, "Starting port number")
-	flag.IntVar(&config.EndPort, "end", 1024, "Ending port number")
-	flag.IntVar(&config.MaxWorkers, "workers", 100, "Number of concurrent workers")
-	flag.BoolVar(&config.Verbose, "verbose", false, "Enable verbose output")
-	flag.BoolVar(&config.Quiet, "quiet", false, "Quiet mode - only show open ports")
-	flag.BoolVar(&config.JSONOutput, "json", false, "Output results as JSON")
-	flag.BoolVar(&config.BannerGrabbing, "banners", true, "Enable banner grabbing")
-	flag.BoolVar(&config.EnableSSL, "ssl", true, "Enable SSL/TLS certificate grabbing")
-	flag.BoolVar(&config.EnableUDP, "udp", false, "Enable UDP scanning")
-	flag.BoolVar(&config.EnableGeolocation, "geo", true, "Enable geolocation lookup")
-	flag.StringVar(&config.Profile, "profile", "default", "Scanning profile (aggressive, default, conservative)")
-	flag.StringVar(&config.NmapScripts, "nmap", "", "Nmap scripts to run (comma-separated, e.g., 'ssh-hostkey,ssl-cert')")
-	flag.IntVar(&config.RateLimitMs, "rate-limit", 10, "Rate limit in milliseconds")
+// usageError marks errors caused by invalid command-line usage
+type usageError struct {
+	msg string
+}
 
-	timeout := flag.Int("timeout", 1, "Connection timeout in seconds")
-	help := flag.Bool("help", false, "Show help message")
-	showProfiles := flag.Bool("profiles", false, "Show available profiles")
-	showNmapScripts := flag.Bool("nmap-help", false, "Show available Nmap scripts")
+func (e *usageError) Error() string {
+	return e.msg
+}
 
-	flag.Parse()
+// usagef returns a usage error with a formatted message
+func usagef(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
 
-	if *help {
-		printHelp()
-		return
-	}
+var commands = []*command{
+	scanCommand,
+	diffCommand,
+	reportCommand,
+	certsCommand,
+	serveCommand,
+	monitorCommand,
+	historyCommand,
+	profilesCommand,
+}
 
-	if *showProfiles {
-		printProfiles()
-		return
-	}
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
 
-	if *showNmapScripts {
-		printNmapScripts()
-		return
+// run dispatches to a subcommand and returns the process exit code
+func run(args []string) int {
+	if len(args) == 0 {
+		printUsage(os.Stderr)
+		return exitUsage
 	}
 
-	// Apply timeout
-	config.TimeoutSeconds = *timeout
-
-	// Validate configuration
-	if err := config.Validate(); err != nil {
-		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
-		os.Exit(1)
+	name := args[0]
+	switch {
+	case name == "help" || name == "-h" || name == "-help" || name == "--help":
+		return runHelp(args[1:])
+	case strings.HasPrefix(name, "-"):
+		// Flags without a subcommand keep working as a plain scan
+		return runCommand(scanCommand, args)
 	}
 
-	// Create formatter config from scanner config
-	formatterConfig := &output.FormatterConfig{
-		Quiet:             config.Quiet,
-		Verbose:           config.Verbose,
-		JSONOutput:        config.JSONOutput,
-		StartPort:         config.StartPort,
-		EndPort:           config.EndPort,
-		Host:              config.Host,
-		MaxWorkers:        config.MaxWorkers,
-		TimeoutSeconds:    config.TimeoutSeconds,
-		Profile:           config.Profile,
-		BannerGrabbing:    config.BannerGrabbing,
-		EnableSSL:         config.EnableSSL,
-		EnableUDP:         config.EnableUDP,
-		EnableGeolocation: config.EnableGeolocation,
-		NmapScripts:       config.NmapScripts,
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "go-scan: unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		return exitUsage
 	}
 
-	// Initialize output formatter
-	formatter := output.NewFormatter(formatterConfig)
-
-	// Print banner
-	formatter.PrintBanner()
-
-	// Print configuration info
-	formatter.PrintConfigInfo()
+	return runCommand(cmd, args[1:])
+}
 
-	// Create and run scanner
-	portScanner := scanner.NewPortScanner(config, formatter)
+// runCommand parses flags for a command and executes it
+func runCommand(cmd *command, args []string) int {
+	fs := newFlagSet(cmd)
+	exec := cmd.setup(fs)
 
-	// Run the scan
-	results, stats, err := portScanner.Scan()
-	if err != nil {
-		formatter.PrintError(fmt.Sprintf("Scan error: %v", err))
-		os.Exit(1)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
 	}
 
-	// Print results
-	if results != nil {
-		for _, result := range results {
-			formatter.PrintResults(&result)
+	if err := exec(fs.Args()); err != nil {
+		var uerr *usageError
+		if errors.As(err, &uerr) {
+			fmt.Fprintf(os.Stderr, "go-scan %s: %v\n\n", cmd.name, err)
+			fs.Usage()
+			return exitUsage
 		}
+		fmt.Fprintf(os.Stderr, "go-scan %s: %v\n", cmd.name, err)
+		return exitError
 	}
 
-	// Print statistics
-	formatter.PrintStatistics(stats)
+	return exitOK
 }
 
-func printHelp() {
-	fmt.Println(`
-GoScan - Advanced Port Scanner
-
-USAGE:
-  go-scan [OPTIONS]
-
-OPTIONS:
-  -host string              Target host to scan (default: scanme.nmap.org)
-  -start int                Starting port number (default: 1)
-  -end int                  Ending port number (default: 1024)
-  -workers int              Number of concurrent workers (default: 100)
-  -timeout int              Connection timeout in seconds (default: 1)
-  -profile string           Scanning profile: aggressive, default, conservative
-  -banners bool             Enable banner grabbing (default: true)
-  -ssl bool                 Enable SSL/TLS certificate grabbing (default: true)
-  -udp bool                 Enable UDP scanning (default: false)
-  -geo bool                 Enable geolocation lookup (default: true)
-  -nmap string              Nmap scripts to run (comma-separated)
-  -rate-limit int           Rate limit in milliseconds (default: 10)
-  -verbose bool             Enable verbose output (default: false)
-  -quiet bool               Quiet mode - only show open ports (default: false)
-  -json bool                Output results as JSON (default: false)
-  -help                     Show this help message
-  -profiles                 Show available scanning profiles
-  -nmap-help                Show available Nmap scripts
-
-EXAMPLES:
-  go-scan -host example.com
-  go-scan -host example.com -nmap-help
-  go-scan -host scanme.nmap.org -start 1 -end 100 -nmap ssh-hostkey,ssl-cert
-`)
-}
-
-func printProfiles() {
-	fmt.Println(`
-AVAILABLE SCANNING PROFILES:
-
-1. AGGRESSIVE
-   Workers: 500, Timeout: 500ms, Rate Limit: 0ms
-   Use Case: Fast scanning of trusted networks
-
-2. DEFAULT
-   Workers: 100, Timeout: 1s, Rate Limit: 10ms
-   Use Case: Balanced speed and reliability
+// runHelp prints general help or the help of a single command
+func runHelp(args []string) int {
+	if len(args) == 0 {
+		printUsage(os.Stdout)
+		return exitOK
+	}
 
-3. CONSERVATIVE
-   Workers: 50, Timeout: 3s, Rate Limit: 50ms
-   Use Case: Slower but safer scanning
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "go-scan: unknown help topic %q\n", args[0])
+		return exitUsage
+	}
 
-USE: go-scan -host example.com -profile aggressive
-`)
+	fs := newFlagSet(cmd)
+	cmd.setup(fs)
+	fs.SetOutput(os.Stdout)
+	fs.Usage()
+	return exitOK
 }
 
-func printNmapScripts() {
-	fmt.Println(`
-
-                      AVAILABLE NMAP SCRIPTS                              
-
-
-The following Nmap NSE scripts are available for advanced reconnaissance:
-`)
-
-	scripts := nmap.ListAvailableScripts()
-
-	fmt.Println()
-	fmt.Println("SERVICE DETECTION & INFORMATION:")
-	fmt.Println("")
-	serviceScripts := []string{"ssh-hostkey", "mysql-info", "mongodb-info", "redis-info", "banner"}
-	for _, script := range serviceScripts {
-		if desc, ok := scripts[script]; ok {
-			fmt.Printf("  %-20s | %s\n", script, desc)
-		}
-	}
-
-	fmt.Println()
-	fmt.Println("SSL/TLS ANALYSIS:")
-	fmt.Println("")
-	sslScripts := []string{"ssl-cert", "ssl-enum-ciphers"}
-	for _, script := range sslScripts {
-		if desc, ok := scripts[script]; ok {
-			fmt.Printf("  %-20s | %s\n", script, desc)
+// newFlagSet creates a flag set whose usage text is generated from its flags
+func newFlagSet(cmd *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: %s\n\n", strings.TrimSpace("go-scan "+cmd.name+" [flags] "+cmd.args))
+		fmt.Fprintf(out, "%s\n", cmd.synopsis)
+		if hasFlags(fs) {
+			fmt.Fprintf(out, "\nFlags:\n")
+			fs.PrintDefaults()
 		}
 	}
+	return fs
+}
 
-	fmt.Println()
-	fmt.Println("HTTP RECONNAISSANCE:")
-	fmt.Println("")
-	httpScripts := []string{"http-title", "http-methods"}
-	for _, script := range httpScripts {
-		if desc, ok := scripts[script]; ok {
-			fmt.Printf("  %-20s | %s\n", script, desc)
-		}
-	}
+// hasFlags reports whether any flags are registered on fs
+func hasFlags(fs *flag.FlagSet) bool {
+	found := false
+	fs.VisitAll(func(*flag.Flag) { found = true })
+	return found
+}
 
-	fmt.Println()
-	fmt.Println("SMB/WINDOWS ENUMERATION:")
-	fmt.Println("")
-	smbScripts := []string{"smb-enum-shares", "smb-os-discovery"}
-	for _, script := range smbScripts {
-		if desc, ok := scripts[script]; ok {
-			fmt.Printf("  %-20s | %s\n", script, desc)
+// findCommand looks up a command by name
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
 		}
 	}
+	return nil
+}
 
-	fmt.Println()
-	fmt.Println("FTP RECONNAISSANCE:")
-	fmt.Println("")
-	ftpScripts := []string{"ftp-anon"}
-	for _, script := range ftpScripts {
-		if desc, ok := scripts[script]; ok {
-			fmt.Printf("  %-20s | %s\n", script, desc)
-		}
+// printUsage prints the list of available commands
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "GoScan - Advanced Port Scanner\n\n")
+	fmt.Fprintf(w, "Usage: go-scan <command> [flags] [arguments]\n\n")
+	fmt.Fprintf(w, "Commands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.synopsis)
 	}
+	fmt.Fprintf(w, "\nRun 'go-scan help <command>' for the flags of a command.\n")
+	fmt.Fprintf(w, "Exit codes: %d success, %d runtime error, %d usage error.\n", exitOK, exitError, exitUsage)
+}
 
-	fmt.Println(`
-
-
-
-USAGE EXAMPLES:
-
-Single script:
-  go-scan -host example.com -end 1000 -nmap ssh-hostkey
-
-Multiple scripts (comma-separated):
-  go-scan -host example.com -end 1000 -nmap ssh-hostkey,ssl-cert,http-title
-
-Combine with profiles:
-  go-scan -host example.com -profile aggressive -nmap ssl-enum-ciphers
-
-With specific port range:
-  go-scan -host scanme.nmap.org -start 20 -end 100 -nmap ssl-cert,http-title
-
-
-
-SCRIPT DESCRIPTIONS:
-
-  ssh-hostkey        - SSH host key fingerprinting (good for server identification)
-  ssl-cert           - Extract SSL/TLS certificate info (use -ssl flag instead)
-  ssl-enum-ciphers   - List supported SSL/TLS ciphers and check vulnerabilities
-  http-title         - Grab HTTP page title and server version
-  http-methods       - Check supported HTTP methods (GET, POST, DELETE, etc.)
-  smb-enum-shares    - List available SMB network shares on Windows
-  smb-os-discovery   - Detect operating system from SMB service
-  mysql-info         - Get MySQL server version and configuration info
-  mongodb-info       - Extract MongoDB server information
-  redis-info         - Get Redis server configuration and stats
-  ftp-anon           - Test for anonymous FTP access
-  banner             - Generic service banner grabbing
-
-
-
-RECOMMENDED SCRIPTS TO TEST:
-
-For SSH Servers:
-  go-scan -host target.com -start 22 -end 22 -nmap ssh-hostkey
-
-For HTTPS/SSL Services:
-  go-scan -host target.com -start 443 -end 443 -nmap ssl-cert,ssl-enum-ciphers
-
-For HTTP Services:
-  go-scan -host target.com -start 80 -end 80 -nmap http-title,http-methods
-
-For Windows Servers (SMB):
-  go-scan -host target.com -start 445 -end 445 -nmap smb-enum-shares,smb-os-discovery
-
-For Databases:
-  go-scan -host target.com -start 3306 -end 3306 -nmap mysql-info
-  go-scan -host target.com -start 27017 -end 27017 -nmap mongodb-info
-  go-scan -host target.com -start 6379 -end 6379 -nmap redis-info
-
-
+// stringList is a flag that may be repeated to collect several values
+type stringList []string
 
-TIPS:
- Use on known-good targets to learn about services
- Requires nmap to be installed on your system
- Combine with aggressive profile for faster scanning
- Results depend on target configuration and firewall rules
- Always obtain authorization before testing!
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
 
-Note: Use 'go-scan -nmap-help' anytime to see this information.
-`)
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
 }
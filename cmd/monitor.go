@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/history"
+	"github.com/Sh4Ryuu/go-scan/internal/metrics"
+	"github.com/Sh4Ryuu/go-scan/internal/monitor"
+	"github.com/Sh4Ryuu/go-scan/internal/notify"
+	"github.com/Sh4Ryuu/go-scan/internal/scanner"
+)
+
+var monitorCommand = &command{
+	name:     "monitor",
+	synopsis: "Rescan targets on a schedule and alert on changes",
+	setup:    setupMonitor,
+}
+
+func setupMonitor(fs *flag.FlagSet) func(args []string) error {
+	configFile := fs.String("config", "", "Monitor configuration file (JSON); replaces the target flags below")
+	scan := scanner.DefaultConfig()
+	hosts := fs.String("host", "", "Comma-separated hosts to monitor")
+	fs.IntVar(&scan.StartPort, "start", scan.StartPort, "Starting port number")
+	fs.IntVar(&scan.EndPort, "end", scan.EndPort, "Ending port number")
+	fs.StringVar(&scan.Profile, "profile", scan.Profile, "Scanning profile (aggressive, default, conservative)")
+	fs.BoolVar(&scan.EnableGeolocation, "geo", false, "Enable geolocation lookup")
+	sched := fs.String("schedule", "1h", "Interval (e.g. 30m, '@every 1h') or cron expression (e.g. '*/15 * * * *')")
+
+	stdout := fs.Bool("stdout", true, "Print alerts to stdout")
+	alertFile := fs.String("alert-file", "", "Append alerts as JSON lines to this file")
+	var webhooks, slackURLs stringList
+	fs.Var(&webhooks, "webhook", "POST alerts as JSON to this URL (repeatable)")
+	fs.Var(&slackURLs, "slack", "Send alerts to this Slack incoming webhook URL (repeatable)")
+	smtpAddr := fs.String("smtp", "", "Email alerts through this SMTP server (host:port)")
+	mailFrom := fs.String("mail-from", "go-scan@localhost", "Sender address for email alerts")
+	mailTo := fs.String("mail-to", "", "Comma-separated recipients for email alerts")
+	minSeverity := fs.String("min-severity", "", "Only send alerts at or above this severity (info, low, medium, high, critical)")
+
+	historyDir := fs.String("history-dir", history.DefaultDir(), "Directory holding saved scan reports")
+	save := fs.Bool("save", true, "Save every run to the history directory so restarts keep their baseline")
+	metricsAddr := fs.String("metrics-addr", "", "Serve Prometheus metrics on this address")
+	once := fs.Bool("once", false, "Run every target set once and exit")
+
+	return func(args []string) error {
+		if len(args) > 0 {
+			return usagef("unexpected arguments: %v", args)
+		}
+
+		var config *monitor.Config
+		if *configFile != "" {
+			var err error
+			if config, err = monitor.LoadConfig(*configFile); err != nil {
+				return err
+			}
+		} else {
+			if *hosts == "" {
+				return usagef("either -config or -host is required")
+			}
+			config = &monitor.Config{
+				Targets: []*monitor.TargetSet{{
+					Name:     "default",
+					Hosts:    splitList(*hosts),
+					Schedule: *sched,
+					Scan:     scan,
+				}},
+			}
+			config.Alerts = flagAlerts(*stdout, *alertFile, webhooks, slackURLs, *smtpAddr, *mailFrom, *mailTo, *minSeverity)
+		}
+
+		if err := config.Validate(); err != nil {
+			return usagef("%v", err)
+		}
+
+		notifier, err := config.Alerts.Dispatcher()
+		if err != nil {
+			return err
+		}
+
+		var store *history.Store
+		if *save {
+			store = history.NewStore(*historyDir)
+		}
+
+		mon := monitor.New(config, store, notifier)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if *metricsAddr != "" {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics.Default.Handler())
+			server := &http.Server{Addr: *metricsAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+			go func() {
+				if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					fmt.Fprintf(os.Stderr, "metrics server: %v\n", err)
+				}
+			}()
+			defer server.Close()
+		}
+
+		if *once {
+			for _, target := range config.Targets {
+				mon.RunOnce(ctx, target)
+			}
+			return nil
+		}
+
+		return mon.Run(ctx)
+	}
+}
+
+// flagAlerts builds the alert configuration from command-line flags
+func flagAlerts(stdout bool, file string, webhooks, slackURLs []string, smtpAddr, mailFrom, mailTo, minSeverity string) monitor.AlertConfig {
+	filter := notify.Filter{MinSeverity: minSeverity}
+	var sinks []notify.SinkConfig
+
+	if stdout {
+		sinks = append(sinks, notify.SinkConfig{Type: notify.TypeStdout, Filter: filter})
+	}
+	if file != "" {
+		sinks = append(sinks, notify.SinkConfig{Type: notify.TypeFile, Path: file, Filter: filter})
+	}
+	for _, url := range webhooks {
+		sinks = append(sinks, notify.SinkConfig{Type: notify.TypeWebhook, URL: url, Filter: filter})
+	}
+	for _, url := range slackURLs {
+		sinks = append(sinks, notify.SinkConfig{Type: notify.TypeSlack, URL: url, Filter: filter})
+	}
+	if smtpAddr != "" {
+		sinks = append(sinks, notify.SinkConfig{
+			Type:   notify.TypeEmail,
+			SMTP:   &notify.SMTPConfig{Addr: smtpAddr, From: mailFrom, To: splitList(mailTo)},
+			Filter: filter,
+		})
+	}
+
+	return monitor.AlertConfig{Sinks: sinks}
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
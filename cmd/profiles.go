@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/Sh4Ryuu/go-scan/internal/scanner"
+)
+
+var profilesCommand = &command{
+	name:     "profiles",
+	synopsis: "Show the available scanning profiles",
+	setup:    setupProfiles,
+}
+
+func setupProfiles(fs *flag.FlagSet) func(args []string) error {
+	return func(args []string) error {
+		if len(args) > 0 {
+			return usagef("unexpected arguments: %v", args)
+		}
+
+		fmt.Println("AVAILABLE SCANNING PROFILES:")
+		for i, name := range scanner.ProfileNames {
+			profile := scanner.ProfileSettings[name]
+			fmt.Printf("\n%d. %s\n", i+1, name)
+			fmt.Printf("   Workers: %v, Timeout: %vms, Rate Limit: %vms\n", profile["workers"], profile["timeout"], profile["rateLimit"])
+			fmt.Printf("   Use Case: %s\n", scanner.ProfileDescriptions[name])
+		}
+		fmt.Println("\nUSE: go-scan scan -host example.com -profile aggressive")
+		return nil
+	}
+}
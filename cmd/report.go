@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Sh4Ryuu/go-scan/internal/history"
+	"github.com/Sh4Ryuu/go-scan/internal/output"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+var reportCommand = &command{
+	name:     "report",
+	args:     "<report-file|history-id>",
+	synopsis: "Print a saved scan report",
+	setup:    setupReport,
+}
+
+func setupReport(fs *flag.FlagSet) func(args []string) error {
+	historyDir := fs.String("history-dir", history.DefaultDir(), "Directory holding saved scan reports")
+	jsonOutput := fs.Bool("json", false, "Print the report as JSON")
+	verbose := fs.Bool("verbose", false, "Show certificate and geolocation details")
+	openOnly := fs.Bool("open", true, "Only show open ports")
+
+	return func(args []string) error {
+		if len(args) != 1 {
+			return usagef("expected exactly one report")
+		}
+
+		report, err := loadReport(history.NewStore(*historyDir), args[0])
+		if err != nil {
+			return err
+		}
+
+		if *jsonOutput {
+			return printJSON(report)
+		}
+
+		formatter := output.NewFormatter(&output.FormatterConfig{
+			Verbose: *verbose,
+			Host:    report.Host,
+		})
+
+		results := report.Results
+		if *openOnly {
+			results = report.OpenResults()
+		}
+		for i := range results {
+			formatter.PrintResults(&results[i])
+		}
+		if report.Stats != nil {
+			formatter.PrintStatistics(report.Stats)
+		}
+		return nil
+	}
+}
+
+// loadReport reads a report from a file path, falling back to a history ID
+func loadReport(store *history.Store, ref string) (*models.ScanReport, error) {
+	if _, err := os.Stat(ref); err == nil {
+		return history.ReadReport(ref)
+	}
+
+	report, err := store.Load(ref)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no report file or history entry named %q", ref)
+		}
+		return nil, err
+	}
+	return report, nil
+}
+
+// printJSON writes a value to stdout as indented JSON
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
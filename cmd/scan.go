@@ -0,0 +1,230 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Sh4Ryuu/go-scan/internal/history"
+	"github.com/Sh4Ryuu/go-scan/internal/nmap"
+	"github.com/Sh4Ryuu/go-scan/internal/output"
+	"github.com/Sh4Ryuu/go-scan/internal/plugin"
+	"github.com/Sh4Ryuu/go-scan/internal/scanner"
+	"github.com/Sh4Ryuu/go-scan/pkg/check"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+var scanCommand = &command{
+	name:     "scan",
+	synopsis: "Scan a host for open ports",
+	setup:    setupScan,
+}
+
+func setupScan(fs *flag.FlagSet) func(args []string) error {
+	config := scanner.DefaultConfig()
+
+	fs.StringVar(&config.Host, "host", config.Host, "Target host to scan")
+	fs.IntVar(&config.StartPort, "start", config.StartPort, "Starting port number")
+	fs.IntVar(&config.EndPort, "end", config.EndPort, "Ending port number")
+	fs.IntVar(&config.MaxWorkers, "workers", config.MaxWorkers, "Number of concurrent workers")
+	fs.IntVar(&config.TimeoutSeconds, "timeout", config.TimeoutSeconds, "Connection timeout in seconds")
+	fs.BoolVar(&config.Verbose, "verbose", config.Verbose, "Enable verbose output")
+	fs.BoolVar(&config.Quiet, "quiet", config.Quiet, "Quiet mode - only show open ports")
+	fs.BoolVar(&config.JSONOutput, "json", config.JSONOutput, "Output results as JSON")
+	fs.BoolVar(&config.BannerGrabbing, "banners", config.BannerGrabbing, "Enable banner grabbing")
+	fs.IntVar(&config.BannerMaxBytes, "banner-max", config.BannerMaxBytes, "Maximum banner size to keep, in bytes")
+	fs.BoolVar(&config.EnableSSL, "ssl", config.EnableSSL, "Enable SSL/TLS certificate grabbing")
+	fs.BoolVar(&config.TLSEnum, "tls-enum", config.TLSEnum, "Enumerate TLS versions, cipher suites and key exchange groups on TLS ports")
+	fs.BoolVar(&config.JARM, "jarm", config.JARM, "Compute the JARM fingerprint of TLS ports")
+	fs.StringVar(&config.JARMDatabase, "jarm-db", config.JARMDatabase, "JSON file mapping extra JARM fingerprints to labels")
+	fs.BoolVar(&config.HTTPEnum, "http", config.HTTPEnum, "Enumerate HTTP and HTTPS services: title, headers, redirects, favicon hash and methods")
+	fs.BoolVar(&config.SSHScan, "ssh", config.SSHScan, "Analyze SSH servers: algorithms, host keys and weak settings")
+	fs.BoolVar(&config.SMBScan, "smb", config.SMBScan, "Analyze SMB servers on ports 445 and 139: dialects, SMB1, signing and OS")
+	fs.BoolVar(&config.ExposureChecks, "exposure", config.ExposureChecks, "Check FTP, Redis, MongoDB, Elasticsearch and memcached for unauthenticated access")
+	plugins := fs.String("plugins", "", "Comma-separated plugin manifests or directories of them, or @file with one per line")
+	fs.IntVar(&config.PluginConcurrency, "plugin-concurrency", plugin.DefaultConcurrency, "Maximum plugin processes running at once")
+	fs.StringVar(&config.ProductRules, "product-rules", config.ProductRules, "JSON file of extra product identification rules")
+	fs.StringVar(&config.VulnDatabase, "vuln-db", config.VulnDatabase, "Local vulnerability feed (NVD 2.0 JSON or CSV), or a directory of them, to match products against")
+	fs.StringVar(&config.SNI, "sni", config.SNI, "Server name to send in TLS handshakes (default: the host)")
+	vhosts := fs.String("vhosts", "", "Comma-separated names, or @file with one per line, to try as SNI on every TLS port")
+	fs.BoolVar(&config.VHostsFromCert, "vhosts-from-cert", config.VHostsFromCert, "Also try the DNS names of each port's default certificate as SNI")
+	fs.StringVar(&config.CABundle, "ca-bundle", config.CABundle, "PEM file of trusted roots for certificate validation (default: system roots)")
+	fs.BoolVar(&config.EnableUDP, "udp", config.EnableUDP, "Enable UDP scanning")
+	fs.BoolVar(&config.EnableGeolocation, "geo", config.EnableGeolocation, "Enable geolocation lookup")
+	fs.StringVar(&config.Profile, "profile", config.Profile, "Scanning profile (aggressive, default, conservative)")
+	fs.StringVar(&config.NmapScripts, "nmap", config.NmapScripts, "Nmap scripts to run (comma-separated, e.g., 'ssh-hostkey,ssl-cert')")
+	fs.IntVar(&config.RateLimitMs, "rate-limit", config.RateLimitMs, "Rate limit in milliseconds")
+
+	outFile := fs.String("o", "", "Write the full scan report as JSON to this file")
+	save := fs.Bool("save", false, "Save the scan report to the history directory")
+	historyDir := fs.String("history-dir", history.DefaultDir(), "Directory holding saved scan reports")
+	listScripts := fs.Bool("list-scripts", false, "List the Nmap scripts accepted by -nmap and exit")
+	listChecks := fs.Bool("list-checks", false, "List the checks the scan would run, given the other flags, and exit")
+
+	return func(args []string) error {
+		if len(args) > 0 {
+			return usagef("unexpected arguments: %v", args)
+		}
+
+		if *listScripts {
+			printNmapScripts()
+			return nil
+		}
+		if *plugins != "" {
+			paths, err := readNameList(*plugins)
+			if err != nil {
+				return err
+			}
+			config.Plugins = paths
+		}
+
+		if *vhosts != "" {
+			names, err := readNameList(*vhosts)
+			if err != nil {
+				return err
+			}
+			config.VHosts = names
+		}
+
+		if err := config.Validate(); err != nil {
+			return usagef("configuration error: %v", err)
+		}
+
+		if *listChecks {
+			return printChecks(config)
+		}
+
+		formatter := output.NewFormatter(newFormatterConfig(config))
+		formatter.PrintBanner()
+		formatter.PrintConfigInfo()
+
+		portScanner, err := scanner.NewPortScanner(config, formatter)
+		if err != nil {
+			return err
+		}
+		results, stats, err := portScanner.Scan()
+		if err != nil {
+			return fmt.Errorf("scan error: %v", err)
+		}
+
+		for i := range results {
+			formatter.PrintResults(&results[i])
+		}
+		formatter.PrintStatistics(stats)
+
+		report := &models.ScanReport{
+			Host:    config.Host,
+			Results: results,
+			Stats:   stats,
+		}
+
+		if *save {
+			id, err := history.NewStore(*historyDir).Save(report)
+			if err != nil {
+				return fmt.Errorf("save report: %v", err)
+			}
+			if !config.Quiet && !config.JSONOutput {
+				fmt.Printf("%s Report saved to history as %s\n", output.SymInfo, id)
+			}
+		}
+
+		if *outFile != "" {
+			if err := history.WriteReport(*outFile, report); err != nil {
+				return fmt.Errorf("write report: %v", err)
+			}
+		}
+
+		return nil
+	}
+}
+
+// newFormatterConfig builds the formatter settings from a scanner config
+func newFormatterConfig(config *scanner.Config) *output.FormatterConfig {
+	return &output.FormatterConfig{
+		Quiet:             config.Quiet,
+		Verbose:           config.Verbose,
+		JSONOutput:        config.JSONOutput,
+		StartPort:         config.StartPort,
+		EndPort:           config.EndPort,
+		Host:              config.Host,
+		MaxWorkers:        config.MaxWorkers,
+		TimeoutSeconds:    config.TimeoutSeconds,
+		Profile:           config.Profile,
+		BannerGrabbing:    config.BannerGrabbing,
+		EnableSSL:         config.EnableSSL,
+		EnableUDP:         config.EnableUDP,
+		EnableGeolocation: config.EnableGeolocation,
+		NmapScripts:       config.NmapScripts,
+	}
+}
+
+// readNameList reads a comma-separated list, or with a leading @ a file
+// holding one name per line; blank lines and # comments are skipped
+func readNameList(value string) ([]string, error) {
+	if !strings.HasPrefix(value, "@") {
+		return splitList(value), nil
+	}
+
+	data, err := os.ReadFile(value[1:])
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			names = append(names, line)
+		}
+	}
+	return names, nil
+}
+
+// printChecks lists the checks a scan with config runs
+func printChecks(config *scanner.Config) error {
+	registry, err := scanner.Checks(config)
+	if err != nil {
+		return err
+	}
+	checks := registry.Checks()
+	if len(checks) == 0 {
+		fmt.Println("No checks enabled; -exposure adds the built-in exposure checks.")
+		return nil
+	}
+
+	fmt.Println("Checks run on matching open ports:")
+	for _, c := range checks {
+		info := c.Info()
+		protocol := info.Protocol
+		if protocol == "" {
+			protocol = check.TCP
+		}
+		var applies []string
+		for _, port := range info.Ports {
+			applies = append(applies, strconv.Itoa(port))
+		}
+		applies = append(applies, info.Services...)
+		fmt.Printf("  %-22s %s %-18s | %s\n", info.Name, protocol, strings.Join(applies, ","), info.Description)
+	}
+	return nil
+}
+
+// printNmapScripts lists the known Nmap scripts
+func printNmapScripts() {
+	scripts := nmap.ListAvailableScripts()
+
+	names := make([]string, 0, len(scripts))
+	for name := range scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println("Available Nmap scripts (pass a comma-separated list to -nmap):")
+	for _, name := range names {
+		fmt.Printf("  %-20s | %s\n", name, scripts[name])
+	}
+	fmt.Println()
+	fmt.Println("Nmap must be installed. See NMAP_SCRIPTS.md for details on each script.")
+	fmt.Println("Example: go-scan scan -host example.com -end 1000 -nmap ssh-hostkey,ssl-cert")
+}
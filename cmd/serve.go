@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/api"
+	"github.com/Sh4Ryuu/go-scan/internal/history"
+	"github.com/Sh4Ryuu/go-scan/internal/nmap"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+var serveCommand = &command{
+	name:     "serve",
+	synopsis: "Run the REST API for submitting and tracking scans",
+	setup:    setupServe,
+}
+
+func setupServe(fs *flag.FlagSet) func(args []string) error {
+	addr := fs.String("addr", "127.0.0.1:8080", "Address to listen on")
+	historyDir := fs.String("history-dir", history.DefaultDir(), "Directory holding saved scan reports")
+	queueSize := fs.Int("queue", 16, "Maximum number of jobs waiting to run")
+	maxJobs := fs.Int("max-jobs", 2, "Maximum number of jobs running at once")
+	maxProbes := fs.Int("max-probes", 500, "Maximum number of probes in flight across all jobs")
+	maxNmapHosts := fs.Int("max-nmap-hosts", nmap.DefaultParallelHosts, "Maximum number of hosts nmap scripts run against at once across all jobs")
+	maxWorkers := fs.Int("max-workers", 1000, "Maximum number of workers a job may ask for")
+	maxPorts := fs.Int("max-ports", 65535, "Maximum number of ports a job may probe, TCP and UDP together")
+	save := fs.Bool("save", false, "Save the report of every completed job to the history directory")
+
+	return func(args []string) error {
+		if len(args) > 0 {
+			return usagef("unexpected arguments: %v", args)
+		}
+
+		store := history.NewStore(*historyDir)
+
+		managerConfig := api.ManagerConfig{
+			QueueSize:    *queueSize,
+			MaxJobs:      *maxJobs,
+			MaxProbes:    *maxProbes,
+			MaxNmapHosts: *maxNmapHosts,
+			MaxWorkers:   *maxWorkers,
+			MaxPorts:     *maxPorts,
+		}
+		if *save {
+			managerConfig.OnJobComplete = func(report *models.ScanReport) {
+				if _, err := store.Save(report); err != nil {
+					fmt.Fprintf(os.Stderr, "save report for %s: %v\n", report.Host, err)
+				}
+			}
+		}
+
+		jobs := api.NewManager(managerConfig)
+		defer jobs.Shutdown()
+
+		server := &http.Server{
+			Addr:              *addr,
+			Handler:           api.NewServer(jobs, store).Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		}()
+
+		fmt.Printf("GoScan API listening on http://%s\n", *addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,392 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/nmap"
+	"github.com/Sh4Ryuu/go-scan/internal/output"
+	"github.com/Sh4Ryuu/go-scan/internal/scanner"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// Job states
+const (
+	StateQueued    = "queued"
+	StateRunning   = "running"
+	StateCompleted = "completed"
+	StateFailed    = "failed"
+	StateCancelled = "cancelled"
+)
+
+// ErrQueueFull is returned when no more jobs can be queued
+var ErrQueueFull = errors.New("job queue is full")
+
+// ErrJobNotFound is returned for unknown job IDs
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrJobFinished is returned when cancelling a job that already ended
+var ErrJobFinished = errors.New("job already finished")
+
+// ErrShutdown is returned when submitting to a manager that has shut down
+var ErrShutdown = errors.New("job manager is shut down")
+
+// Progress reports how far a running job has come
+type Progress struct {
+	Scanned int `json:"scanned"`
+	Total   int `json:"total"`
+	Percent int `json:"percent"`
+}
+
+// JobStatus is the externally visible state of a job
+type JobStatus struct {
+	ID         string         `json:"id"`
+	State      string         `json:"state"`
+	Config     scanner.Config `json:"config"`
+	Progress   Progress       `json:"progress"`
+	OpenPorts  int            `json:"open_ports"`
+	Error      string         `json:"error,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	StartedAt  *time.Time     `json:"started_at,omitempty"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+}
+
+// Job is a scan submitted through the API
+type Job struct {
+	mu         sync.Mutex
+	id         string
+	state      string
+	config     *scanner.Config
+	scanner    *scanner.PortScanner
+	report     *models.ScanReport
+	err        string
+	createdAt  time.Time
+	startedAt  time.Time
+	finishedAt time.Time
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+// Status returns a snapshot of the job's state
+func (j *Job) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := JobStatus{
+		ID:        j.id,
+		State:     j.state,
+		Config:    *j.config,
+		Error:     j.err,
+		CreatedAt: j.createdAt,
+	}
+	if !j.startedAt.IsZero() {
+		started := j.startedAt
+		status.StartedAt = &started
+	}
+	if !j.finishedAt.IsZero() {
+		finished := j.finishedAt
+		status.FinishedAt = &finished
+	}
+
+	if j.scanner != nil {
+		scanned, total := j.scanner.Progress()
+		status.Progress = Progress{Scanned: scanned, Total: total}
+		if total > 0 {
+			status.Progress.Percent = scanned * 100 / total
+		}
+	}
+	if j.report != nil {
+		status.OpenPorts = len(j.report.OpenResults())
+	}
+
+	return status
+}
+
+// Report returns the scan report once the job has completed
+func (j *Job) Report() (*models.ScanReport, string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.report, j.state
+}
+
+// ManagerConfig controls queueing and concurrency of API jobs
+type ManagerConfig struct {
+	QueueSize     int // jobs waiting to run
+	MaxJobs       int // jobs running at once
+	MaxProbes     int // probes in flight across all running jobs
+	MaxNmapHosts  int // nmap runs at once across all running jobs
+	MaxWorkers    int // workers a job may ask for
+	MaxPorts      int // ports a job may probe, TCP and UDP together
+	MaxRetained   int // finished jobs kept for status and results
+	OnJobComplete func(*models.ScanReport)
+}
+
+// Manager queues and runs scan jobs
+type Manager struct {
+	config  ManagerConfig
+	queue   chan *Job
+	limiter *scanner.Limiter
+	nmap    *nmap.Runner
+
+	mu     sync.Mutex
+	jobs   map[string]*Job
+	order  []string
+	closed bool
+
+	wg sync.WaitGroup
+}
+
+// NewManager creates a job manager and starts its job runners
+func NewManager(config ManagerConfig) *Manager {
+	if config.QueueSize < 1 {
+		config.QueueSize = 16
+	}
+	if config.MaxJobs < 1 {
+		config.MaxJobs = 2
+	}
+	if config.MaxProbes < 1 {
+		config.MaxProbes = 500
+	}
+	if config.MaxRetained < 1 {
+		config.MaxRetained = 100
+	}
+	if config.MaxWorkers < 1 {
+		config.MaxWorkers = 1000
+	}
+	if config.MaxPorts < 1 {
+		config.MaxPorts = 65535
+	}
+
+	m := &Manager{
+		config:  config,
+		queue:   make(chan *Job, config.QueueSize),
+		limiter: scanner.NewLimiter(config.MaxProbes),
+		nmap:    nmap.NewRunner(config.MaxNmapHosts),
+		jobs:    make(map[string]*Job),
+	}
+
+	for i := 0; i < config.MaxJobs; i++ {
+		m.wg.Add(1)
+		go m.runner()
+	}
+
+	return m
+}
+
+// Submit validates a scan configuration and queues it as a new job. The
+// limits apply to the validated configuration, since a profile may raise
+// the worker count.
+func (m *Manager) Submit(config *scanner.Config) (*Job, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.MaxWorkers > m.config.MaxWorkers {
+		return nil, fmt.Errorf("workers must be at most %d", m.config.MaxWorkers)
+	}
+	probes := config.GetPortCount()
+	if config.EnableUDP {
+		probes *= 2
+	}
+	if probes > m.config.MaxPorts {
+		return nil, fmt.Errorf("scan must probe at most %d ports, TCP and UDP together", m.config.MaxPorts)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &Job{
+		id:        newJobID(),
+		state:     StateQueued,
+		config:    config,
+		createdAt: time.Now(),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		cancel()
+		return nil, ErrShutdown
+	}
+
+	select {
+	case m.queue <- job:
+	default:
+		cancel()
+		return nil, ErrQueueFull
+	}
+
+	m.jobs[job.id] = job
+	m.order = append(m.order, job.id)
+	m.prune()
+	return job, nil
+}
+
+// Get looks up a job by ID
+func (m *Manager) Get(id string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// List returns the status of all known jobs, oldest first
+func (m *Manager) List() []JobStatus {
+	m.mu.Lock()
+	jobs := make([]*Job, 0, len(m.order))
+	for _, id := range m.order {
+		jobs = append(jobs, m.jobs[id])
+	}
+	m.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(jobs))
+	for _, job := range jobs {
+		statuses = append(statuses, job.Status())
+	}
+	return statuses
+}
+
+// Cancel stops a queued or running job
+func (m *Manager) Cancel(id string) error {
+	job, err := m.Get(id)
+	if err != nil {
+		return err
+	}
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	switch job.state {
+	case StateQueued:
+		job.state = StateCancelled
+		job.finishedAt = time.Now()
+	case StateRunning:
+		// the runner records the final state once the scan stops
+	default:
+		return ErrJobFinished
+	}
+	job.cancel()
+	return nil
+}
+
+// Shutdown cancels all jobs and waits for the runners to exit
+func (m *Manager) Shutdown() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	for _, job := range m.jobs {
+		job.cancel()
+	}
+	close(m.queue)
+	m.mu.Unlock()
+
+	m.wg.Wait()
+}
+
+// runner executes queued jobs one at a time
+func (m *Manager) runner() {
+	defer m.wg.Done()
+	for job := range m.queue {
+		m.run(job)
+	}
+}
+
+// run executes a single job
+func (m *Manager) run(job *Job) {
+	job.mu.Lock()
+	if job.state != StateQueued {
+		job.mu.Unlock()
+		return
+	}
+	// API jobs never print; the formatter only drives progress output
+	formatter := output.NewFormatter(&output.FormatterConfig{Quiet: true})
+	portScanner, err := scanner.NewPortScanner(job.config, formatter)
+	if err != nil {
+		job.state = StateFailed
+		job.err = err.Error()
+		job.finishedAt = time.Now()
+		job.mu.Unlock()
+		job.cancel()
+		return
+	}
+	job.scanner = portScanner
+	job.scanner.SetLimiter(m.limiter)
+	job.scanner.SetNmapRunner(m.nmap)
+	job.state = StateRunning
+	job.startedAt = time.Now()
+	job.mu.Unlock()
+
+	results, stats, err := job.scanner.ScanContext(job.ctx)
+
+	report := &models.ScanReport{
+		Host:    job.config.Host,
+		Results: results,
+		Stats:   stats,
+	}
+
+	job.mu.Lock()
+	job.finishedAt = time.Now()
+	job.report = report
+	switch {
+	case errors.Is(err, context.Canceled):
+		job.state = StateCancelled
+	case err != nil:
+		job.state = StateFailed
+		job.err = err.Error()
+	default:
+		job.state = StateCompleted
+	}
+	completed := job.state == StateCompleted
+	job.mu.Unlock()
+	job.cancel()
+
+	if completed && m.config.OnJobComplete != nil {
+		m.config.OnJobComplete(report)
+	}
+}
+
+// prune drops the oldest finished jobs beyond the retention limit.
+// Callers must hold m.mu.
+func (m *Manager) prune() {
+	excess := len(m.order) - m.config.MaxRetained
+	if excess <= 0 {
+		return
+	}
+
+	kept := m.order[:0]
+	for _, id := range m.order {
+		job := m.jobs[id]
+		job.mu.Lock()
+		finished := !job.finishedAt.IsZero()
+		job.mu.Unlock()
+
+		if excess > 0 && finished {
+			delete(m.jobs, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	m.order = kept
+}
+
+// newJobID returns a random job identifier
+func newJobID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/scanner"
+)
+
+// closedPort returns a loopback port nothing listens on, so scans of it
+// finish at once
+func closedPort(t *testing.T) int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	return port
+}
+
+// localScan is a one-port scan of the loopback address
+func localScan(t *testing.T) *scanner.Config {
+	config := scanner.DefaultConfig()
+	config.Host = "127.0.0.1"
+	config.StartPort = closedPort(t)
+	config.EndPort = config.StartPort
+	config.EnableGeolocation = false
+	return config
+}
+
+// waitState polls a job until it reaches state
+func waitState(t *testing.T, job *Job, state string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status().State != state {
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is %s, want %s", job.id, job.Status().State, state)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSubmitLimits(t *testing.T) {
+	m := NewManager(ManagerConfig{MaxWorkers: 200, MaxPorts: 100})
+	defer m.Shutdown()
+
+	tests := []struct {
+		name   string
+		modify func(*scanner.Config)
+		want   string
+	}{
+		{"workers", func(c *scanner.Config) { c.Profile, c.MaxWorkers = "", 201 }, "workers must be at most 200"},
+		{"aggressive profile", func(c *scanner.Config) { c.Profile, c.MaxWorkers = "aggressive", 10 }, "workers must be at most 200"},
+		{"ports", func(c *scanner.Config) { c.StartPort, c.EndPort = 1, 101 }, "scan must probe at most 100 ports"},
+		{"UDP doubles the ports", func(c *scanner.Config) { c.StartPort, c.EndPort, c.EnableUDP = 1, 51, true }, "scan must probe at most 100 ports"},
+		{"invalid", func(c *scanner.Config) { c.Host = "" }, "host cannot be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := localScan(t)
+			tt.modify(config)
+			if _, err := m.Submit(config); err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+	if jobs := m.List(); len(jobs) != 0 {
+		t.Errorf("rejected scans were queued: %+v", jobs)
+	}
+
+	// The conservative profile's 50 workers are within the limit
+	config := localScan(t)
+	config.Profile, config.MaxWorkers = "conservative", 1000
+	job, err := m.Submit(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, job, StateCompleted)
+}
+
+func TestQueueFullAndCancel(t *testing.T) {
+	m := NewManager(ManagerConfig{QueueSize: 1, MaxJobs: 1, MaxProbes: 1})
+	defer m.Shutdown()
+
+	// Holding the only probe slot keeps the running job from progressing
+	if err := m.limiter.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	running, err := m.Submit(localScan(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, running, StateRunning)
+
+	queued, err := m.Submit(localScan(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Submit(localScan(t)); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("got error %v, want ErrQueueFull", err)
+	}
+
+	if err := m.Cancel(queued.id); err != nil {
+		t.Fatal(err)
+	}
+	if status := queued.Status(); status.State != StateCancelled || status.FinishedAt == nil {
+		t.Errorf("queued job after cancel: %+v", status)
+	}
+	if err := m.Cancel(running.id); err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, running, StateCancelled)
+	m.limiter.Release()
+
+	if err := m.Cancel(running.id); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("cancelling twice: got error %v, want ErrJobFinished", err)
+	}
+	if err := m.Cancel("nope"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("cancelling an unknown job: got error %v, want ErrJobNotFound", err)
+	}
+
+	// The cancelled queued job is skipped and the queue has room again
+	next, err := m.Submit(localScan(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, next, StateCompleted)
+	if report, _ := next.Report(); report == nil || report.Host != "127.0.0.1" {
+		t.Errorf("report %+v", report)
+	}
+	if queued.Status().StartedAt != nil {
+		t.Error("the cancelled job was started")
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	m := NewManager(ManagerConfig{})
+	m.Shutdown()
+	m.Shutdown()
+	if _, err := m.Submit(localScan(t)); !errors.Is(err, ErrShutdown) {
+		t.Errorf("got error %v, want ErrShutdown", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	m := NewManager(ManagerConfig{MaxRetained: 2})
+	defer m.Shutdown()
+
+	var ids []string
+	for i := 0; i < 4; i++ {
+		job, err := m.Submit(localScan(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitState(t, job, StateCompleted)
+		ids = append(ids, job.id)
+	}
+	// Each submit drops the oldest finished jobs beyond the limit
+	jobs := m.List()
+	if len(jobs) != 2 || jobs[0].ID != ids[2] || jobs[1].ID != ids[3] {
+		var got []string
+		for _, job := range jobs {
+			got = append(got, job.ID)
+		}
+		t.Errorf("kept %v, want %v", got, ids[2:])
+	}
+	if _, err := m.Get(ids[0]); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("pruned job: got error %v", err)
+	}
+	if id := newJobID(); len(id) != 16 {
+		t.Errorf("job ID %q is not 16 hex digits", id)
+	}
+}
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Sh4Ryuu/go-scan/internal/history"
+	"github.com/Sh4Ryuu/go-scan/internal/metrics"
+	"github.com/Sh4Ryuu/go-scan/internal/nmap"
+	"github.com/Sh4Ryuu/go-scan/internal/scanner"
+)
+
+const maxRequestBytes = 1 << 20
+
+// Server exposes scan jobs and saved reports over a REST API, along with
+// Prometheus metrics on /metrics
+type Server struct {
+	jobs  *Manager
+	store *history.Store
+}
+
+// NewServer creates an API server for a job manager and history store
+func NewServer(jobs *Manager, store *history.Store) *Server {
+	return &Server{jobs: jobs, store: store}
+}
+
+// Handler returns the HTTP handler serving the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/scans", s.handleScans)
+	mux.HandleFunc("/api/scans/", s.handleScan)
+	mux.HandleFunc("/api/history", s.handleHistory)
+	mux.HandleFunc("/api/history/", s.handleHistoryEntry)
+	mux.Handle("/metrics", metrics.Default.Handler())
+	return mux
+}
+
+// handleScans lists jobs (GET) or submits a new one (POST)
+func (s *Server) handleScans(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.jobs.List())
+	case http.MethodPost:
+		s.submit(w, r)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+// submit decodes a scan configuration and queues it
+func (s *Server) submit(w http.ResponseWriter, r *http.Request) {
+	config := scanner.DefaultConfig()
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid scan request: %v", err))
+		return
+	}
+	if err := checkRequest(config); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid scan request: %v", err))
+		return
+	}
+
+	job, err := s.jobs.Submit(config)
+	switch {
+	case errors.Is(err, ErrQueueFull):
+		writeError(w, http.StatusTooManyRequests, err.Error())
+		return
+	case errors.Is(err, ErrShutdown):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Location", "/api/scans/"+job.id)
+	writeJSON(w, http.StatusAccepted, job.Status())
+}
+
+// checkRequest refuses the settings a remote client must not control.
+// Plugins run local executables and the file settings read the server's
+// filesystem, so only the operator may configure them; nmap only runs
+// the known scripts, against a host it cannot mistake for an option.
+func checkRequest(config *scanner.Config) error {
+	if len(config.Plugins) > 0 {
+		return fmt.Errorf("plugins cannot be set through the API")
+	}
+	for _, field := range []struct{ name, value string }{
+		{"ca_bundle", config.CABundle},
+		{"product_rules", config.ProductRules},
+		{"vuln_db", config.VulnDatabase},
+		{"jarm_db", config.JARMDatabase},
+	} {
+		if field.value != "" {
+			return fmt.Errorf("%s cannot be set through the API", field.name)
+		}
+	}
+	if strings.HasPrefix(config.Host, "-") {
+		return fmt.Errorf("invalid host %q", config.Host)
+	}
+	for _, script := range config.GetNmapScriptsList() {
+		if !nmap.ValidateScript(script) {
+			return fmt.Errorf("unknown nmap script %q", script)
+		}
+	}
+	return nil
+}
+
+// handleScan serves /api/scans/{id}, /api/scans/{id}/cancel and /api/scans/{id}/results
+func (s *Server) handleScan(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/scans/"), "/"), "/")
+	if len(parts) > 2 || parts[0] == "" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	job, err := s.jobs.Get(parts[0])
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, job.Status())
+	case action == "" && r.Method == http.MethodDelete,
+		action == "cancel" && r.Method == http.MethodPost:
+		s.cancel(w, job)
+	case action == "results" && r.Method == http.MethodGet:
+		s.results(w, job)
+	case action == "", action == "cancel", action == "results":
+		methodNotAllowed(w)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// cancel stops a job
+func (s *Server) cancel(w http.ResponseWriter, job *Job) {
+	if err := s.jobs.Cancel(job.id); err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job.Status())
+}
+
+// results returns the report of a finished job
+func (s *Server) results(w http.ResponseWriter, job *Job) {
+	report, state := job.Report()
+	if report == nil {
+		writeError(w, http.StatusConflict, fmt.Sprintf("job is %s, results are not available yet", state))
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleHistory lists saved reports
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	entries, err := s.store.List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if entries == nil {
+		entries = []history.Entry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// handleHistoryEntry returns a single saved report
+func (s *Server) handleHistoryEntry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/api/history/")
+	report, err := s.store.Load(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "report not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON error body
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// methodNotAllowed rejects a request made with an unsupported method
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+	}
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// request sends a request to the API and decodes the JSON reply into v
+func request(t *testing.T, server *httptest.Server, method, path, body string, v interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if v != nil {
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatalf("%s %s: %v in %q", method, path, err, data)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestSubmitRejected(t *testing.T) {
+	m := NewManager(ManagerConfig{MaxWorkers: 200})
+	defer m.Shutdown()
+	server := httptest.NewServer(NewServer(m, nil).Handler())
+	defer server.Close()
+
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"host": "127.0.0.1", "plugins": ["/bin/true"]}`, "plugins cannot be set through the API"},
+		{`{"host": "127.0.0.1", "vuln_db": "/etc/passwd"}`, "vuln_db cannot be set through the API"},
+		{`{"host": "-oN/tmp/x"}`, `invalid host "-oN/tmp/x"`},
+		{`{"host": "127.0.0.1", "nmap_scripts": "../evil"}`, `unknown nmap script "../evil"`},
+		{`{"host": "127.0.0.1", "bogus": 1}`, "unknown field"},
+		{`{"host": "127.0.0.1", "profile": "aggressive"}`, "workers must be at most 200"},
+		{`{"host": "127.0.0.1", "profile": "", "workers": 201}`, "workers must be at most 200"},
+	}
+	for _, tt := range tests {
+		var reply map[string]string
+		status := request(t, server, http.MethodPost, "/api/scans", tt.body, &reply)
+		if status != http.StatusBadRequest || !strings.Contains(reply["error"], tt.want) {
+			t.Errorf("%s: got %d %q, want 400 %q", tt.body, status, reply["error"], tt.want)
+		}
+	}
+	if jobs := m.List(); len(jobs) != 0 {
+		t.Errorf("rejected scans were queued: %+v", jobs)
+	}
+}
+
+func TestScanLifecycle(t *testing.T) {
+	m := NewManager(ManagerConfig{QueueSize: 1, MaxJobs: 1, MaxProbes: 1})
+	defer m.Shutdown()
+	server := httptest.NewServer(NewServer(m, nil).Handler())
+	defer server.Close()
+
+	// Holding the only probe slot keeps the first job running
+	if err := m.limiter.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	body := func() string {
+		return fmt.Sprintf(`{"host": "127.0.0.1", "start_port": %d, "end_port": %[1]d, "geo": false}`, closedPort(t))
+	}
+
+	var running JobStatus
+	if status := request(t, server, http.MethodPost, "/api/scans", body(), &running); status != http.StatusAccepted {
+		t.Fatalf("submit: got %d", status)
+	}
+	job, err := m.Get(running.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, job, StateRunning)
+
+	var queued JobStatus
+	if status := request(t, server, http.MethodPost, "/api/scans", body(), &queued); status != http.StatusAccepted || queued.State != StateQueued {
+		t.Fatalf("second submit: got %d %+v", status, queued)
+	}
+	var reply map[string]string
+	if status := request(t, server, http.MethodPost, "/api/scans", body(), &reply); status != http.StatusTooManyRequests {
+		t.Errorf("full queue: got %d %q, want 429", status, reply["error"])
+	}
+
+	if status := request(t, server, http.MethodGet, "/api/scans/"+running.ID+"/results", "", &reply); status != http.StatusConflict {
+		t.Errorf("results of a running job: got %d, want 409", status)
+	}
+	if status := request(t, server, http.MethodGet, "/api/scans/nope", "", &reply); status != http.StatusNotFound {
+		t.Errorf("unknown job: got %d, want 404", status)
+	}
+	if status := request(t, server, http.MethodGet, "/api/scans/"+running.ID+"/cancel", "", nil); status != http.StatusMethodNotAllowed {
+		t.Errorf("GET cancel: got %d, want 405", status)
+	}
+
+	var cancelled JobStatus
+	if status := request(t, server, http.MethodDelete, "/api/scans/"+queued.ID, "", &cancelled); status != http.StatusAccepted || cancelled.State != StateCancelled {
+		t.Errorf("cancel queued job: got %d %+v", status, cancelled)
+	}
+	if status := request(t, server, http.MethodPost, "/api/scans/"+running.ID+"/cancel", "", nil); status != http.StatusAccepted {
+		t.Errorf("cancel running job: got %d", status)
+	}
+	waitState(t, job, StateCancelled)
+	m.limiter.Release()
+	if status := request(t, server, http.MethodPost, "/api/scans/"+running.ID+"/cancel", "", &reply); status != http.StatusConflict {
+		t.Errorf("cancel finished job: got %d, want 409", status)
+	}
+
+	var jobs []JobStatus
+	if status := request(t, server, http.MethodGet, "/api/scans", "", &jobs); status != http.StatusOK || len(jobs) != 2 {
+		t.Errorf("list: got %d %+v", status, jobs)
+	}
+}
@@ -0,0 +1,152 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// Change kinds reported by Compare
+const (
+	KindNewPort     = "new_port"
+	KindClosedPort  = "closed_port"
+	KindCertChanged = "cert_changed"
+	KindCertExpired = "cert_expired"
+)
+
+// Change describes a single difference between two scan reports
+type Change struct {
+	Kind     string             `json:"kind"`
+	Host     string             `json:"host"`
+	Port     int                `json:"port"`
+	Protocol string             `json:"protocol"`
+	Message  string             `json:"message"`
+	Before   *models.ScanResult `json:"before,omitempty"`
+	After    *models.ScanResult `json:"after,omitempty"`
+}
+
+type portKey struct {
+	port     int
+	protocol string
+}
+
+// Compare returns the changes between an earlier and a later report.
+// Only open ports are considered; a port missing from the later report
+// is treated as closed.
+func Compare(before, after *models.ScanReport) []Change {
+	old := indexOpen(before)
+	cur := indexOpen(after)
+	host := after.Host
+
+	var changes []Change
+
+	for key, result := range cur {
+		result := result
+		prev, existed := old[key]
+		if !existed {
+			changes = append(changes, Change{
+				Kind:     KindNewPort,
+				Host:     host,
+				Port:     key.port,
+				Protocol: key.protocol,
+				Message:  fmt.Sprintf("new open port %d/%s%s", key.port, key.protocol, serviceSuffix(&result)),
+				After:    &result,
+			})
+			changes = append(changes, compareCerts(host, key, nil, &result)...)
+		} else {
+			prev := prev
+			changes = append(changes, compareCerts(host, key, &prev, &result)...)
+		}
+	}
+
+	for key, result := range old {
+		result := result
+		if _, still := cur[key]; !still {
+			changes = append(changes, Change{
+				Kind:     KindClosedPort,
+				Host:     host,
+				Port:     key.port,
+				Protocol: key.protocol,
+				Message:  fmt.Sprintf("port %d/%s%s is no longer open", key.port, key.protocol, serviceSuffix(&result)),
+				Before:   &result,
+			})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Port != changes[j].Port {
+			return changes[i].Port < changes[j].Port
+		}
+		if changes[i].Protocol != changes[j].Protocol {
+			return changes[i].Protocol < changes[j].Protocol
+		}
+		return changes[i].Kind < changes[j].Kind
+	})
+
+	return changes
+}
+
+// compareCerts reports certificate changes on an open port. before is
+// nil for a newly opened port, whose certificate can only have expired.
+func compareCerts(host string, key portKey, before, after *models.ScanResult) []Change {
+	var changes []Change
+	if after.SSLInfo == nil {
+		return changes
+	}
+
+	if before != nil && before.SSLInfo != nil && before.SSLInfo.Fingerprint != after.SSLInfo.Fingerprint {
+		changes = append(changes, Change{
+			Kind:     KindCertChanged,
+			Host:     host,
+			Port:     key.port,
+			Protocol: key.protocol,
+			Message:  fmt.Sprintf("certificate on port %d changed (%s -> %s)", key.port, shortFingerprint(before.SSLInfo.Fingerprint), shortFingerprint(after.SSLInfo.Fingerprint)),
+			Before:   before,
+			After:    after,
+		})
+	}
+
+	wasExpired := before != nil && before.SSLInfo != nil && before.SSLInfo.IsExpired
+	if after.SSLInfo.IsExpired && !wasExpired {
+		changes = append(changes, Change{
+			Kind:     KindCertExpired,
+			Host:     host,
+			Port:     key.port,
+			Protocol: key.protocol,
+			Message:  fmt.Sprintf("certificate on port %d expired on %s", key.port, after.SSLInfo.ValidTo.Format("2006-01-02")),
+			Before:   before,
+			After:    after,
+		})
+	}
+
+	return changes
+}
+
+// indexOpen maps the open results of a report by port and protocol
+func indexOpen(report *models.ScanReport) map[portKey]models.ScanResult {
+	index := make(map[portKey]models.ScanResult)
+	if report == nil {
+		return index
+	}
+	for _, result := range report.OpenResults() {
+		index[portKey{result.Port, result.Protocol}] = result
+	}
+	return index
+}
+
+// serviceSuffix formats the service name of a result for messages
+func serviceSuffix(result *models.ScanResult) string {
+	if result.Service != "" {
+		return fmt.Sprintf(" (%s)", result.Service)
+	}
+	return ""
+}
+
+// shortFingerprint shortens a fingerprint for display
+func shortFingerprint(fp string) string {
+	if len(fp) > 16 {
+		return fp[:16]
+	}
+	return fp
+}
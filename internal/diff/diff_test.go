@@ -0,0 +1,126 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// result builds an open TCP result, with a certificate when fingerprint is set
+func result(port int, service, fingerprint string, expired bool) models.ScanResult {
+	r := models.ScanResult{Host: "192.0.2.10", Port: port, Protocol: "tcp", Status: "open", Service: service}
+	if fingerprint != "" {
+		r.SSLInfo = &models.SSLCertInfo{
+			Fingerprint: fingerprint,
+			IsExpired:   expired,
+			ValidTo:     time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC),
+		}
+	}
+	return r
+}
+
+func report(results ...models.ScanResult) *models.ScanReport {
+	return &models.ScanReport{Host: "192.0.2.10", Results: results}
+}
+
+func TestCompare(t *testing.T) {
+	closed := result(25, "smtp", "", false)
+	closed.Status = "closed"
+	udp := result(53, "dns", "", false)
+	udp.Protocol = "udp"
+
+	tests := []struct {
+		name          string
+		before, after *models.ScanReport
+		want          []string // kind and message of each change
+	}{
+		{"no changes", report(result(22, "ssh", "", false)), report(result(22, "ssh", "", false)), nil},
+		{
+			"first scan",
+			nil,
+			report(result(22, "ssh", "", false), closed),
+			[]string{"new_port: new open port 22/tcp (ssh)"},
+		},
+		{
+			"opened and closed",
+			report(result(22, "ssh", "", false), udp),
+			report(result(80, "", "", false), closed),
+			[]string{
+				"closed_port: port 22/tcp (ssh) is no longer open",
+				"closed_port: port 53/udp (dns) is no longer open",
+				"new_port: new open port 80/tcp",
+			},
+		},
+		{"port stays closed", report(closed), report(closed), nil},
+		{
+			"certificate changed",
+			report(result(443, "https", "aaaaaaaaaaaaaaaaaaaa", false)),
+			report(result(443, "https", "bbbbbbbbbbbbbbbbbbbb", false)),
+			[]string{"cert_changed: certificate on port 443 changed (aaaaaaaaaaaaaaaa -> bbbbbbbbbbbbbbbb)"},
+		},
+		{
+			"certificate expired",
+			report(result(443, "https", "aa", false)),
+			report(result(443, "https", "aa", true)),
+			[]string{"cert_expired: certificate on port 443 expired on 2024-01-20"},
+		},
+		{
+			"still expired",
+			report(result(443, "https", "aa", true)),
+			report(result(443, "https", "aa", true)),
+			nil,
+		},
+		{
+			"renewed",
+			report(result(443, "https", "aa", true)),
+			report(result(443, "https", "bb", false)),
+			[]string{"cert_changed: certificate on port 443 changed (aa -> bb)"},
+		},
+		{
+			"new port with an expired certificate",
+			report(result(443, "https", "aa", false)),
+			report(result(443, "https", "aa", false), result(8443, "https-alt", "cc", true)),
+			[]string{
+				"cert_expired: certificate on port 8443 expired on 2024-01-20",
+				"new_port: new open port 8443/tcp (https-alt)",
+			},
+		},
+		{
+			"certificate seen for the first time",
+			report(result(443, "https", "", false)),
+			report(result(443, "https", "aa", true)),
+			[]string{"cert_expired: certificate on port 443 expired on 2024-01-20"},
+		},
+	}
+	for _, tt := range tests {
+		changes := Compare(tt.before, tt.after)
+		var got []string
+		for _, change := range changes {
+			got = append(got, change.Kind+": "+change.Message)
+			if change.Host != "192.0.2.10" {
+				t.Errorf("%s: change on host %q", tt.name, change.Host)
+			}
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+func TestCompareResults(t *testing.T) {
+	changes := Compare(report(result(443, "https", "aa", false)), report(result(443, "https", "aa", true), result(8443, "", "cc", true)))
+	if len(changes) != 3 {
+		t.Fatalf("got %d changes", len(changes))
+	}
+	for _, change := range changes {
+		if change.After == nil {
+			t.Errorf("%s on port %d has no later result", change.Kind, change.Port)
+		}
+		wantBefore := change.Port == 443
+		if (change.Before != nil) != wantBefore {
+			t.Errorf("%s on port %d: earlier result %+v", change.Kind, change.Port, change.Before)
+		}
+	}
+}
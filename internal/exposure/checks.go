@@ -0,0 +1,331 @@
+package exposure
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// Maximum size of a listing or INFO reply read as evidence
+const maxEvidenceRead = 64 * 1024
+
+// ftpAnonymous logs in as anonymous and lists the root directory
+func ftpAnonymous(address string, opts Options) *models.Finding {
+	c, err := dial(address, opts)
+	if err != nil {
+		return nil
+	}
+	defer c.Close()
+
+	if code, _, err := c.ftpReply(); err != nil || code != 220 {
+		return nil
+	}
+	code, reply, err := c.ftpCommand("USER anonymous")
+	if err == nil && code == 331 {
+		code, reply, err = c.ftpCommand("PASS anonymous@example.com")
+	}
+	if err != nil || code != 230 {
+		return nil
+	}
+
+	evidence := fmt.Sprintf("USER anonymous: %d %s", code, reply)
+	// The data connection would need its own TLS session; list in
+	// plaintext only
+	if !opts.TLS {
+		if names, err := c.ftpList(address, opts); err == nil {
+			evidence += "; listing: " + nameList(names)
+		}
+	}
+	c.ftpCommand("QUIT")
+
+	return &models.Finding{
+		ID:       "ftp-anonymous",
+		Title:    "FTP allows anonymous login",
+		Severity: models.SeverityMedium,
+		Detail:   "logged in as anonymous",
+		Evidence: evidence,
+	}
+}
+
+// ftpCommand sends a command and reads its reply
+func (c *conn) ftpCommand(command string) (int, string, error) {
+	if _, err := c.Write([]byte(command + "\r\n")); err != nil {
+		return 0, "", err
+	}
+	return c.ftpReply()
+}
+
+// ftpReply reads a possibly multi-line reply, returning its code and the
+// text of its first line
+func (c *conn) ftpReply() (int, string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return 0, "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if len(line) < 3 {
+		return 0, "", fmt.Errorf("short FTP reply %q", line)
+	}
+	code, err := strconv.Atoi(line[:3])
+	if err != nil {
+		return 0, "", fmt.Errorf("bad FTP reply %q", line)
+	}
+	text := strings.TrimSpace(line[3:])
+	if strings.HasPrefix(line[3:], "-") {
+		text = strings.TrimSpace(line[4:])
+		for {
+			next, err := c.r.ReadString('\n')
+			if err != nil {
+				return 0, "", err
+			}
+			if strings.HasPrefix(next, line[:3]+" ") {
+				break
+			}
+		}
+	}
+	return code, text, nil
+}
+
+// pasvAddress matches the h1,h2,h3,h4,p1,p2 of a PASV reply
+var pasvAddress = regexp.MustCompile(`(\d+),(\d+),(\d+),(\d+),(\d+),(\d+)`)
+
+// ftpList lists the names in the current directory over a passive data
+// connection. The data connection goes to the control connection's host,
+// not the address in the PASV reply, which is often private.
+func (c *conn) ftpList(address string, opts Options) ([]string, error) {
+	code, reply, err := c.ftpCommand("PASV")
+	if err != nil {
+		return nil, err
+	}
+	match := pasvAddress.FindStringSubmatch(reply)
+	if code != 227 || match == nil {
+		return nil, fmt.Errorf("PASV: %d %s", code, reply)
+	}
+	p1, _ := strconv.Atoi(match[5])
+	p2, _ := strconv.Atoi(match[6])
+	host, _, _ := net.SplitHostPort(address)
+
+	data, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(p1*256+p2)), opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer data.Close()
+
+	if code, reply, err = c.ftpCommand("NLST"); err != nil {
+		return nil, err
+	}
+	if code != 125 && code != 150 {
+		return nil, fmt.Errorf("NLST: %d %s", code, reply)
+	}
+	data.SetDeadline(time.Now().Add(opts.Timeout))
+	listing, err := io.ReadAll(io.LimitReader(data, maxEvidenceRead))
+	if err != nil {
+		return nil, err
+	}
+	c.ftpReply() // 226 Transfer complete
+
+	var names []string
+	for _, line := range strings.Split(string(listing), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			names = append(names, line)
+		}
+	}
+	return names, nil
+}
+
+// redisInfo runs INFO, which a server requiring AUTH refuses
+func redisInfo(address string, opts Options) *models.Finding {
+	c, err := dial(address, opts)
+	if err != nil {
+		return nil
+	}
+	defer c.Close()
+
+	if _, err := c.Write([]byte("*1\r\n$4\r\nINFO\r\n")); err != nil {
+		return nil
+	}
+	line, err := c.r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "$") {
+		return nil // -NOAUTH, -DENIED or not Redis
+	}
+	size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil || size <= 0 {
+		return nil
+	}
+	info, _ := io.ReadAll(io.LimitReader(c.r, int64(min(size, maxEvidenceRead))))
+
+	fields := make(map[string]string)
+	var keyspace []string
+	for _, line := range strings.Split(string(info), "\r\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields[name] = value
+		if strings.HasPrefix(name, "db") {
+			keys, _, _ := strings.Cut(value, ",")
+			keyspace = append(keyspace, name+" "+keys)
+		}
+	}
+	if fields["redis_version"] == "" {
+		return nil
+	}
+
+	evidence := "redis_version:" + fields["redis_version"]
+	for _, name := range []string{"os", "role", "connected_clients"} {
+		if fields[name] != "" {
+			evidence += ", " + name + ":" + fields[name]
+		}
+	}
+	evidence += "; keyspace: " + nameList(keyspace)
+
+	return &models.Finding{
+		ID:       "redis-no-auth",
+		Title:    "Redis accepts commands without authentication",
+		Severity: models.SeverityCritical,
+		Detail:   "INFO answered without AUTH",
+		Evidence: evidence,
+	}
+}
+
+// memcachedStats runs stats, which a server requiring SASL refuses
+func memcachedStats(address string, opts Options) *models.Finding {
+	c, err := dial(address, opts)
+	if err != nil {
+		return nil
+	}
+	defer c.Close()
+
+	if _, err := c.Write([]byte("stats\r\n")); err != nil {
+		return nil
+	}
+	stats := make(map[string]string)
+	for read := 0; read < maxEvidenceRead; {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return nil
+		}
+		read += len(line)
+		line = strings.TrimRight(line, "\r\n")
+		if line == "END" {
+			break
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "STAT" {
+			return nil
+		}
+		stats[fields[1]] = fields[2]
+	}
+	if stats["version"] == "" {
+		return nil
+	}
+
+	evidence := "version " + stats["version"]
+	for _, name := range []string{"curr_items", "bytes", "curr_connections"} {
+		if stats[name] != "" {
+			evidence += ", " + name + " " + stats[name]
+		}
+	}
+
+	return &models.Finding{
+		ID:       "memcached-no-auth",
+		Title:    "memcached accepts commands without authentication",
+		Severity: models.SeverityHigh,
+		Detail:   "stats answered without SASL",
+		Evidence: evidence,
+	}
+}
+
+// elasticRoot is the part of the Elasticsearch root document the check
+// uses
+type elasticRoot struct {
+	ClusterName string `json:"cluster_name"`
+	Version     struct {
+		Number       string `json:"number"`
+		Distribution string `json:"distribution"` // "opensearch" for OpenSearch
+	} `json:"version"`
+}
+
+// elasticIndices lists the indices through /_cat/indices
+func elasticIndices(address string, opts Options) *models.Finding {
+	scheme := "http"
+	if opts.TLS {
+		scheme = "https"
+	}
+	base := scheme + "://" + address
+	client := httpClient(address, opts)
+
+	// The root document identifies the cluster, so any other web server
+	// on the port is not mistaken for one
+	var root elasticRoot
+	if status, body, err := httpGet(client, base+"/"); err != nil || status != http.StatusOK || json.Unmarshal(body, &root) != nil || root.ClusterName == "" {
+		return nil
+	}
+	status, body, err := httpGet(client, base+"/_cat/indices?h=index,docs.count&s=index")
+	if err != nil || status != http.StatusOK {
+		return nil
+	}
+
+	var indices []string
+	for _, line := range strings.Split(string(body), "\n") {
+		fields := strings.Fields(line)
+		switch len(fields) {
+		case 0:
+		case 1:
+			indices = append(indices, fields[0])
+		default:
+			indices = append(indices, fields[0]+" ("+fields[1]+" docs)")
+		}
+	}
+
+	product := "Elasticsearch"
+	if root.Version.Distribution == "opensearch" {
+		product = "OpenSearch"
+	}
+	return &models.Finding{
+		ID:       "elasticsearch-no-auth",
+		Title:    product + " accepts requests without authentication",
+		Severity: models.SeverityHigh,
+		Detail:   "/_cat/indices answered without credentials",
+		Evidence: fmt.Sprintf("cluster %s, %s %s; %d indices: %s",
+			root.ClusterName, product, root.Version.Number, len(indices), nameList(indices)),
+	}
+}
+
+// httpClient returns a client that always connects to address
+func httpClient(address string, opts Options) *http.Client {
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	return &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, address)
+			},
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true, ServerName: opts.ServerName},
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// httpGet fetches target, returning its status and the start of its body
+func httpGet(client *http.Client, target string) (int, []byte, error) {
+	resp, err := client.Get(target)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxEvidenceRead))
+	return resp.StatusCode, body, err
+}
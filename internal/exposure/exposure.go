@@ -0,0 +1,150 @@
+// Package exposure confirms that common data services accept clients
+// without authentication. Every check is read-only: it logs in or queries
+// the way any client could, and reports what it saw as evidence.
+package exposure
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/ssl"
+	"github.com/Sh4Ryuu/go-scan/pkg/check"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// Options control how checks connect
+type Options struct {
+	TLS        bool   // wrap connections in TLS
+	ServerName string // SNI for TLS connections
+	Timeout    time.Duration
+}
+
+// exposureCheck confirms unauthenticated access to one kind of service.
+// run returns nil when access is denied or the service is not what the
+// check expects.
+type exposureCheck struct {
+	info check.Info
+	run  func(address string, opts Options) *models.Finding
+}
+
+var exposureChecks = []exposureCheck{
+	{
+		info: check.Info{
+			Name:        "ftp-anonymous",
+			Description: "FTP anonymous login and root listing",
+			Ports:       []int{21, 990},
+			Services:    []string{"ftp"},
+			Banner:      regexp.MustCompile(`(?i)^220[ -].*ftp`),
+		},
+		run: ftpAnonymous,
+	},
+	{
+		info: check.Info{
+			Name:        "redis-no-auth",
+			Description: "Redis INFO without AUTH",
+			Ports:       []int{6379, 6380},
+			Services:    []string{"redis"},
+			Banner:      regexp.MustCompile(`^Redis `),
+		},
+		run: redisInfo,
+	},
+	{
+		info: check.Info{
+			Name:        "mongodb-no-auth",
+			Description: "MongoDB listDatabases without credentials",
+			Ports:       []int{27017, 27018, 27019},
+			Services:    []string{"mongodb"},
+		},
+		run: mongoDatabases,
+	},
+	{
+		info: check.Info{
+			Name:        "elasticsearch-no-auth",
+			Description: "Elasticsearch /_cat/indices without credentials",
+			Ports:       []int{9200, 9201},
+			Services:    []string{"elasticsearch"},
+		},
+		run: elasticIndices,
+	},
+	{
+		info: check.Info{
+			Name:        "memcached-no-auth",
+			Description: "memcached stats without SASL",
+			Ports:       []int{11211},
+			Services:    []string{"memcached"},
+			Banner:      regexp.MustCompile(`^(STAT|VERSION) `),
+		},
+		run: memcachedStats,
+	},
+}
+
+// Checks returns the exposure checks, for registering with the scanner
+func Checks() []check.Check {
+	checks := make([]check.Check, len(exposureChecks))
+	for i := range exposureChecks {
+		c := exposureChecks[i]
+		checks[i] = check.Func(c.info, func(ctx context.Context, target *check.Target) ([]models.Finding, error) {
+			opts := Options{TLS: target.TLS, ServerName: target.ServerName, Timeout: check.DefaultTimeout}
+			if deadline, ok := ctx.Deadline(); ok {
+				opts.Timeout = time.Until(deadline)
+			}
+			if finding := c.run(target.Address, opts); finding != nil {
+				return []models.Finding{*finding}, nil
+			}
+			return nil, nil
+		})
+	}
+	return checks
+}
+
+// conn is a connection with buffered reads
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// dial connects to address, in TLS when asked, with a deadline covering
+// the whole check
+func dial(address string, opts Options) (*conn, error) {
+	raw, err := net.DialTimeout("tcp", address, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	raw.SetDeadline(time.Now().Add(opts.Timeout))
+
+	c := raw
+	if opts.TLS {
+		tlsConn := ssl.Client(raw, opts.ServerName, nil)
+		if err := tlsConn.Handshake(); err != nil {
+			raw.Close()
+			return nil, err
+		}
+		c = tlsConn
+	}
+	return &conn{Conn: c, r: bufio.NewReader(c)}, nil
+}
+
+// Maximum number of names listed in evidence
+const maxEvidenceNames = 10
+
+// nameList joins names for evidence, eliding past maxEvidenceNames
+func nameList(names []string) string {
+	if len(names) == 0 {
+		return "(none)"
+	}
+	list := ""
+	for i, name := range names {
+		if i == maxEvidenceNames {
+			return list + fmt.Sprintf(", ... (%d more)", len(names)-i)
+		}
+		if i > 0 {
+			list += ", "
+		}
+		list += name
+	}
+	return list
+}
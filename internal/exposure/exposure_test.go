@@ -0,0 +1,365 @@
+package exposure
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/testutil"
+	"github.com/Sh4Ryuu/go-scan/pkg/check"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+var opts = Options{Timeout: 2 * time.Second}
+
+// checkFinding compares a finding's ID and evidence with the wanted ones;
+// an empty want expects no finding
+func checkFinding(t *testing.T, name string, got *models.Finding, wantID, wantEvidence string) {
+	t.Helper()
+	switch {
+	case wantID == "" && got != nil:
+		t.Errorf("%s: got %+v, want no finding", name, got)
+	case wantID == "":
+	case got == nil:
+		t.Errorf("%s: no finding, want %s", name, wantID)
+	case got.ID != wantID || got.Evidence != wantEvidence:
+		t.Errorf("%s: got %s with evidence\n%s\nwant %s with\n%s", name, got.ID, got.Evidence, wantID, wantEvidence)
+	}
+}
+
+// ftpServer is an FTP stand-in whose PASS reply is passCode. Listings go
+// over a passive data connection whose PASV reply names a private address.
+func ftpServer(t *testing.T, greeting string, passCode int) string {
+	return testutil.Serve(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, greeting)
+		var data net.Listener
+		defer func() {
+			if data != nil {
+				data.Close()
+			}
+		}()
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command, _, _ := strings.Cut(strings.TrimSpace(line), " ")
+			switch command {
+			case "USER":
+				fmt.Fprint(conn, "331 Please specify the password.\r\n")
+			case "PASS":
+				if passCode != 230 {
+					fmt.Fprintf(conn, "%d Login incorrect.\r\n", passCode)
+					continue
+				}
+				fmt.Fprint(conn, "230 Login successful.\r\n")
+			case "PASV":
+				if data, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+					return
+				}
+				port := data.Addr().(*net.TCPAddr).Port
+				fmt.Fprintf(conn, "227 Entering Passive Mode (10,0,0,5,%d,%d).\r\n", port/256, port%256)
+			case "NLST":
+				dataConn, err := data.Accept()
+				if err != nil {
+					return
+				}
+				fmt.Fprint(conn, "150 Here comes the directory listing.\r\n")
+				fmt.Fprint(dataConn, "pub\r\nincoming\r\n")
+				dataConn.Close()
+				fmt.Fprint(conn, "226 Directory send OK.\r\n")
+			case "QUIT":
+				fmt.Fprint(conn, "221 Goodbye.\r\n")
+				return
+			default:
+				fmt.Fprint(conn, "500 Unknown command.\r\n")
+			}
+		}
+	})
+}
+
+func TestFTPAnonymous(t *testing.T) {
+	tests := []struct {
+		name     string
+		greeting string
+		passCode int
+		evidence string
+	}{
+		{"anonymous", "220 (vsFTPd 3.0.5)\r\n", 230, "USER anonymous: 230 Login successful.; listing: pub, incoming"},
+		{"multi-line greeting", "220-Welcome\r\n220-to the archive\r\n220 ready\r\n", 230, "USER anonymous: 230 Login successful.; listing: pub, incoming"},
+		{"denied", "220 (vsFTPd 3.0.5)\r\n", 530, ""},
+		{"not FTP", "SSH-2.0-OpenSSH_9.6\r\n", 230, ""},
+	}
+	for _, tt := range tests {
+		wantID := ""
+		if tt.evidence != "" {
+			wantID = "ftp-anonymous"
+		}
+		checkFinding(t, tt.name, ftpAnonymous(ftpServer(t, tt.greeting, tt.passCode), opts), wantID, tt.evidence)
+	}
+}
+
+// lineServer answers the first line a client sends with reply
+func lineServer(t *testing.T, want, reply string) string {
+	return testutil.Serve(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		var request strings.Builder
+		for !strings.Contains(request.String(), want) {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			request.WriteString(line)
+		}
+		fmt.Fprint(conn, reply)
+	})
+}
+
+func TestRedisInfo(t *testing.T) {
+	info := "# Server\r\nredis_version:7.2.4\r\nos:Linux 6.1.0 x86_64\r\n\r\n# Clients\r\nconnected_clients:1\r\n\r\n" +
+		"# Replication\r\nrole:master\r\n\r\n# Keyspace\r\ndb0:keys=12,expires=0,avg_ttl=0\r\ndb3:keys=1,expires=1,avg_ttl=5\r\n"
+	tests := []struct {
+		name     string
+		reply    string
+		evidence string
+	}{
+		{
+			"open",
+			"$" + strconv.Itoa(len(info)) + "\r\n" + info + "\r\n",
+			"redis_version:7.2.4, os:Linux 6.1.0 x86_64, role:master, connected_clients:1; keyspace: db0 keys=12, db3 keys=1",
+		},
+		{"empty keyspace", "$32\r\n# Server\r\nredis_version:6.0.16\r\n\r\n", "redis_version:6.0.16; keyspace: (none)"},
+		{"password required", "-NOAUTH Authentication required.\r\n", ""},
+		{"protected mode", "-DENIED Redis is running in protected mode\r\n", ""},
+		{"bulk reply without a version", "$6\r\nfoobar\r\n", ""},
+	}
+	for _, tt := range tests {
+		wantID := ""
+		if tt.evidence != "" {
+			wantID = "redis-no-auth"
+		}
+		checkFinding(t, tt.name, redisInfo(lineServer(t, "INFO\r\n", tt.reply), opts), wantID, tt.evidence)
+	}
+}
+
+func TestMemcachedStats(t *testing.T) {
+	stats := "STAT pid 1\r\nSTAT version 1.6.21\r\nSTAT curr_connections 2\r\nSTAT curr_items 5\r\nSTAT bytes 420\r\nEND\r\n"
+	tests := []struct {
+		name     string
+		reply    string
+		evidence string
+	}{
+		{"open", stats, "version 1.6.21, curr_items 5, bytes 420, curr_connections 2"},
+		{"SASL required", "ERROR\r\n", ""},
+		{"no version", "STAT pid 1\r\nEND\r\n", ""},
+		{"cut short", "STAT pid 1\r\nSTAT version 1.6.21\r\n", ""},
+	}
+	for _, tt := range tests {
+		wantID := ""
+		if tt.evidence != "" {
+			wantID = "memcached-no-auth"
+		}
+		checkFinding(t, tt.name, memcachedStats(lineServer(t, "stats\r\n", tt.reply), opts), wantID, tt.evidence)
+	}
+}
+
+// bsonDouble encodes a double element
+func bsonDouble(name string, value float64) []byte {
+	element := append([]byte{0x01}, name...)
+	element = append(element, 0)
+	return binary.LittleEndian.AppendUint64(element, math.Float64bits(value))
+}
+
+// bsonBool encodes a boolean element
+func bsonBool(name string, value bool) []byte {
+	element := append([]byte{0x08}, name...)
+	if value {
+		return append(element, 0, 1)
+	}
+	return append(element, 0, 0)
+}
+
+// bsonArray encodes an array of documents
+func bsonArray(name string, docs ...[]byte) []byte {
+	var elements [][]byte
+	for i, doc := range docs {
+		element := append([]byte{0x03}, strconv.Itoa(i)...)
+		elements = append(elements, append(append(element, 0), doc...))
+	}
+	element := append([]byte{0x04}, name...)
+	return append(append(element, 0), bsonDocument(elements...)...)
+}
+
+// mongoServer answers listDatabases on admin with reply, as an OP_MSG
+func mongoServer(t *testing.T, reply []byte) string {
+	return testutil.Serve(t, func(conn net.Conn) {
+		header := make([]byte, mongoHeaderLen)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		body := make([]byte, binary.LittleEndian.Uint32(header)-mongoHeaderLen)
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+		request, _, err := parseBSON(body[5:])
+		if err != nil || request["listDatabases"] != int32(1) || request["$db"] != "admin" {
+			t.Errorf("request %v, %v", request, err)
+			return
+		}
+
+		msg := make([]byte, mongoHeaderLen+5)
+		binary.LittleEndian.PutUint32(msg[8:], binary.LittleEndian.Uint32(header[4:])) // responseTo
+		binary.LittleEndian.PutUint32(msg[12:], opMsg)
+		msg = append(msg, reply...)
+		binary.LittleEndian.PutUint32(msg, uint32(len(msg)))
+		conn.Write(msg)
+	})
+}
+
+func TestMongoDatabases(t *testing.T) {
+	open := bsonDocument(
+		bsonArray("databases",
+			bsonDocument(bsonString("name", "admin"), bsonDouble("sizeOnDisk", 40960), bsonBool("empty", false)),
+			bsonDocument(bsonString("name", "shop"), bsonDouble("sizeOnDisk", 79040), bsonBool("empty", false)),
+		),
+		bsonDouble("totalSize", 120000),
+		bsonDouble("ok", 1),
+	)
+	unauthorized := bsonDocument(
+		bsonDouble("ok", 0),
+		bsonString("errmsg", "command listDatabases requires authentication"),
+		bsonInt32("code", 13),
+		bsonString("codeName", "Unauthorized"),
+	)
+
+	checkFinding(t, "open", mongoDatabases(mongoServer(t, open), opts), "mongodb-no-auth", "2 databases: admin, shop; total size 120000 bytes")
+	checkFinding(t, "unauthorized", mongoDatabases(mongoServer(t, unauthorized), opts), "", "")
+	checkFinding(t, "not MongoDB", mongoDatabases(lineServer(t, "", "HTTP/1.1 400 Bad Request\r\n\r\n"), opts), "", "")
+}
+
+func TestParseBSONErrors(t *testing.T) {
+	for _, data := range [][]byte{
+		{1, 2},
+		{5, 0, 0, 0, 1},                  // no terminating zero
+		{9, 0, 0, 0, 0x10, 'a', 0, 1, 0}, // truncated int32
+		{7, 0, 0, 0, 0x10, 'a', 0},       // unterminated name
+		append(bsonDocument([]byte{0x0e, 'x', 0, 0, 0, 0, 0}), 0), // unsupported symbol type
+	} {
+		if doc, _, err := parseBSON(data); err == nil {
+			t.Errorf("parseBSON(%x) = %v", data, doc)
+		}
+	}
+}
+
+func TestElasticIndices(t *testing.T) {
+	elastic := func(root string, status int) string {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/":
+				w.WriteHeader(status)
+				fmt.Fprint(w, root)
+			case "/_cat/indices":
+				if got := r.URL.Query().Get("h"); got != "index,docs.count" {
+					t.Errorf("columns %q", got)
+				}
+				fmt.Fprint(w, "logs-2024.01 1200\norders 35\n.kibana\n")
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		t.Cleanup(server.Close)
+		return server.Listener.Addr().String()
+	}
+
+	tests := []struct {
+		name, root string
+		status     int
+		title      string
+		evidence   string
+	}{
+		{
+			"Elasticsearch",
+			`{"name": "node-1", "cluster_name": "prod", "version": {"number": "8.12.0", "build_flavor": "default"}, "tagline": "You Know, for Search"}`,
+			http.StatusOK,
+			"Elasticsearch accepts requests without authentication",
+			"cluster prod, Elasticsearch 8.12.0; 3 indices: logs-2024.01 (1200 docs), orders (35 docs), .kibana",
+		},
+		{
+			"OpenSearch",
+			`{"cluster_name": "search", "version": {"distribution": "opensearch", "number": "2.11.1"}}`,
+			http.StatusOK,
+			"OpenSearch accepts requests without authentication",
+			"cluster search, OpenSearch 2.11.1; 3 indices: logs-2024.01 (1200 docs), orders (35 docs), .kibana",
+		},
+		{"credentials required", `{"error": "security_exception"}`, http.StatusUnauthorized, "", ""},
+		{"another web server", `<html><title>Welcome</title></html>`, http.StatusOK, "", ""},
+		{"JSON that is not a cluster", `{"status": "ok"}`, http.StatusOK, "", ""},
+	}
+	for _, tt := range tests {
+		finding := elasticIndices(elastic(tt.root, tt.status), opts)
+		wantID := ""
+		if tt.title != "" {
+			wantID = "elasticsearch-no-auth"
+		}
+		checkFinding(t, tt.name, finding, wantID, tt.evidence)
+		if finding != nil && finding.Title != tt.title {
+			t.Errorf("%s: title %q, want %q", tt.name, finding.Title, tt.title)
+		}
+	}
+}
+
+func TestChecks(t *testing.T) {
+	address := lineServer(t, "stats\r\n", "STAT version 1.6.21\r\nEND\r\n")
+	_, port, _ := net.SplitHostPort(address)
+	portNumber, _ := strconv.Atoi(port)
+
+	registry := check.NewRegistry()
+	for _, c := range Checks() {
+		if err := registry.Register(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	target := &check.Target{
+		Host:     "127.0.0.1",
+		Address:  address,
+		Port:     portNumber,
+		Protocol: check.TCP,
+		Result:   &models.ScanResult{Port: portNumber, Protocol: check.TCP, Service: "memcached"},
+	}
+	findings, errs := registry.Run(context.Background(), target, check.RunOptions{Timeout: 2 * time.Second})
+	if len(errs) != 0 || len(findings) != 1 || findings[0].Check != "memcached-no-auth" || findings[0].Evidence != "version 1.6.21" {
+		t.Errorf("got %+v, errors %+v", findings, errs)
+	}
+}
+
+func TestNameList(t *testing.T) {
+	var names []string
+	for i := 1; i <= 12; i++ {
+		names = append(names, "db"+strconv.Itoa(i))
+	}
+	tests := []struct {
+		names []string
+		want  string
+	}{
+		{nil, "(none)"},
+		{names[:2], "db1, db2"},
+		{names[:10], "db1, db2, db3, db4, db5, db6, db7, db8, db9, db10"},
+		{names, "db1, db2, db3, db4, db5, db6, db7, db8, db9, db10, ... (2 more)"},
+	}
+	for _, tt := range tests {
+		if got := nameList(tt.names); got != tt.want {
+			t.Errorf("nameList(%v) = %q, want %q", tt.names, got, tt.want)
+		}
+	}
+}
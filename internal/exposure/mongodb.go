@@ -0,0 +1,231 @@
+package exposure
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// MongoDB wire protocol
+const (
+	opMsg          = 2013
+	maxMongoReply  = 16 * 1024 * 1024
+	mongoHeaderLen = 16
+)
+
+// mongoDatabases runs listDatabases against the admin database, which a
+// server with authorization enabled refuses
+func mongoDatabases(address string, opts Options) *models.Finding {
+	c, err := dial(address, opts)
+	if err != nil {
+		return nil
+	}
+	defer c.Close()
+
+	reply, err := mongoCommand(c, bsonDocument(
+		bsonInt32("listDatabases", 1),
+		bsonString("$db", "admin"),
+	))
+	if err != nil || !bsonOK(reply) {
+		return nil // Unauthorized, or not MongoDB
+	}
+
+	var names []string
+	databases, _ := reply["databases"].([]interface{})
+	for _, db := range databases {
+		if doc, ok := db.(map[string]interface{}); ok {
+			if name, ok := doc["name"].(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+
+	evidence := fmt.Sprintf("%d databases: %s", len(names), nameList(names))
+	if size, ok := bsonNumber(reply["totalSize"]); ok {
+		evidence += fmt.Sprintf("; total size %d bytes", int64(size))
+	}
+
+	return &models.Finding{
+		ID:       "mongodb-no-auth",
+		Title:    "MongoDB accepts commands without authentication",
+		Severity: models.SeverityCritical,
+		Detail:   "listDatabases answered without credentials",
+		Evidence: evidence,
+	}
+}
+
+// mongoCommand sends a command as an OP_MSG and decodes the reply body
+func mongoCommand(c *conn, command []byte) (map[string]interface{}, error) {
+	msg := make([]byte, mongoHeaderLen+5, mongoHeaderLen+5+len(command))
+	binary.LittleEndian.PutUint32(msg[4:], 1) // request ID
+	binary.LittleEndian.PutUint32(msg[12:], opMsg)
+	// flag bits and section kind 0 stay zero
+	msg = append(msg, command...)
+	binary.LittleEndian.PutUint32(msg, uint32(len(msg)))
+	if _, err := c.Write(msg); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, mongoHeaderLen)
+	if _, err := io.ReadFull(c.r, header); err != nil {
+		return nil, err
+	}
+	length := binary.LittleEndian.Uint32(header)
+	if length < mongoHeaderLen+5 || length > maxMongoReply {
+		return nil, fmt.Errorf("bad MongoDB reply length %d", length)
+	}
+	if op := binary.LittleEndian.Uint32(header[12:]); op != opMsg {
+		return nil, fmt.Errorf("unexpected MongoDB opcode %d", op)
+	}
+	body := make([]byte, length-mongoHeaderLen)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return nil, err
+	}
+	if body[4] != 0 {
+		return nil, fmt.Errorf("unexpected OP_MSG section kind %d", body[4])
+	}
+	doc, _, err := parseBSON(body[5:])
+	return doc, err
+}
+
+// bsonDocument wraps encoded elements into a document
+func bsonDocument(elements ...[]byte) []byte {
+	doc := make([]byte, 4)
+	for _, element := range elements {
+		doc = append(doc, element...)
+	}
+	doc = append(doc, 0)
+	binary.LittleEndian.PutUint32(doc, uint32(len(doc)))
+	return doc
+}
+
+// bsonInt32 encodes an int32 element
+func bsonInt32(name string, value int32) []byte {
+	element := append([]byte{0x10}, name...)
+	element = append(element, 0)
+	return binary.LittleEndian.AppendUint32(element, uint32(value))
+}
+
+// bsonString encodes a string element
+func bsonString(name, value string) []byte {
+	element := append([]byte{0x02}, name...)
+	element = append(element, 0)
+	element = binary.LittleEndian.AppendUint32(element, uint32(len(value)+1))
+	element = append(element, value...)
+	return append(element, 0)
+}
+
+// bsonOK reports whether a command reply has ok: 1
+func bsonOK(doc map[string]interface{}) bool {
+	ok, _ := bsonNumber(doc["ok"])
+	return ok == 1
+}
+
+// bsonNumber converts any BSON number to a float64
+func bsonNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// parseBSON decodes a document into a map, arrays into slices. Only the
+// types command replies use are decoded; others are skipped as nil.
+func parseBSON(data []byte) (map[string]interface{}, int, error) {
+	if len(data) < 5 {
+		return nil, 0, fmt.Errorf("short BSON document")
+	}
+	size := int(binary.LittleEndian.Uint32(data))
+	if size < 5 || size > len(data) || data[size-1] != 0 {
+		return nil, 0, fmt.Errorf("bad BSON document size %d", size)
+	}
+
+	doc := make(map[string]interface{})
+	pos := 4
+	for pos < size-1 {
+		kind := data[pos]
+		end := pos + 1
+		for end < size && data[end] != 0 {
+			end++
+		}
+		if end >= size-1 {
+			return nil, 0, fmt.Errorf("unterminated BSON element name")
+		}
+		name := string(data[pos+1 : end])
+		pos = end + 1
+
+		value, n, err := parseBSONValue(kind, data[pos:size-1])
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %v", name, err)
+		}
+		doc[name] = value
+		pos += n
+	}
+	return doc, size, nil
+}
+
+// bsonFixedSizes are the lengths of fixed-size BSON types: double,
+// ObjectId, bool, datetime, null, int32, timestamp, int64, decimal128,
+// and the max and min keys
+var bsonFixedSizes = map[byte]int{
+	0x01: 8, 0x07: 12, 0x08: 1, 0x09: 8, 0x0a: 0, 0x10: 4, 0x11: 8, 0x12: 8, 0x13: 16, 0x7f: 0, 0xff: 0,
+}
+
+// parseBSONValue decodes one element value, returning it and its length
+func parseBSONValue(kind byte, data []byte) (interface{}, int, error) {
+	if n, ok := bsonFixedSizes[kind]; ok {
+		if len(data) < n {
+			return nil, 0, fmt.Errorf("truncated value")
+		}
+		switch kind {
+		case 0x01:
+			return math.Float64frombits(binary.LittleEndian.Uint64(data)), n, nil
+		case 0x08:
+			return data[0] != 0, n, nil
+		case 0x10:
+			return int32(binary.LittleEndian.Uint32(data)), n, nil
+		case 0x12:
+			return int64(binary.LittleEndian.Uint64(data)), n, nil
+		}
+		return nil, n, nil
+	}
+
+	if len(data) < 4 {
+		return nil, 0, fmt.Errorf("truncated value")
+	}
+	length := int(binary.LittleEndian.Uint32(data))
+	switch kind {
+	case 0x02: // string
+		if length < 1 || 4+length > len(data) {
+			return nil, 0, fmt.Errorf("bad string length %d", length)
+		}
+		return strings.TrimSuffix(string(data[4:4+length]), "\x00"), 4 + length, nil
+	case 0x03: // document
+		return parseBSON(data)
+	case 0x04: // array, a document keyed "0", "1", ...
+		doc, n, err := parseBSON(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		array := make([]interface{}, len(doc))
+		for i := range array {
+			array[i] = doc[fmt.Sprint(i)]
+		}
+		return array, n, nil
+	case 0x05: // binary: length, subtype, bytes
+		if length < 0 || 5+length > len(data) {
+			return nil, 0, fmt.Errorf("bad binary length %d", length)
+		}
+		return nil, 5 + length, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported BSON type 0x%02x", kind)
+}
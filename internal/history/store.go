@@ -0,0 +1,201 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+const reportExt = ".json"
+
+// Store keeps saved scan reports as JSON files in a directory
+type Store struct {
+	Dir string
+}
+
+// Entry describes a saved report without loading its results
+type Entry struct {
+	ID        string    `json:"id"`
+	Host      string    `json:"host"`
+	StartTime time.Time `json:"start_time"`
+	OpenPorts int       `json:"open_ports"`
+	Path      string    `json:"path"`
+}
+
+// DefaultDir returns the default history directory
+func DefaultDir() string {
+	if dir := os.Getenv("GOSCAN_HISTORY"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".go-scan-history"
+	}
+	return filepath.Join(home, ".go-scan", "history")
+}
+
+// NewStore creates a store rooted at dir
+func NewStore(dir string) *Store {
+	if dir == "" {
+		dir = DefaultDir()
+	}
+	return &Store{Dir: dir}
+}
+
+// Save writes a report to the store and assigns it an ID. IDs are the
+// start time in milliseconds and the host; a report that would reuse an
+// existing ID gets a numeric suffix instead of replacing the file.
+func (s *Store) Save(report *models.ScanReport) (string, error) {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return "", fmt.Errorf("create history directory: %v", err)
+	}
+
+	started := time.Now()
+	if report.Stats != nil && !report.Stats.StartTime.IsZero() {
+		started = report.Stats.StartTime
+	}
+	base := fmt.Sprintf("%s-%s", started.UTC().Format("20060102T150405.000Z"), sanitize(report.Host))
+
+	for n := 1; ; n++ {
+		report.ID = base
+		if n > 1 {
+			report.ID = fmt.Sprintf("%s-%d", base, n)
+		}
+		data, err := marshalReport(report)
+		if err != nil {
+			return "", err
+		}
+		file, err := os.OpenFile(s.path(report.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = file.Write(data)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", fmt.Errorf("write report %s: %v", report.ID, err)
+		}
+		return report.ID, nil
+	}
+}
+
+// Load reads the report with the given ID
+func (s *Store) Load(id string) (*models.ScanReport, error) {
+	return ReadReport(s.path(id))
+}
+
+// List returns all saved reports, oldest first
+func (s *Store) List() ([]Entry, error) {
+	files, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), reportExt) {
+			continue
+		}
+		path := filepath.Join(s.Dir, file.Name())
+		report, err := ReadReport(path)
+		if err != nil {
+			continue
+		}
+		entry := Entry{
+			ID:        strings.TrimSuffix(file.Name(), reportExt),
+			Host:      report.Host,
+			OpenPorts: len(report.OpenResults()),
+			Path:      path,
+		}
+		if report.Stats != nil {
+			entry.StartTime = report.Stats.StartTime
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].StartTime.Equal(entries[j].StartTime) {
+			return entries[i].ID < entries[j].ID
+		}
+		return entries[i].StartTime.Before(entries[j].StartTime)
+	})
+
+	return entries, nil
+}
+
+// Latest returns the most recent report for a host, or nil if none exists
+func (s *Store) Latest(host string) (*models.ScanReport, error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Host == host {
+			return ReadReport(entries[i].Path)
+		}
+	}
+	return nil, nil
+}
+
+// Delete removes the report with the given ID
+func (s *Store) Delete(id string) error {
+	return os.Remove(s.path(id))
+}
+
+// path returns the file path for a report ID
+func (s *Store) path(id string) string {
+	return filepath.Join(s.Dir, filepath.Base(id)+reportExt)
+}
+
+// ReadReport reads a JSON scan report from a file
+func ReadReport(path string) (*models.ScanReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var report models.ScanReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("parse report %s: %v", path, err)
+	}
+	return &report, nil
+}
+
+// WriteReport writes a scan report to a file as indented JSON
+func WriteReport(path string, report *models.ScanReport) error {
+	data, err := marshalReport(report)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// marshalReport encodes a report the way the store writes it
+func marshalReport(report *models.ScanReport) ([]byte, error) {
+	return json.MarshalIndent(report, "", "  ")
+}
+
+// sanitize makes a host name safe for use in a file name
+func sanitize(host string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, host)
+}
@@ -0,0 +1,103 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+func testReport(host string, started time.Time, open ...int) *models.ScanReport {
+	report := &models.ScanReport{Host: host, Stats: &models.ScanStats{StartTime: started}}
+	for _, port := range open {
+		report.Results = append(report.Results, models.ScanResult{Host: host, Port: port, Protocol: "tcp", Status: "open"})
+	}
+	return report
+}
+
+func TestSaveIDs(t *testing.T) {
+	store := NewStore(t.TempDir())
+	started := time.Date(2024, 1, 22, 12, 0, 0, 250e6, time.UTC)
+
+	tests := []struct {
+		report *models.ScanReport
+		want   string
+	}{
+		{testReport("example.com", started, 22), "20240122T120000.250Z-example.com"},
+		{testReport("example.com", started, 22, 443), "20240122T120000.250Z-example.com-2"},
+		{testReport("example.com", started), "20240122T120000.250Z-example.com-3"},
+		{testReport("example.com", started.Add(time.Millisecond)), "20240122T120000.251Z-example.com"},
+		{testReport("fe80::1%eth0", started), "20240122T120000.250Z-fe80__1_eth0"},
+	}
+	for _, tt := range tests {
+		id, err := store.Save(tt.report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != tt.want || tt.report.ID != tt.want {
+			t.Errorf("got ID %q, want %q", id, tt.want)
+		}
+	}
+
+	// Reports saved under the same start time keep their own results
+	report, err := store.Load("20240122T120000.250Z-example.com-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.OpenResults()) != 2 {
+		t.Errorf("loaded %+v", report.Results)
+	}
+}
+
+func TestListAndLatest(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if entries, err := store.List(); err != nil || entries != nil {
+		t.Errorf("empty store: got %v, %v", entries, err)
+	}
+
+	started := time.Date(2024, 1, 22, 12, 0, 0, 0, time.UTC)
+	for _, report := range []*models.ScanReport{
+		testReport("a.example", started.Add(time.Hour), 80),
+		testReport("b.example", started, 22),
+		testReport("a.example", started, 22, 80),
+		testReport("a.example", started.Add(time.Hour), 80, 443),
+	} {
+		if _, err := store.Save(report); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	want := []string{
+		"20240122T120000.000Z-a.example",
+		"20240122T120000.000Z-b.example",
+		"20240122T130000.000Z-a.example",
+		"20240122T130000.000Z-a.example-2",
+	}
+	if len(ids) != len(want) {
+		t.Fatalf("got %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Errorf("entry %d is %s, want %s", i, ids[i], want[i])
+		}
+	}
+
+	latest, err := store.Latest("a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest == nil || latest.ID != want[3] {
+		t.Errorf("latest %+v", latest)
+	}
+	if latest, _ := store.Latest("c.example"); latest != nil {
+		t.Errorf("latest of an unknown host: %+v", latest)
+	}
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"io"
+	"math"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sh4Ryuu/go-scan/internal/testutil"
+)
+
+// testdata/registry.txt is the exposition below, checked by hand against
+// the Prometheus text format
+
+func TestRegistryExposition(t *testing.T) {
+	r := NewRegistry()
+
+	probes := r.NewCounter("test_probes_total", "Probes sent, by protocol\nand state.", "protocol", "state")
+	probes.Inc("tcp", "open")
+	probes.Add(2, "tcp", "open")
+	probes.Inc("udp", "open|filtered")
+	probes.Add(-5, "tcp", "open") // counters never go down
+	probes.Inc("tcp")             // missing label values are empty
+
+	hosts := r.NewGauge("test_host_info", `Label values escape \, " and newlines.`, "host")
+	hosts.Set(1, `C:\scans "prod"`)
+	hosts.Set(1, "two\nlines")
+
+	limits := r.NewGauge("test_limits", "A gauge without labels.")
+	limits.Set(math.Inf(1))
+	limits.Add(-1)
+
+	r.NewCounter("test_unused_total", "A family without series.", "host")
+
+	durations := r.NewHistogram("test_duration_seconds", "Run time.", []float64{5, 0.5, 1}, "status")
+	for _, v := range []float64{0.2, 0.5, 0.75, 3, 12} {
+		durations.Observe(v, "success")
+	}
+	durations.Observe(1, "error")
+
+	server := httptest.NewServer(r.Handler())
+	defer server.Close()
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("content type %q", got)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := testutil.Fixture(t, "registry.txt"); string(body) != string(want) {
+		t.Errorf("got\n%s\nwant\n%s", body, want)
+	}
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/ssl"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// ExpiringSoonDays is how close to expiry a certificate counts as expiring
+// soon; it matches the threshold of the certificate findings
+const ExpiringSoonDays = ssl.ExpiringSoonDays
+
+// Default is the registry that go-scan records its telemetry in
+var Default = NewRegistry()
+
+var (
+	probesSent = Default.NewCounter("goscan_probes_total",
+		"Probes sent, by protocol and resulting port state.", "protocol", "state")
+	openPorts = Default.NewGauge("goscan_open_ports",
+		"Open TCP ports found on a host by its most recent scan.", "host")
+	scansTotal = Default.NewCounter("goscan_scans_total",
+		"Completed scans per host.", "host")
+	scanDuration = Default.NewGauge("goscan_scan_duration_seconds",
+		"Duration of the most recent scan of a host.", "host")
+	scanRate = Default.NewGauge("goscan_scan_ports_per_second",
+		"Ports scanned per second by the most recent scan of a host.", "host")
+	scanErrors = Default.NewCounter("goscan_scan_errors_total",
+		"Probe errors other than refused or timed-out connections.", "host")
+	certsExpiringSoon = Default.NewGauge("goscan_certificates_expiring_soon",
+		"Certificates on a host expiring within 30 days, as of its most recent scan.", "host")
+	certsExpired = Default.NewGauge("goscan_certificates_expired",
+		"Expired certificates on a host, as of its most recent scan.", "host")
+	nmapRunDuration = Default.NewHistogram("goscan_nmap_run_duration_seconds",
+		"Run time of nmap runs, each covering every script on every open port of a host.",
+		[]float64{1, 2.5, 5, 10, 30, 60, 120, 300}, "status")
+)
+
+// ObserveProbe records a single probe and the state it found
+func ObserveProbe(protocol, state string) {
+	probesSent.Inc(protocol, state)
+}
+
+// ObserveScan records the outcome of a completed scan
+func ObserveScan(stats *models.ScanStats, results []models.ScanResult) {
+	host := stats.TargetHost
+
+	scansTotal.Inc(host)
+	scanDuration.Set(stats.DurationSeconds, host)
+	scanRate.Set(stats.PortsPerSec, host)
+	scanErrors.Add(float64(stats.ErrorCount), host)
+
+	open, expiring, expired := 0, 0, 0
+	soon := stats.EndTime.Add(ExpiringSoonDays * 24 * time.Hour)
+	for _, result := range results {
+		// Like the scan stats, only TCP counts: UDP "open" just means a
+		// probe got a reply
+		if result.Status != "open" || result.Protocol != "tcp" {
+			continue
+		}
+		open++
+		if result.SSLInfo == nil {
+			continue
+		}
+		switch {
+		case result.SSLInfo.IsExpired:
+			expired++
+		case result.SSLInfo.ValidTo.Before(soon):
+			expiring++
+		}
+	}
+
+	openPorts.Set(float64(open), host)
+	certsExpiringSoon.Set(float64(expiring), host)
+	certsExpired.Set(float64(expired), host)
+}
+
+// ObserveNmapRun records the run time of one nmap run against a host
+func ObserveNmapRun(duration time.Duration, status string) {
+	nmapRunDuration.Observe(duration.Seconds(), status)
+}
@@ -0,0 +1,151 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Sh4Ryuu/go-scan/internal/notify"
+	"github.com/Sh4Ryuu/go-scan/internal/scanner"
+	"github.com/Sh4Ryuu/go-scan/internal/schedule"
+)
+
+// Config describes what the monitor scans and where its alerts go
+type Config struct {
+	Targets []*TargetSet `json:"targets"`
+	Alerts  AlertConfig  `json:"alerts"`
+}
+
+// TargetSet is a group of hosts scanned together on one schedule
+type TargetSet struct {
+	Name     string          `json:"name"`
+	Hosts    []string        `json:"hosts"`
+	Schedule string          `json:"schedule"` // interval ("30m") or cron expression
+	Scan     *scanner.Config `json:"scan"`
+
+	schedule schedule.Schedule
+}
+
+// AlertConfig selects the alert destinations. Stdout, File and Webhooks
+// are shorthands; Sinks allows Slack, email, templates, filters and limits.
+type AlertConfig struct {
+	Stdout   bool                `json:"stdout"`
+	File     string              `json:"file,omitempty"`
+	Webhooks []string            `json:"webhooks,omitempty"`
+	Sinks    []notify.SinkConfig `json:"sinks,omitempty"`
+}
+
+// SinkConfigs returns the shorthand destinations and Sinks as sink configs
+func (a AlertConfig) SinkConfigs() []notify.SinkConfig {
+	var configs []notify.SinkConfig
+	if a.Stdout {
+		configs = append(configs, notify.SinkConfig{Type: notify.TypeStdout})
+	}
+	if a.File != "" {
+		configs = append(configs, notify.SinkConfig{Type: notify.TypeFile, Path: a.File})
+	}
+	for _, url := range a.Webhooks {
+		configs = append(configs, notify.SinkConfig{Type: notify.TypeWebhook, URL: url})
+	}
+	return append(configs, a.Sinks...)
+}
+
+// Dispatcher builds a dispatcher delivering to every configured sink
+func (a AlertConfig) Dispatcher() (*notify.Dispatcher, error) {
+	dispatcher := notify.NewDispatcher()
+	for i, config := range a.SinkConfigs() {
+		sink, err := notify.BuildSink(config)
+		if err != nil {
+			return nil, fmt.Errorf("alert sink %d (%s): %v", i+1, config.Type, err)
+		}
+		dispatcher.Add(sink)
+	}
+	return dispatcher, nil
+}
+
+// LoadConfig reads a monitor configuration from a JSON file. Scan settings
+// omitted for a target set take the scanner defaults.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw struct {
+		Targets []struct {
+			Name     string          `json:"name"`
+			Hosts    []string        `json:"hosts"`
+			Schedule string          `json:"schedule"`
+			Scan     json.RawMessage `json:"scan"`
+		} `json:"targets"`
+		Alerts AlertConfig `json:"alerts"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse %s: %v", path, err)
+	}
+
+	config := &Config{Alerts: raw.Alerts}
+	for _, t := range raw.Targets {
+		scan := scanner.DefaultConfig()
+		if len(t.Scan) > 0 {
+			if err := json.Unmarshal(t.Scan, scan); err != nil {
+				return nil, fmt.Errorf("target %q: scan settings: %v", t.Name, err)
+			}
+		}
+		config.Targets = append(config.Targets, &TargetSet{
+			Name:     t.Name,
+			Hosts:    t.Hosts,
+			Schedule: t.Schedule,
+			Scan:     scan,
+		})
+	}
+
+	return config, nil
+}
+
+// Validate checks the configuration and parses the schedules
+func (c *Config) Validate() error {
+	if len(c.Targets) == 0 {
+		return fmt.Errorf("no target sets configured")
+	}
+
+	for i, target := range c.Targets {
+		if target.Name == "" {
+			target.Name = fmt.Sprintf("targets-%d", i+1)
+		}
+		if len(target.Hosts) == 0 {
+			return fmt.Errorf("target %q: no hosts", target.Name)
+		}
+		if target.Scan == nil {
+			target.Scan = scanner.DefaultConfig()
+		}
+
+		sched, err := schedule.Parse(target.Schedule)
+		if err != nil {
+			return fmt.Errorf("target %q: %v", target.Name, err)
+		}
+		target.schedule = sched
+
+		for _, host := range target.Hosts {
+			if host == "" {
+				return fmt.Errorf("target %q: empty host", target.Name)
+			}
+		}
+
+		// Validate the shared settings once, keeping the CA pool, rules,
+		// databases and plugin checks they load for every run to reuse
+		scan := *target.Scan
+		scan.Host = target.Hosts[0]
+		if err := scan.Validate(); err != nil {
+			return fmt.Errorf("target %q: %v", target.Name, err)
+		}
+		scan.Host = ""
+		*target.Scan = scan
+	}
+
+	if _, err := c.Alerts.Dispatcher(); err != nil {
+		return err
+	}
+
+	return nil
+}
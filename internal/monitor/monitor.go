@@ -0,0 +1,238 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/diff"
+	"github.com/Sh4Ryuu/go-scan/internal/history"
+	"github.com/Sh4Ryuu/go-scan/internal/nmap"
+	"github.com/Sh4Ryuu/go-scan/internal/notify"
+	"github.com/Sh4Ryuu/go-scan/internal/output"
+	"github.com/Sh4Ryuu/go-scan/internal/scanner"
+	"github.com/Sh4Ryuu/go-scan/internal/schedule"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// Monitor rescans target sets on their schedules and raises alerts when
+// a run differs from the previous one
+type Monitor struct {
+	config   *Config
+	store    *history.Store
+	notifier *notify.Dispatcher
+	nmap     *nmap.Runner // shared so target sets together respect its host limit
+
+	// Logf reports run progress; it defaults to stderr
+	Logf func(format string, args ...interface{})
+
+	mu       sync.Mutex
+	previous map[string]*models.ScanReport
+}
+
+// New creates a monitor for a validated configuration. When store is
+// non-nil every run is saved to it and the latest saved report of each
+// host seeds the comparison.
+func New(config *Config, store *history.Store, notifier *notify.Dispatcher) *Monitor {
+	return &Monitor{
+		config:   config,
+		store:    store,
+		notifier: notifier,
+		nmap:     nmap.NewRunner(nmap.DefaultParallelHosts),
+		previous: make(map[string]*models.ScanReport),
+		Logf: func(format string, args ...interface{}) {
+			fmt.Fprintf(os.Stderr, format+"\n", args...)
+		},
+	}
+}
+
+// Run scans every target set on its schedule until ctx is cancelled.
+// Interval schedules run immediately and then once per interval; cron
+// schedules wait for their first matching minute.
+func (m *Monitor) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, target := range m.config.Targets {
+		wg.Add(1)
+		go func(target *TargetSet) {
+			defer wg.Done()
+			m.loop(ctx, target)
+		}(target)
+	}
+	wg.Wait()
+	return nil
+}
+
+// loop runs a single target set on its schedule
+func (m *Monitor) loop(ctx context.Context, target *TargetSet) {
+	next := time.Now()
+	if _, isInterval := target.schedule.(schedule.Interval); !isInterval {
+		next = target.schedule.Next(next)
+	}
+
+	for {
+		if next.IsZero() {
+			m.Logf("monitor %s: schedule %q never fires again", target.Name, target.Schedule)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		started := time.Now()
+		m.RunOnce(ctx, target)
+		next = target.schedule.Next(started)
+		if !next.After(time.Now()) {
+			next = target.schedule.Next(time.Now())
+		}
+	}
+}
+
+// RunOnce scans every host of a target set once, dispatches alerts for
+// changes and returns them
+func (m *Monitor) RunOnce(ctx context.Context, target *TargetSet) []notify.Event {
+	var events []notify.Event
+
+	for _, host := range target.Hosts {
+		if ctx.Err() != nil {
+			break
+		}
+
+		report, err := m.scan(ctx, target, host)
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			m.Logf("monitor %s: scan of %s failed: %v", target.Name, host, err)
+			continue
+		}
+
+		previous := m.swapPrevious(target, host, report)
+		if previous == nil {
+			m.Logf("monitor %s: baseline for %s recorded (%d open ports)", target.Name, host, len(report.OpenResults()))
+			continue
+		}
+
+		changes := diff.Compare(previous, report)
+		hostEvents := eventsFromChanges(target.Name, changes)
+		m.Logf("monitor %s: %s scanned, %d open ports, %d changes", target.Name, host, len(report.OpenResults()), len(changes))
+		m.notifier.Dispatch(ctx, hostEvents)
+		events = append(events, hostEvents...)
+	}
+
+	return events
+}
+
+// scan runs one scan of a host with the target set's settings, which
+// Config.Validate has already checked and loaded
+func (m *Monitor) scan(ctx context.Context, target *TargetSet, host string) (*models.ScanReport, error) {
+	config := *target.Scan
+	config.Host = host
+
+	formatter := output.NewFormatter(&output.FormatterConfig{Quiet: true})
+	portScanner, err := scanner.NewPortScanner(&config, formatter)
+	if err != nil {
+		return nil, err
+	}
+	portScanner.SetNmapRunner(m.nmap)
+	results, stats, err := portScanner.ScanContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ScanReport{Host: host, Results: results, Stats: stats}
+	if m.store != nil {
+		if _, err := m.store.Save(report); err != nil {
+			m.Logf("monitor %s: save report for %s: %v", target.Name, host, err)
+		}
+	}
+	return report, nil
+}
+
+// swapPrevious records the latest report of a host and returns the one before it
+func (m *Monitor) swapPrevious(target *TargetSet, host string, report *models.ScanReport) *models.ScanReport {
+	key := target.Name + "/" + host
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous, seen := m.previous[key]
+	m.previous[key] = report
+	if seen || m.store == nil {
+		return previous
+	}
+
+	// First run since start: compare against the report saved before this one
+	entries, err := m.store.List()
+	if err != nil {
+		return nil
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Host != host || entries[i].ID == report.ID {
+			continue
+		}
+		previous, err = history.ReadReport(entries[i].Path)
+		if err != nil {
+			return nil
+		}
+		return previous
+	}
+	return nil
+}
+
+// eventsFromChanges turns report differences into alert events
+func eventsFromChanges(target string, changes []diff.Change) []notify.Event {
+	now := time.Now()
+	events := make([]notify.Event, 0, len(changes))
+
+	for _, change := range changes {
+		event := notify.Event{
+			Kind:     change.Kind,
+			Target:   target,
+			Host:     change.Host,
+			Port:     change.Port,
+			Protocol: change.Protocol,
+			Message:  change.Message,
+			Time:     now,
+		}
+
+		result := change.After
+		if result == nil {
+			result = change.Before
+		}
+		if result != nil {
+			event.Service = result.Service
+			if result.SSLInfo != nil {
+				expiry := result.SSLInfo.ValidTo
+				event.CertExpiry = &expiry
+			}
+		}
+
+		switch change.Kind {
+		case diff.KindNewPort:
+			event.State = "open"
+			event.Severity = models.SeverityMedium
+		case diff.KindClosedPort:
+			event.State = "closed"
+			event.Severity = models.SeverityLow
+		case diff.KindCertChanged:
+			event.State = "open"
+			event.Severity = models.SeverityMedium
+		case diff.KindCertExpired:
+			event.State = "open"
+			event.Severity = models.SeverityHigh
+		default:
+			event.Severity = models.SeverityInfo
+		}
+
+		events = append(events, event)
+	}
+
+	return events
+}
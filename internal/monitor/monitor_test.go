@@ -0,0 +1,164 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Sh4Ryuu/go-scan/internal/history"
+	"github.com/Sh4Ryuu/go-scan/internal/notify"
+	"github.com/Sh4Ryuu/go-scan/internal/scanner"
+)
+
+// recordSink keeps the events it receives
+type recordSink struct {
+	mu     sync.Mutex
+	events []notify.Event
+}
+
+func (s *recordSink) Name() string { return "record" }
+
+func (s *recordSink) Send(ctx context.Context, event notify.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+// adjacentPorts listens on a loopback port and returns it with the next
+// port, which is left free
+func adjacentPorts(t *testing.T) (net.Listener, int) {
+	for i := 0; i < 20; i++ {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		port := ln.Addr().(*net.TCPAddr).Port
+		if next, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port+1))); err == nil {
+			next.Close()
+			return ln, port
+		}
+		ln.Close()
+	}
+	t.Skip("no two adjacent free loopback ports")
+	return nil, 0
+}
+
+// testTarget scans port and the one after it on the loopback address
+func testTarget(port int) *TargetSet {
+	scan := scanner.DefaultConfig()
+	scan.StartPort, scan.EndPort = port, port+1
+	scan.BannerGrabbing = false
+	scan.EnableSSL = false
+	scan.EnableGeolocation = false
+	return &TargetSet{Name: "local", Hosts: []string{"127.0.0.1"}, Schedule: "1h", Scan: scan}
+}
+
+func TestRunOnce(t *testing.T) {
+	first, port := adjacentPorts(t)
+	defer first.Close()
+
+	config := &Config{Targets: []*TargetSet{testTarget(port)}}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	store := history.NewStore(t.TempDir())
+	sink := &recordSink{}
+	m := New(config, store, notify.NewDispatcher(sink))
+	m.Logf = t.Logf
+	target := config.Targets[0]
+	ctx := context.Background()
+
+	if events := m.RunOnce(ctx, target); len(events) != 0 {
+		t.Fatalf("baseline run raised %+v", events)
+	}
+
+	second, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port+1)))
+	if err != nil {
+		t.Skipf("port %d was taken: %v", port+1, err)
+	}
+	defer second.Close()
+	events := m.RunOnce(ctx, target)
+	if len(events) != 1 || events[0].Kind != "new_port" || events[0].Port != port+1 ||
+		events[0].State != "open" || events[0].Severity != "medium" || events[0].Target != "local" {
+		t.Fatalf("second run raised %+v", events)
+	}
+
+	// A new monitor compares its first run against the saved reports
+	first.Close()
+	restarted := New(config, store, notify.NewDispatcher(sink))
+	restarted.Logf = t.Logf
+	events = restarted.RunOnce(ctx, target)
+	if len(events) != 1 || events[0].Kind != "closed_port" || events[0].Port != port || events[0].Severity != "low" {
+		t.Fatalf("run after restart raised %+v", events)
+	}
+
+	if len(sink.events) != 2 {
+		t.Errorf("dispatched %+v", sink.events)
+	}
+	if entries, _ := store.List(); len(entries) != 3 {
+		t.Errorf("saved %d reports, want 3", len(entries))
+	}
+}
+
+func TestValidateLoadsOnce(t *testing.T) {
+	ln, port := adjacentPorts(t)
+	defer ln.Close()
+
+	rules := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(rules, []byte(`[{"source": "banner", "pattern": "^acme", "vendor": "acme", "product": "acme"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	target := testTarget(port)
+	target.Scan.ProductRules = rules
+	target.Scan.Profile = "conservative"
+	config := &Config{Targets: []*TargetSet{target}}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if target.Scan.Products == nil || target.Scan.MaxWorkers != 50 || target.Scan.Host != "" {
+		t.Errorf("validated scan settings %+v", target.Scan)
+	}
+
+	// Runs reuse the loaded rules rather than reading the file again
+	if err := os.Remove(rules); err != nil {
+		t.Fatal(err)
+	}
+	var logged []string
+	m := New(config, nil, notify.NewDispatcher())
+	m.Logf = func(format string, args ...interface{}) { logged = append(logged, format) }
+	m.RunOnce(context.Background(), target)
+	if len(logged) != 1 || !strings.Contains(logged[0], "baseline") {
+		t.Errorf("logged %q", logged)
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*TargetSet)
+		want   string
+	}{
+		{"no hosts", func(t *TargetSet) { t.Hosts = nil }, `target "local": no hosts`},
+		{"empty host", func(t *TargetSet) { t.Hosts = []string{"a", ""} }, `target "local": empty host`},
+		{"schedule", func(t *TargetSet) { t.Schedule = "* * *" }, `target "local": cron expression`},
+		{"scan", func(t *TargetSet) { t.Scan.EndPort = 0 }, `target "local": end port must be between 1 and 65535`},
+		{"rules", func(t *TargetSet) { t.Scan.ProductRules = "/nonexistent" }, `target "local": product rules`},
+	}
+	for _, tt := range tests {
+		target := testTarget(1000)
+		tt.modify(target)
+		err := (&Config{Targets: []*TargetSet{target}}).Validate()
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.want)
+		}
+	}
+	if err := (&Config{}).Validate(); err == nil {
+		t.Error("a configuration without targets validated")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"context"
 	"fmt"
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,67 +29,213 @@ var AvailableScripts = map[string]string{
 	"banner":           "Grabs service banner",
 }
 
-// RunScript executes an nmap script on a specific port
-func RunScript(host string, port int, protocol string, script string, timeout time.Duration) models.NmapScriptResult {
-	result := models.NmapScriptResult{
-		Script:   script,
-		Port:     port,
-		Protocol: protocol,
-		Status:   "error",
+// DefaultParallelHosts is how many hosts a Runner scans at once unless
+// told otherwise
+const DefaultParallelHosts = 4
+
+// Script result statuses
+const (
+	StatusSuccess = "success"
+	StatusError   = "error"
+	StatusSkipped = "skipped" // nmap ran, but the script did not apply to the port
+)
+
+// perPortTimeout is added to the caller's timeout for each port of a run,
+// covering version detection and the scripts
+const perPortTimeout = 5 * time.Second
+
+// Runner runs nmap once per host, with at most a fixed number of hosts at
+// once. Scanners running side by side share one Runner to share the limit.
+type Runner struct {
+	slots chan struct{}
+}
+
+// NewRunner creates a runner scanning up to parallel hosts at once
+func NewRunner(parallel int) *Runner {
+	if parallel < 1 {
+		parallel = DefaultParallelHosts
 	}
+	return &Runner{slots: make(chan struct{}, parallel)}
+}
+
+// Run is the outcome of one nmap run against a host
+type Run struct {
+	Duration time.Duration
+	Err      error
+
+	// Results has an entry for every port asked about, with one result
+	// per script in the order given
+	Results map[int][]models.NmapScriptResult
+}
 
-	startTime := time.Now()
-	defer func() {
-		result.Duration = time.Since(startTime)
-	}()
+// RunHost runs every script against the given open TCP ports of host in a
+// single nmap process and maps the scripts' output back to their ports.
+// The run may take timeout plus a few seconds for each port.
+func (r *Runner) RunHost(ctx context.Context, host string, ports []int, scripts []string, timeout time.Duration) *Run {
+	run := &Run{}
+	if len(ports) == 0 || len(scripts) == 0 {
+		return run
+	}
+	fail := func(err error) *Run {
+		run.Err = err
+		run.Results = errorResults(ports, scripts, err, run.Duration)
+		return run
+	}
 
-	// Check if nmap is available
+	args, err := buildArgs(host, ports, scripts)
+	if err != nil {
+		return fail(err)
+	}
 	if !isNmapInstalled() {
-		result.Error = "nmap not installed"
-		return result
+		return fail(fmt.Errorf("nmap not installed"))
 	}
 
-	// Build nmap command
-	portStr := fmt.Sprintf("%d/%s", port, protocol)
-	cmdStr := fmt.Sprintf("nmap -p %s -sV --script=%s %s", portStr, script, host)
+	select {
+	case r.slots <- struct{}{}:
+		defer func() { <-r.slots }()
+	case <-ctx.Done():
+		return fail(ctx.Err())
+	}
 
-	// Execute command with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), timeout+5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, timeout+time.Duration(len(ports))*perPortTimeout)
 	defer cancel()
 
-	output, err := executeCommand(ctx, cmdStr)
+	start := time.Now()
+	output, err := executeCommand(ctx, args)
+	var parsed *xmlRun
+	if err == nil {
+		parsed, err = parseXML(output)
+	}
+	run.Duration = time.Since(start)
 	if err != nil {
-		result.Error = err.Error()
-		return result
+		return fail(err)
 	}
 
-	result.Output = output
-	result.Status = "success"
-	return result
+	run.Results = mapResults(parsed, ports, scripts, run.Duration)
+	return run
 }
 
-// RunScriptMultiple runs multiple scripts on a port
-func RunScriptMultiple(host string, port int, protocol string, scripts []string, timeout time.Duration) []models.NmapScriptResult {
-	results := make([]models.NmapScriptResult, 0, len(scripts))
-
+// buildArgs builds the nmap arguments for a run. The ports are known to be
+// open, so host discovery is skipped. Hosts that nmap would read as an
+// option and scripts outside AvailableScripts are refused.
+func buildArgs(host string, ports []int, scripts []string) ([]string, error) {
+	if host == "" || strings.HasPrefix(host, "-") {
+		return nil, fmt.Errorf("invalid nmap target %q", host)
+	}
 	for _, script := range scripts {
-		result := RunScript(host, port, protocol, script, timeout)
-		results = append(results, result)
+		if !ValidateScript(script) {
+			return nil, fmt.Errorf("unknown nmap script %q", script)
+		}
 	}
 
+	sorted := append([]int(nil), ports...)
+	sort.Ints(sorted)
+	portList := make([]string, len(sorted))
+	for i, port := range sorted {
+		portList[i] = strconv.Itoa(port)
+	}
+	return []string{
+		"-Pn", "-sV",
+		"-p", "T:" + strings.Join(portList, ","),
+		"--script", strings.Join(scripts, ","),
+		"-oX", "-",
+		host,
+	}, nil
+}
+
+// mapResults gives every port one result per script. Host scripts, such as
+// smb-os-discovery, are reported on the port they most likely ran over.
+func mapResults(parsed *xmlRun, ports []int, scripts []string, duration time.Duration) map[int][]models.NmapScriptResult {
+	outputs := make(map[int]map[string]string)
+	for _, port := range parsed.Host.Ports {
+		if port.Protocol != "tcp" {
+			continue
+		}
+		for _, script := range port.Scripts {
+			if outputs[port.ID] == nil {
+				outputs[port.ID] = make(map[string]string)
+			}
+			outputs[port.ID][script.ID] = scriptOutput(script.Output)
+		}
+	}
+	if len(parsed.Host.HostScripts) > 0 {
+		port := hostScriptPort(ports)
+		if outputs[port] == nil {
+			outputs[port] = make(map[string]string)
+		}
+		for _, script := range parsed.Host.HostScripts {
+			outputs[port][script.ID] = scriptOutput(script.Output)
+		}
+	}
+
+	results := make(map[int][]models.NmapScriptResult, len(ports))
+	for _, port := range ports {
+		for _, script := range scripts {
+			result := models.NmapScriptResult{
+				Script:   script,
+				Port:     port,
+				Protocol: "tcp",
+				Status:   StatusSkipped,
+				Duration: duration,
+			}
+			if output, ok := outputs[port][script]; ok {
+				result.Status = StatusSuccess
+				result.Output = output
+			}
+			results[port] = append(results[port], result)
+		}
+	}
+	return results
+}
+
+// hostScriptPort picks the port to report host script output on: SMB
+// when it is open, since most host scripts run over it, else the lowest
+func hostScriptPort(ports []int) int {
+	for _, preferred := range []int{445, 139} {
+		for _, port := range ports {
+			if port == preferred {
+				return port
+			}
+		}
+	}
+	sorted := append([]int(nil), ports...)
+	sort.Ints(sorted)
+	return sorted[0]
+}
+
+// scriptOutput tidies a script's output attribute, which nmap starts with
+// a newline when it spans several lines
+func scriptOutput(output string) string {
+	return strings.TrimRight(strings.TrimPrefix(output, "\n"), " \n")
+}
+
+// errorResults reports err for every port and script of a failed run
+func errorResults(ports []int, scripts []string, err error, duration time.Duration) map[int][]models.NmapScriptResult {
+	results := make(map[int][]models.NmapScriptResult, len(ports))
+	for _, port := range ports {
+		for _, script := range scripts {
+			results[port] = append(results[port], models.NmapScriptResult{
+				Script:   script,
+				Port:     port,
+				Protocol: "tcp",
+				Status:   StatusError,
+				Error:    err.Error(),
+				Duration: duration,
+			})
+		}
+	}
 	return results
 }
 
 // isNmapInstalled checks if nmap is available
 func isNmapInstalled() bool {
-	cmd := exec.Command("nmap", "-V")
-	err := cmd.Run()
+	_, err := exec.LookPath("nmap")
 	return err == nil
 }
 
-// executeCommand executes a shell command and returns output
-func executeCommand(ctx context.Context, cmdStr string) (string, error) {
-	cmd := exec.CommandContext(ctx, "nmap", parseNmapArgs(cmdStr)...)
+// executeCommand runs nmap with args and returns its standard output
+func executeCommand(ctx context.Context, args []string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "nmap", args...)
 
 	var out bytes.Buffer
 	var errOut bytes.Buffer
@@ -95,20 +243,18 @@ func executeCommand(ctx context.Context, cmdStr string) (string, error) {
 	cmd.Stderr = &errOut
 
 	err := cmd.Run()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("nmap killed: %v", ctx.Err())
+	}
 	if err != nil {
-		return "", fmt.Errorf("command failed: %v", err)
+		if msg := strings.TrimSpace(errOut.String()); msg != "" {
+			msg, _, _ = strings.Cut(msg, "\n")
+			return nil, fmt.Errorf("command failed: %v: %s", err, msg)
+		}
+		return nil, fmt.Errorf("command failed: %v", err)
 	}
 
-	return out.String(), nil
-}
-
-// parseNmapArgs parses nmap command string to arguments
-func parseNmapArgs(cmdStr string) []string {
-	parts := strings.Fields(cmdStr)
-	if len(parts) > 0 && parts[0] == "nmap" {
-		return parts[1:]
-	}
-	return parts
+	return out.Bytes(), nil
 }
 
 // ListAvailableScripts returns list of available scripts
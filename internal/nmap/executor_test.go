@@ -0,0 +1,114 @@
+package nmap
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/testutil"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+func TestBuildArgs(t *testing.T) {
+	args, err := buildArgs("192.0.2.10", []int{8080, 22, 443}, []string{"ssh-hostkey", "http-title"})
+	want := "-Pn -sV -p T:22,443,8080 --script ssh-hostkey,http-title -oX - 192.0.2.10"
+	if err != nil || strings.Join(args, " ") != want {
+		t.Errorf("got %q, %v, want %q", args, err, want)
+	}
+
+	tests := []struct {
+		host    string
+		scripts []string
+		want    string
+	}{
+		{"-iL/etc/passwd", []string{"banner"}, `invalid nmap target "-iL/etc/passwd"`},
+		{"--script-args=x", []string{"banner"}, `invalid nmap target "--script-args=x"`},
+		{"", []string{"banner"}, `invalid nmap target ""`},
+		{"example.com", []string{"banner", "http-shellshock"}, `unknown nmap script "http-shellshock"`},
+	}
+	for _, tt := range tests {
+		if args, err := buildArgs(tt.host, []int{80}, tt.scripts); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: got %q, %v, want error %q", tt.host, args, err, tt.want)
+		}
+	}
+}
+
+func TestRunHostRefusesOptions(t *testing.T) {
+	run := NewRunner(1).RunHost(context.Background(), "-oN/tmp/x", []int{22, 80}, []string{"banner"}, time.Second)
+	if run.Err == nil || len(run.Results) != 2 {
+		t.Fatalf("got %+v", run)
+	}
+	for port, results := range run.Results {
+		if len(results) != 1 || results[0].Status != StatusError || results[0].Port != port ||
+			results[0].Error != `invalid nmap target "-oN/tmp/x"` {
+			t.Errorf("port %d: got %+v", port, results)
+		}
+	}
+}
+
+func TestMapResults(t *testing.T) {
+	parsed, err := parseXML(testutil.Fixture(t, "report.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ports := []int{22, 80, 445, 8080}
+	scripts := []string{"ssh-hostkey", "http-title", "smb-os-discovery"}
+	results := mapResults(parsed, ports, scripts, 30*time.Second)
+
+	// Every port gets every script, skipped unless nmap reported it there
+	want := map[int][]string{
+		22:   {"success:   256 aa:bb:cc:dd (ECDSA)\n  256 ee:ff:00:11 (ED25519)", "skipped", "skipped"},
+		80:   {"skipped", "success: Welcome to nginx!", "skipped"},
+		445:  {"skipped", "skipped", "success:   OS: Windows Server 2019 Standard 17763\n  Computer name: FILES01"},
+		8080: {"skipped", "skipped", "skipped"},
+	}
+	if len(results) != len(want) {
+		t.Errorf("results for %d ports, want %d", len(results), len(want))
+	}
+	for port, statuses := range want {
+		for i, status := range statuses {
+			if i >= len(results[port]) {
+				t.Errorf("port %d: %d results, want %d", port, len(results[port]), len(statuses))
+				break
+			}
+			result := results[port][i]
+			got := result.Status
+			if result.Output != "" {
+				got += ": " + result.Output
+			}
+			if got != status || result.Script != scripts[i] || result.Port != port || result.Protocol != "tcp" ||
+				result.Duration != 30*time.Second {
+				t.Errorf("port %d, %s: got %+v, want %s", port, scripts[i], result, status)
+			}
+		}
+	}
+}
+
+func TestHostScriptPort(t *testing.T) {
+	tests := []struct {
+		ports []int
+		want  int
+	}{
+		{[]int{22, 139, 445}, 445},
+		{[]int{139, 3389}, 139},
+		{[]int{8080, 22, 443}, 22},
+	}
+	for _, tt := range tests {
+		if got := hostScriptPort(tt.ports); got != tt.want {
+			t.Errorf("hostScriptPort(%v) = %d, want %d", tt.ports, got, tt.want)
+		}
+	}
+
+	// With no SMB port, host script output lands on the lowest port
+	parsed := &xmlRun{Host: xmlHost{HostScripts: []xmlScript{{ID: "smb-os-discovery", Output: "OS: Linux"}}}}
+	results := mapResults(parsed, []int{8080, 22}, []string{"smb-os-discovery"}, 0)
+	if got := results[22]; len(got) != 1 || got[0] != (models.NmapScriptResult{
+		Script: "smb-os-discovery", Port: 22, Protocol: "tcp", Status: StatusSuccess, Output: "OS: Linux",
+	}) {
+		t.Errorf("port 22: got %+v", got)
+	}
+	if got := results[8080]; len(got) != 1 || got[0].Status != StatusSkipped {
+		t.Errorf("port 8080: got %+v", got)
+	}
+}
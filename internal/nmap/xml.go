@@ -0,0 +1,50 @@
+package nmap
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// xmlRun is the part of nmap's -oX output the runner reads
+type xmlRun struct {
+	Host  xmlHost `xml:"host"`
+	Stats struct {
+		Finished struct {
+			Exit     string `xml:"exit,attr"`
+			ErrorMsg string `xml:"errormsg,attr"`
+		} `xml:"finished"`
+	} `xml:"runstats"`
+}
+
+// xmlHost is the first host of a run; a run only ever scans one
+type xmlHost struct {
+	Ports       []xmlPort   `xml:"ports>port"`
+	HostScripts []xmlScript `xml:"hostscript>script"`
+}
+
+type xmlPort struct {
+	Protocol string      `xml:"protocol,attr"`
+	ID       int         `xml:"portid,attr"`
+	Scripts  []xmlScript `xml:"script"`
+}
+
+type xmlScript struct {
+	ID     string `xml:"id,attr"`
+	Output string `xml:"output,attr"`
+}
+
+// parseXML decodes nmap's XML report, turning a run nmap reports as
+// failed into an error
+func parseXML(data []byte) (*xmlRun, error) {
+	var run xmlRun
+	if err := xml.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("parse nmap XML: %v", err)
+	}
+	if finished := run.Stats.Finished; finished.Exit == "error" {
+		if finished.ErrorMsg != "" {
+			return nil, fmt.Errorf("nmap failed: %s", finished.ErrorMsg)
+		}
+		return nil, fmt.Errorf("nmap failed")
+	}
+	return &run, nil
+}
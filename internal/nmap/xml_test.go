@@ -0,0 +1,35 @@
+package nmap
+
+import (
+	"testing"
+
+	"github.com/Sh4Ryuu/go-scan/internal/testutil"
+)
+
+func TestParseXML(t *testing.T) {
+	parsed, err := parseXML(testutil.Fixture(t, "report.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Host.Ports) != 5 || len(parsed.Host.HostScripts) != 1 || parsed.Stats.Finished.Exit != "success" {
+		t.Errorf("got %+v", parsed)
+	}
+
+	tests := []struct {
+		report string
+		want   string
+	}{
+		{
+			`<nmaprun><runstats><finished exit="error" errormsg="Failed to resolve &quot;nosuchhost&quot;."/></runstats></nmaprun>`,
+			`nmap failed: Failed to resolve "nosuchhost".`,
+		},
+		{`<nmaprun><runstats><finished exit="error"/></runstats></nmaprun>`, "nmap failed"},
+		{`<nmaprun><host><ports>`, "parse nmap XML: XML syntax error on line 1: unexpected EOF"},
+		{"", "parse nmap XML: EOF"},
+	}
+	for _, tt := range tests {
+		if run, err := parseXML([]byte(tt.report)); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: got %+v, %v, want error %q", tt.report, run, err, tt.want)
+		}
+	}
+}
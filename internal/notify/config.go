@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+)
+
+// Sink types accepted by BuildSink
+const (
+	TypeStdout  = "stdout"
+	TypeFile    = "file"
+	TypeWebhook = "webhook"
+	TypeSlack   = "slack"
+	TypeEmail   = "email"
+)
+
+// defaultAttempts is how often network sinks try to deliver an event
+const defaultAttempts = 3
+
+// SinkConfig describes a single notification sink
+type SinkConfig struct {
+	Type     string      `json:"type"`
+	URL      string      `json:"url,omitempty"`      // webhook and slack
+	Template string      `json:"template,omitempty"` // webhook body template
+	Path     string      `json:"path,omitempty"`     // file
+	SMTP     *SMTPConfig `json:"smtp,omitempty"`     // email
+	Filter   Filter      `json:"filter,omitempty"`
+
+	// Attempts is the number of delivery attempts; network sinks default
+	// to 3, set 1 to disable retries
+	Attempts            int `json:"attempts,omitempty"`
+	RetryBackoffSeconds int `json:"retry_backoff_seconds,omitempty"`
+	RateLimitPerMinute  int `json:"rate_limit_per_minute,omitempty"`
+	RateLimitBurst      int `json:"rate_limit_burst,omitempty"`
+}
+
+// BuildSink creates a sink from its configuration, wrapped with the
+// configured filter, rate limit and retries
+func BuildSink(config SinkConfig) (Sink, error) {
+	var sink Sink
+	network := true
+
+	switch config.Type {
+	case TypeStdout:
+		sink, network = NewStdoutSink(), false
+	case TypeFile:
+		if config.Path == "" {
+			return nil, fmt.Errorf("file sink needs a path")
+		}
+		sink, network = NewFileSink(config.Path), false
+	case TypeWebhook:
+		if config.URL == "" {
+			return nil, fmt.Errorf("webhook sink needs a url")
+		}
+		if config.Template == "" {
+			sink = NewWebhookSink(config.URL)
+			break
+		}
+		webhook, err := NewTemplateWebhookSink(config.URL, config.Template)
+		if err != nil {
+			return nil, err
+		}
+		sink = webhook
+	case TypeSlack:
+		if config.URL == "" {
+			return nil, fmt.Errorf("slack sink needs a url")
+		}
+		sink = NewSlackSink(config.URL)
+	case TypeEmail:
+		if config.SMTP == nil {
+			return nil, fmt.Errorf("email sink needs smtp settings")
+		}
+		email, err := NewEmailSink(*config.SMTP)
+		if err != nil {
+			return nil, err
+		}
+		sink = email
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", config.Type)
+	}
+
+	attempts := config.Attempts
+	if attempts == 0 && network {
+		attempts = defaultAttempts
+	}
+	backoff := time.Duration(config.RetryBackoffSeconds) * time.Second
+	if backoff <= 0 {
+		backoff = 2 * time.Second
+	}
+
+	// Retries sit inside the rate limit so a retried event uses one token,
+	// and the filter sits outside so dropped events use none
+	sink = WithRetry(sink, attempts, backoff)
+	sink = WithRateLimit(sink, config.RateLimitPerMinute, config.RateLimitBurst)
+	sink = WithFilter(sink, config.Filter)
+	return sink, nil
+}
@@ -0,0 +1,114 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// retrySink retries failed deliveries with exponential backoff
+type retrySink struct {
+	Sink
+	attempts int
+	backoff  time.Duration
+}
+
+// WithRetry wraps a sink so failed sends are retried up to attempts times
+// in total, doubling the wait after each failure
+func WithRetry(sink Sink, attempts int, backoff time.Duration) Sink {
+	if attempts <= 1 {
+		return sink
+	}
+	return &retrySink{Sink: sink, attempts: attempts, backoff: backoff}
+}
+
+// Send delivers the event, retrying on failure
+func (s *retrySink) Send(ctx context.Context, event Event) error {
+	wait := s.backoff
+	var err error
+	for attempt := 1; attempt <= s.attempts; attempt++ {
+		if err = s.Sink.Send(ctx, event); err == nil {
+			return nil
+		}
+		if attempt == s.attempts {
+			break
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		wait *= 2
+	}
+	return fmt.Errorf("giving up after %d attempts: %v", s.attempts, err)
+}
+
+// rateLimitSink spaces out deliveries using a token bucket
+type rateLimitSink struct {
+	Sink
+	interval time.Duration
+	burst    int
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// WithRateLimit wraps a sink so it sends at most perMinute events per
+// minute, allowing bursts of up to burst events
+func WithRateLimit(sink Sink, perMinute, burst int) Sink {
+	if perMinute <= 0 {
+		return sink
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimitSink{
+		Sink:     sink,
+		interval: time.Minute / time.Duration(perMinute),
+		burst:    burst,
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// Send waits for a token and then delivers the event
+func (s *rateLimitSink) Send(ctx context.Context, event Event) error {
+	for {
+		wait := s.take()
+		if wait == 0 {
+			return s.Sink.Send(ctx, event)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// take consumes a token, or returns how long to wait for the next one
+func (s *rateLimitSink) take() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.tokens += float64(now.Sub(s.last)) / float64(s.interval)
+	if s.tokens > float64(s.burst) {
+		s.tokens = float64(s.burst)
+	}
+	s.last = now
+
+	if s.tokens >= 1 {
+		s.tokens--
+		return 0
+	}
+	return time.Duration((1 - s.tokens) * float64(s.interval))
+}
@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer fails the first failures requests with 503 and accepts the
+// rest, counting them all
+func flakyServer(t *testing.T, failures int32) (string, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, &requests
+}
+
+func TestRetry(t *testing.T) {
+	url, requests := flakyServer(t, 2)
+	sink := WithRetry(NewWebhookSink(url), 3, time.Millisecond)
+	if err := sink.Send(context.Background(), testEvent); err != nil {
+		t.Fatal(err)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("%d requests, want 3", n)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	url, requests := flakyServer(t, 100)
+	err := WithRetry(NewWebhookSink(url), 3, time.Millisecond).Send(context.Background(), testEvent)
+	if err == nil || err.Error() != "giving up after 3 attempts: webhook returned 503 Service Unavailable" {
+		t.Errorf("got error %v", err)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("%d requests, want 3", n)
+	}
+
+	// One attempt means no retry wrapper at all
+	requests.Store(0)
+	if err := WithRetry(NewWebhookSink(url), 1, time.Millisecond).Send(context.Background(), testEvent); err == nil {
+		t.Error("send succeeded")
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("%d requests, want 1", n)
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	url, requests := flakyServer(t, 100)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := WithRetry(NewWebhookSink(url), 3, time.Hour).Send(ctx, testEvent)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want the context's", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("%d requests, want 1", n)
+	}
+}
+
+// countSink counts the events it receives
+type countSink struct{ sent atomic.Int32 }
+
+func (s *countSink) Name() string { return "count" }
+
+func (s *countSink) Send(ctx context.Context, event Event) error {
+	s.sent.Add(1)
+	return nil
+}
+
+func TestRateLimit(t *testing.T) {
+	counter := &countSink{}
+	sink := WithRateLimit(counter, 1200, 2) // a token every 50ms
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := sink.Send(context.Background(), testEvent); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The burst covers two events; the third waits for a token
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond || elapsed > time.Second {
+		t.Errorf("three events took %v, want about 50ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := WithRateLimit(counter, 1, 1).Send(ctx, testEvent); err != nil {
+		t.Errorf("first event of a fresh bucket: %v", err)
+	}
+	if n := counter.sent.Load(); n != 4 {
+		t.Errorf("%d events sent, want 4", n)
+	}
+}
+
+func TestBuildSinkFilterBeforeRateLimit(t *testing.T) {
+	url, requests := flakyServer(t, 0)
+	sink, err := BuildSink(SinkConfig{
+		Type: TypeWebhook, URL: url,
+		Filter:             Filter{MinSeverity: "high"},
+		RateLimitPerMinute: 1, RateLimitBurst: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	low, high := testEvent, testEvent
+	low.Severity, high.Severity = "low", "critical"
+	ctx := context.Background()
+	for _, event := range []Event{low, low, high} {
+		if err := sink.Send(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("%d requests, want only the critical event", n)
+	}
+
+	// The one token is spent, so the next event waits until the deadline
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := sink.Send(ctx, high); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want the context's", err)
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	url, _ := flakyServer(t, 100)
+	counter := &countSink{}
+	d := NewDispatcher(counter)
+	d.Add(NewWebhookSink(url))
+
+	var failures []string
+	d.OnError = func(sink Sink, event Event, err error) {
+		failures = append(failures, sink.Name()+": "+err.Error())
+	}
+	d.Dispatch(context.Background(), []Event{testEvent, testEvent})
+
+	if n := counter.sent.Load(); n != 2 {
+		t.Errorf("%d events sent, want 2", n)
+	}
+	if len(failures) != 2 || !strings.HasSuffix(failures[0], "webhook returned 503 Service Unavailable") {
+		t.Errorf("failures %q", failures)
+	}
+}
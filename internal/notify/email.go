@@ -0,0 +1,147 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPConfig describes how to deliver alert emails
+type SMTPConfig struct {
+	Addr          string   `json:"addr"` // host:port of the SMTP server
+	From          string   `json:"from"`
+	To            []string `json:"to"`
+	Username      string   `json:"username,omitempty"`
+	Password      string   `json:"password,omitempty"`
+	SubjectPrefix string   `json:"subject_prefix,omitempty"`
+	// InsecureSkipVerify disables certificate checks when upgrading with STARTTLS
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+}
+
+// EmailSink sends each event as a plain-text email over SMTP
+type EmailSink struct {
+	config  SMTPConfig
+	timeout time.Duration
+}
+
+// NewEmailSink creates an SMTP sink
+func NewEmailSink(config SMTPConfig) (*EmailSink, error) {
+	if config.Addr == "" || config.From == "" || len(config.To) == 0 {
+		return nil, fmt.Errorf("smtp sink needs addr, from and at least one recipient")
+	}
+	if _, _, err := net.SplitHostPort(config.Addr); err != nil {
+		return nil, fmt.Errorf("smtp addr %q: %v", config.Addr, err)
+	}
+	if config.SubjectPrefix == "" {
+		config.SubjectPrefix = "[go-scan]"
+	}
+	return &EmailSink{config: config, timeout: 30 * time.Second}, nil
+}
+
+// Name returns the sink name
+func (s *EmailSink) Name() string {
+	return "email:" + s.config.Addr
+}
+
+// Send delivers the event as an email. STARTTLS is used when the server
+// offers it; credentials are only sent when configured.
+func (s *EmailSink) Send(ctx context.Context, event Event) error {
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	dialer := &net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", s.config.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(deadline)
+
+	host, _, _ := net.SplitHostPort(s.config.Addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		tlsConfig := &tls.Config{ServerName: host, InsecureSkipVerify: s.config.InsecureSkipVerify}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("starttls: %v", err)
+		}
+	}
+
+	if s.config.Username != "" {
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("auth: %v", err)
+		}
+	}
+
+	if err := client.Mail(s.config.From); err != nil {
+		return err
+	}
+	for _, to := range s.config.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(s.message(event)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message builds the RFC 5322 message for an event
+func (s *EmailSink) message(event Event) []byte {
+	subject := fmt.Sprintf("%s %s: %s on %s", s.config.SubjectPrefix, strings.ToUpper(event.Severity), event.Kind, endpoint(event))
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.config.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.config.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", headerSafe(subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", event.Time.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: text/plain; charset=utf-8\r\n")
+	fmt.Fprintf(&b, "\r\n")
+
+	fmt.Fprintf(&b, "%s\r\n\r\n", event.Message)
+	fmt.Fprintf(&b, "Kind:     %s\r\n", event.Kind)
+	fmt.Fprintf(&b, "Severity: %s\r\n", event.Severity)
+	fmt.Fprintf(&b, "Host:     %s\r\n", endpoint(event))
+	if event.Target != "" {
+		fmt.Fprintf(&b, "Target:   %s\r\n", event.Target)
+	}
+	if event.State != "" {
+		fmt.Fprintf(&b, "State:    %s\r\n", event.State)
+	}
+	if event.Service != "" {
+		fmt.Fprintf(&b, "Service:  %s\r\n", event.Service)
+	}
+	if event.CertExpiry != nil {
+		fmt.Fprintf(&b, "Certificate expiry: %s\r\n", event.CertExpiry.Format("2006-01-02"))
+	}
+	fmt.Fprintf(&b, "Time:     %s\r\n", event.Time.Format(time.RFC3339))
+
+	return b.Bytes()
+}
+
+// headerSafe strips line breaks from a header value
+func headerSafe(v string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(v)
+}
@@ -0,0 +1,158 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/testutil"
+)
+
+// smtpSession is what the SMTP stand-in saw from one client
+type smtpSession struct {
+	commands []string
+	data     string
+}
+
+// smtpServer runs a minimal SMTP stand-in that accepts every message,
+// offering AUTH PLAIN, and reports each session on the returned channel.
+// rcptCode is the reply to RCPT TO.
+func smtpServer(t *testing.T, rcptCode string) (string, <-chan smtpSession) {
+	sessions := make(chan smtpSession, 4)
+	address := testutil.Serve(t, func(conn net.Conn) {
+		var session smtpSession
+		defer func() { sessions <- session }()
+
+		r := bufio.NewReader(conn)
+		fmt.Fprintf(conn, "220 mail.test ESMTP stand-in\r\n")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			session.commands = append(session.commands, line)
+
+			verb := strings.ToUpper(strings.Fields(line + " ")[0])
+			switch verb {
+			case "EHLO":
+				fmt.Fprintf(conn, "250-mail.test\r\n250 AUTH PLAIN\r\n")
+			case "AUTH":
+				fmt.Fprintf(conn, "235 2.7.0 accepted\r\n")
+			case "MAIL":
+				fmt.Fprintf(conn, "250 2.1.0 ok\r\n")
+			case "RCPT":
+				fmt.Fprintf(conn, "%s recipient\r\n", rcptCode)
+			case "DATA":
+				fmt.Fprintf(conn, "354 go ahead\r\n")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				session.data = data.String()
+				fmt.Fprintf(conn, "250 2.0.0 queued\r\n")
+			case "QUIT":
+				fmt.Fprintf(conn, "221 bye\r\n")
+				return
+			default:
+				fmt.Fprintf(conn, "502 unknown\r\n")
+			}
+		}
+	})
+	return address, sessions
+}
+
+func TestEmailSink(t *testing.T) {
+	address, sessions := smtpServer(t, "250")
+	sink, err := NewEmailSink(SMTPConfig{
+		Addr:     address,
+		From:     "scanner@example.com",
+		To:       []string{"ops@example.com", "sec@example.com"},
+		Username: "scanner",
+		Password: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expiry := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	event := Event{
+		Kind: "cert_expiring", Target: "prod", Host: "192.0.2.10", Port: 443, Severity: "high",
+		Message: "certificate expires in 10 days\r\nBcc: evil@example.com", CertExpiry: &expiry,
+		Time: time.Date(2024, 1, 22, 12, 0, 0, 0, time.UTC),
+	}
+	if err := sink.Send(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	session := <-sessions
+	want := []string{
+		"AUTH PLAIN AHNjYW5uZXIAc2VjcmV0",
+		"MAIL FROM:<scanner@example.com>",
+		"RCPT TO:<ops@example.com>",
+		"RCPT TO:<sec@example.com>",
+		"DATA",
+		"QUIT",
+	}
+	if got := session.commands[1:]; strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	headers, body, _ := strings.Cut(session.data, "\r\n\r\n")
+	for _, header := range []string{
+		"Subject: [go-scan] HIGH: cert_expiring on 192.0.2.10:443",
+		"To: ops@example.com, sec@example.com",
+		"Date: Mon, 22 Jan 2024 12:00:00 +0000",
+	} {
+		if !strings.Contains(headers, header+"\r\n") {
+			t.Errorf("headers lack %q:\n%s", header, headers)
+		}
+	}
+	if strings.Contains(headers, "Bcc") {
+		t.Errorf("message text leaked into the headers:\n%s", headers)
+	}
+	if !strings.Contains(body, "Certificate expiry: 2024-02-01\r\n") || !strings.Contains(body, "Target:   prod\r\n") {
+		t.Errorf("body:\n%s", body)
+	}
+}
+
+func TestEmailSinkRejected(t *testing.T) {
+	address, sessions := smtpServer(t, "550")
+	sink, err := NewEmailSink(SMTPConfig{Addr: address, From: "a@example.com", To: []string{"b@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(context.Background(), Event{Kind: "new_port", Host: "h"}); err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("got error %v, want the 550 reply", err)
+	}
+	// Without credentials the sink never authenticates
+	for _, command := range (<-sessions).commands {
+		if strings.HasPrefix(command, "AUTH") {
+			t.Errorf("sent %q without credentials", command)
+		}
+	}
+}
+
+func TestNewEmailSinkErrors(t *testing.T) {
+	for _, config := range []SMTPConfig{
+		{From: "a@example.com", To: []string{"b@example.com"}},
+		{Addr: "mail.example.com:25", To: []string{"b@example.com"}},
+		{Addr: "mail.example.com:25", From: "a@example.com"},
+		{Addr: "mail.example.com", From: "a@example.com", To: []string{"b@example.com"}},
+	} {
+		if _, err := NewEmailSink(config); err == nil {
+			t.Errorf("NewEmailSink(%+v) succeeded", config)
+		}
+	}
+}
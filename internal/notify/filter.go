@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"context"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// Filter selects the events a sink receives. Empty fields match everything.
+type Filter struct {
+	Kinds       []string `json:"kinds,omitempty"`
+	States      []string `json:"states,omitempty"`       // port states, e.g. "open"
+	MinSeverity string   `json:"min_severity,omitempty"` // e.g. "medium"
+	// CertExpiresWithinDays keeps only events about certificates expiring
+	// within this many days (expired certificates included)
+	CertExpiresWithinDays int `json:"cert_expires_within_days,omitempty"`
+}
+
+// Match reports whether an event passes the filter
+func (f Filter) Match(event Event) bool {
+	if len(f.Kinds) > 0 && !contains(f.Kinds, event.Kind) {
+		return false
+	}
+	if len(f.States) > 0 && !contains(f.States, event.State) {
+		return false
+	}
+	if f.MinSeverity != "" && models.SeverityRank(event.Severity) < models.SeverityRank(f.MinSeverity) {
+		return false
+	}
+	if f.CertExpiresWithinDays > 0 {
+		if event.CertExpiry == nil {
+			return false
+		}
+		limit := event.Time.Add(time.Duration(f.CertExpiresWithinDays) * 24 * time.Hour)
+		if event.CertExpiry.After(limit) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsZero reports whether the filter matches every event
+func (f Filter) IsZero() bool {
+	return len(f.Kinds) == 0 && len(f.States) == 0 && f.MinSeverity == "" && f.CertExpiresWithinDays == 0
+}
+
+// filteredSink drops events that do not pass a filter
+type filteredSink struct {
+	Sink
+	filter Filter
+}
+
+// WithFilter wraps a sink so it only receives events matching filter
+func WithFilter(sink Sink, filter Filter) Sink {
+	if filter.IsZero() {
+		return sink
+	}
+	return &filteredSink{Sink: sink, filter: filter}
+}
+
+// Send forwards the event if it matches the filter
+func (s *filteredSink) Send(ctx context.Context, event Event) error {
+	if !s.filter.Match(event) {
+		return nil
+	}
+	return s.Sink.Send(ctx, event)
+}
+
+// contains reports whether list holds value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
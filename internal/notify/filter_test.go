@@ -0,0 +1,62 @@
+package notify
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFilterMatch(t *testing.T) {
+	now := time.Date(2024, 1, 22, 12, 0, 0, 0, time.UTC)
+	days := func(n int) *time.Time {
+		t := now.Add(time.Duration(n) * 24 * time.Hour)
+		return &t
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		event  Event
+		want   bool
+	}{
+		{"empty filter", Filter{}, Event{Kind: "new_port"}, true},
+		{"kind", Filter{Kinds: []string{"cert_expiring"}}, Event{Kind: "new_port"}, false},
+		{"state open", Filter{States: []string{"open"}}, Event{State: "open"}, true},
+		{"state closed", Filter{States: []string{"open"}}, Event{State: "closed"}, false},
+		{"no state", Filter{States: []string{"open"}}, Event{Kind: "cert_changed"}, false},
+		{"severity equal", Filter{MinSeverity: "medium"}, Event{Severity: "medium"}, true},
+		{"severity above", Filter{MinSeverity: "medium"}, Event{Severity: "critical"}, true},
+		{"severity below", Filter{MinSeverity: "medium"}, Event{Severity: "low"}, false},
+		{"unknown severity", Filter{MinSeverity: "info"}, Event{Severity: "bogus"}, false},
+		{"expiry inside window", Filter{CertExpiresWithinDays: 30}, Event{Time: now, CertExpiry: days(10)}, true},
+		{"expiry at window", Filter{CertExpiresWithinDays: 30}, Event{Time: now, CertExpiry: days(30)}, true},
+		{"expiry outside window", Filter{CertExpiresWithinDays: 30}, Event{Time: now, CertExpiry: days(31)}, false},
+		{"already expired", Filter{CertExpiresWithinDays: 30}, Event{Time: now, CertExpiry: days(-3)}, true},
+		{"no certificate", Filter{CertExpiresWithinDays: 30}, Event{Time: now}, false},
+		{
+			"all conditions",
+			Filter{Kinds: []string{"cert_expiring"}, States: []string{"open"}, MinSeverity: "high", CertExpiresWithinDays: 7},
+			Event{Kind: "cert_expiring", State: "open", Severity: "high", Time: now, CertExpiry: days(5)},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(tt.event); got != tt.want {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWithFilter(t *testing.T) {
+	counter := &countSink{}
+	if sink := WithFilter(counter, Filter{}); sink != Sink(counter) {
+		t.Error("an empty filter wrapped the sink")
+	}
+
+	sink := WithFilter(counter, Filter{States: []string{"open"}})
+	sink.Send(context.Background(), Event{State: "closed"})
+	sink.Send(context.Background(), Event{State: "open"})
+	if n := counter.sent.Load(); n != 1 {
+		t.Errorf("%d events passed, want 1", n)
+	}
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Event is something a scan found that someone should hear about
+type Event struct {
+	Kind       string     `json:"kind"`
+	Target     string     `json:"target,omitempty"`
+	Host       string     `json:"host"`
+	Port       int        `json:"port,omitempty"`
+	Protocol   string     `json:"protocol,omitempty"`
+	State      string     `json:"state,omitempty"` // port state after the change
+	Service    string     `json:"service,omitempty"`
+	Severity   string     `json:"severity"`
+	Message    string     `json:"message"`
+	CertExpiry *time.Time `json:"cert_expiry,omitempty"`
+	Time       time.Time  `json:"time"`
+}
+
+// Sink delivers events to a destination
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, event Event) error
+}
+
+// Dispatcher fans events out to a set of sinks
+type Dispatcher struct {
+	sinks []Sink
+	// OnError is called when a sink fails; it defaults to logging on stderr
+	OnError func(sink Sink, event Event, err error)
+}
+
+// NewDispatcher creates a dispatcher for the given sinks
+func NewDispatcher(sinks ...Sink) *Dispatcher {
+	return &Dispatcher{
+		sinks: sinks,
+		OnError: func(sink Sink, event Event, err error) {
+			fmt.Fprintf(os.Stderr, "notify %s: %s on %s: %v\n", sink.Name(), event.Kind, event.Host, err)
+		},
+	}
+}
+
+// Add registers another sink
+func (d *Dispatcher) Add(sink Sink) {
+	d.sinks = append(d.sinks, sink)
+}
+
+// Len returns the number of sinks
+func (d *Dispatcher) Len() int {
+	return len(d.sinks)
+}
+
+// Dispatch sends every event to every sink. Sinks run concurrently; events
+// are delivered to each sink in order.
+func (d *Dispatcher) Dispatch(ctx context.Context, events []Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, sink := range d.sinks {
+		wg.Add(1)
+		go func(sink Sink) {
+			defer wg.Done()
+			for _, event := range events {
+				if err := sink.Send(ctx, event); err != nil && d.OnError != nil {
+					d.OnError(sink, event, err)
+				}
+			}
+		}(sink)
+	}
+	wg.Wait()
+}
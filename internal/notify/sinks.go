@@ -0,0 +1,275 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// WriterSink writes one line of text per event
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewStdoutSink creates a sink printing events to stdout
+func NewStdoutSink() *WriterSink {
+	return &WriterSink{w: os.Stdout}
+}
+
+// Name returns the sink name
+func (s *WriterSink) Name() string {
+	return "stdout"
+}
+
+// Send writes the event as a line of text
+func (s *WriterSink) Send(ctx context.Context, event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target := ""
+	if event.Target != "" {
+		target = "[" + event.Target + "] "
+	}
+	_, err := fmt.Fprintf(s.w, "%s %s%-8s %-12s %s: %s\n",
+		event.Time.Format(time.RFC3339), target, event.Severity, event.Kind, event.Host, event.Message)
+	return err
+}
+
+// FileSink appends events to a file as JSON lines
+type FileSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileSink creates a sink appending to path
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+// Name returns the sink name
+func (s *FileSink) Name() string {
+	return "file:" + s.path
+}
+
+// Send appends the event to the file
+func (s *FileSink) Send(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// WebhookSink posts each event as JSON to a URL. Without a template the
+// event itself is posted; with one the rendered template is the body.
+type WebhookSink struct {
+	url      string
+	client   *http.Client
+	template *template.Template
+}
+
+// NewWebhookSink creates a sink posting to url
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewTemplateWebhookSink creates a sink posting the rendered text/template
+// body to url. The template receives the Event and may use the json
+// function to quote values, e.g. {"text": {{json .Message}}}.
+func NewTemplateWebhookSink(url, body string) (*WebhookSink, error) {
+	tmpl, err := template.New("webhook").Funcs(templateFuncs).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("parse webhook template: %v", err)
+	}
+
+	sink := NewWebhookSink(url)
+	sink.template = tmpl
+	return sink, nil
+}
+
+// Name returns the sink name
+func (s *WebhookSink) Name() string {
+	return "webhook:" + s.url
+}
+
+// Send posts the event
+func (s *WebhookSink) Send(ctx context.Context, event Event) error {
+	var body []byte
+	var err error
+
+	if s.template == nil {
+		body, err = json.Marshal(event)
+	} else {
+		body, err = renderJSON(s.template, event)
+	}
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.client, s.url, body)
+}
+
+// templateFuncs are available to webhook templates
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"upper": strings.ToUpper,
+}
+
+// renderJSON executes a template and checks that the output is valid JSON
+func renderJSON(tmpl *template.Template, event Event) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, event); err != nil {
+		return nil, fmt.Errorf("render webhook template: %v", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("webhook template did not produce valid JSON")
+	}
+	return buf.Bytes(), nil
+}
+
+// SlackSink posts events as Slack-compatible incoming webhook messages
+type SlackSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewSlackSink creates a sink posting to a Slack incoming webhook URL
+func NewSlackSink(url string) *SlackSink {
+	return &SlackSink{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name returns the sink name
+func (s *SlackSink) Name() string {
+	return "slack"
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type slackAttachment struct {
+	Color    string       `json:"color"`
+	Title    string       `json:"title"`
+	Text     string       `json:"text"`
+	Fields   []slackField `json:"fields"`
+	Fallback string       `json:"fallback"`
+	Ts       int64        `json:"ts"`
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+// Send posts the event as a Slack message
+func (s *SlackSink) Send(ctx context.Context, event Event) error {
+	summary := fmt.Sprintf("[%s] %s on %s", strings.ToUpper(event.Severity), event.Kind, endpoint(event))
+
+	fields := []slackField{
+		{Title: "Host", Value: endpoint(event), Short: true},
+		{Title: "Severity", Value: event.Severity, Short: true},
+	}
+	if event.Target != "" {
+		fields = append(fields, slackField{Title: "Target", Value: event.Target, Short: true})
+	}
+	if event.Service != "" {
+		fields = append(fields, slackField{Title: "Service", Value: event.Service, Short: true})
+	}
+	if event.CertExpiry != nil {
+		fields = append(fields, slackField{Title: "Certificate Expiry", Value: event.CertExpiry.Format("2006-01-02"), Short: true})
+	}
+
+	body, err := json.Marshal(slackMessage{
+		Text: summary,
+		Attachments: []slackAttachment{{
+			Color:    severityColor(event.Severity),
+			Title:    event.Kind,
+			Text:     event.Message,
+			Fields:   fields,
+			Fallback: summary + ": " + event.Message,
+			Ts:       event.Time.Unix(),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.client, s.url, body)
+}
+
+// endpoint formats the host and port of an event
+func endpoint(event Event) string {
+	if event.Port == 0 {
+		return event.Host
+	}
+	return net.JoinHostPort(event.Host, strconv.Itoa(event.Port))
+}
+
+// severityColor maps a severity to a Slack attachment color
+func severityColor(severity string) string {
+	switch severity {
+	case models.SeverityCritical, models.SeverityHigh:
+		return "danger"
+	case models.SeverityMedium:
+		return "warning"
+	case models.SeverityLow:
+		return "good"
+	default:
+		return "#808080"
+	}
+}
+
+// postJSON posts a JSON body and fails on non-2xx responses
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-scan")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
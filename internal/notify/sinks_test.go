@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testEvent = Event{
+	Kind: "new_port", Target: "prod", Host: "192.0.2.10", Port: 8080, Protocol: "tcp",
+	State: "open", Service: "http", Severity: "medium", Message: "port 8080/tcp opened",
+	Time: time.Date(2024, 1, 22, 12, 0, 0, 0, time.UTC),
+}
+
+// webhookServer records the bodies posted to it and answers with status
+func webhookServer(t *testing.T, status int) (string, <-chan []byte) {
+	bodies := make(chan []byte, 8)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s with content type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, bodies
+}
+
+func TestWebhookSink(t *testing.T) {
+	url, bodies := webhookServer(t, http.StatusNoContent)
+	if err := NewWebhookSink(url).Send(context.Background(), testEvent); err != nil {
+		t.Fatal(err)
+	}
+	var got Event
+	if err := json.Unmarshal(<-bodies, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != testEvent {
+		t.Errorf("got %+v, want %+v", got, testEvent)
+	}
+}
+
+func TestTemplateWebhookSink(t *testing.T) {
+	url, bodies := webhookServer(t, http.StatusOK)
+	sink, err := NewTemplateWebhookSink(url, `{"text": {{json .Message}}, "level": "{{upper .Severity}}", "port": {{.Port}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := testEvent
+	event.Message = `a "quoted" message`
+	if err := sink.Send(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(<-bodies), `{"text": "a \"quoted\" message", "level": "MEDIUM", "port": 8080}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// Templates that do not render JSON fail before anything is posted
+	sink, _ = NewTemplateWebhookSink(url, `{"text": "{{.Message}}"}`)
+	if err := sink.Send(context.Background(), event); err == nil || !strings.Contains(err.Error(), "valid JSON") {
+		t.Errorf("got error %v", err)
+	}
+	if _, err := NewTemplateWebhookSink(url, `{{.Message`); err == nil {
+		t.Error("parsing a broken template succeeded")
+	}
+}
+
+func TestWebhookSinkStatus(t *testing.T) {
+	url, _ := webhookServer(t, http.StatusBadGateway)
+	err := NewWebhookSink(url).Send(context.Background(), testEvent)
+	if err == nil || err.Error() != "webhook returned 502 Bad Gateway" {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestSlackSink(t *testing.T) {
+	url, bodies := webhookServer(t, http.StatusOK)
+	expiry := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	event := testEvent
+	event.Severity, event.CertExpiry = "high", &expiry
+	if err := NewSlackSink(url).Send(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	var got slackMessage
+	if err := json.Unmarshal(<-bodies, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != "[HIGH] new_port on 192.0.2.10:8080" || len(got.Attachments) != 1 {
+		t.Fatalf("got %+v", got)
+	}
+	attachment := got.Attachments[0]
+	if attachment.Color != "danger" || attachment.Text != event.Message || attachment.Ts != event.Time.Unix() {
+		t.Errorf("attachment %+v", attachment)
+	}
+	fields := map[string]string{}
+	for _, f := range attachment.Fields {
+		fields[f.Title] = f.Value
+	}
+	want := map[string]string{
+		"Host": "192.0.2.10:8080", "Severity": "high", "Target": "prod", "Service": "http", "Certificate Expiry": "2024-02-01",
+	}
+	if len(fields) != len(want) {
+		t.Errorf("fields %v, want %v", fields, want)
+	}
+	for title, value := range want {
+		if fields[title] != value {
+			t.Errorf("field %s = %q, want %q", title, fields[title], value)
+		}
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink := NewFileSink(path)
+	for i := 0; i < 2; i++ {
+		if err := sink.Send(context.Background(), testEvent); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !json.Valid([]byte(lines[1])) {
+		t.Errorf("file holds %q", data)
+	}
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := &WriterSink{w: &buf}
+	sink.Send(context.Background(), testEvent)
+	if got, want := buf.String(), "2024-01-22T12:00:00Z [prod] medium   new_port     192.0.2.10: port 8080/tcp opened\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestBuildSinkErrors(t *testing.T) {
+	tests := []struct {
+		config SinkConfig
+		want   string
+	}{
+		{SinkConfig{Type: "pager"}, `unknown sink type "pager"`},
+		{SinkConfig{Type: TypeFile}, "file sink needs a path"},
+		{SinkConfig{Type: TypeWebhook}, "webhook sink needs a url"},
+		{SinkConfig{Type: TypeSlack}, "slack sink needs a url"},
+		{SinkConfig{Type: TypeEmail}, "email sink needs smtp settings"},
+		{SinkConfig{Type: TypeWebhook, URL: "http://x", Template: "{{"}, "parse webhook template"},
+	}
+	for _, tt := range tests {
+		if _, err := BuildSink(tt.config); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("BuildSink(%+v): got error %v, want %q", tt.config, err, tt.want)
+		}
+	}
+}
@@ -31,8 +31,15 @@ const (
 	SymGeo     = "[G]"
 	SymCert    = "[S]"
 	SymNetwork = "[N]"
+	SymWeb     = "[W]"
+	SymKey     = "[K]"
+	SymProduct = "[P]"
+	SymShare   = "[F]"
 )
 
+// maxBannerWidth is where banners are cut outside verbose mode
+const maxBannerWidth = 120
+
 // FormatterConfig holds the minimal config needed for formatting
 type FormatterConfig struct {
 	Quiet             bool
@@ -179,22 +186,98 @@ func (f *Formatter) printTextResults(result *models.ScanResult) {
 	}
 
 	if result.Banner != "" {
-		fmt.Printf(" - %s%s%s", ColorCyan, result.Banner, ColorReset)
+		banner := result.Banner
+		if !f.config.Verbose && len(banner) > maxBannerWidth {
+			banner = banner[:maxBannerWidth-3] + "..."
+		}
+		fmt.Printf(" - %s%s%s", ColorCyan, banner, ColorReset)
+		if f.config.Verbose && result.BannerFirstByteMs > 0 {
+			fmt.Printf(" %s(%.0fms)%s", ColorGray, result.BannerFirstByteMs, ColorReset)
+		}
 	}
 
 	if result.IsSSL && result.SSLInfo != nil {
-		fmt.Printf(" %s[HTTPS]%s", ColorMagenta, ColorReset)
+		if result.SSLInfo.StartTLS != "" {
+			fmt.Printf(" %s[STARTTLS %s]%s", ColorMagenta, result.SSLInfo.StartTLS, ColorReset)
+		} else {
+			fmt.Printf(" %s[TLS]%s", ColorMagenta, ColorReset)
+		}
+	}
+
+	if result.TLS != nil {
+		fmt.Printf(" %sgrade %s%s", gradeColor(result.TLS.Grade), result.TLS.Grade, ColorReset)
+	}
+
+	if result.Severity != "" {
+		fmt.Printf(" %s[%s]%s", severityColor(result.Severity), strings.ToUpper(result.Severity), ColorReset)
+	}
+
+	if result.SSLInfo != nil && result.SSLInfo.JARMLabel != "" {
+		fmt.Printf(" %s[JARM: %s]%s", ColorYellow, result.SSLInfo.JARMLabel, ColorReset)
 	}
 
 	fmt.Println()
 
-	if result.SSLInfo != nil && f.config.Verbose {
+	if result.SSLInfo != nil && (f.config.Verbose || len(result.SSLInfo.VHosts) > 0) {
 		f.printSSLInfo(result.SSLInfo)
 	}
 
+	if result.TLS != nil {
+		f.printTLSEnumeration(result.TLS)
+	}
+
+	for _, product := range result.Products {
+		f.printProduct(&product)
+	}
+
+	if result.HTTP != nil {
+		f.printHTTPInfo(result.HTTP)
+	}
+
+	if result.SSH != nil {
+		f.printSSHInfo(result.SSH)
+	}
+
+	if result.SMB != nil {
+		f.printSMBInfo(result.SMB)
+	}
+
+	for _, finding := range result.Findings {
+		f.printFinding(&finding)
+	}
+
+	if f.config.Verbose {
+		for _, checkErr := range result.CheckErrors {
+			fmt.Printf("    %s check %s: %s%s%s\n", SymCross, checkErr.Check, ColorRed, checkErr.Error, ColorReset)
+		}
+	}
+
 	if result.Geolocation != nil && f.config.Verbose {
 		f.printGeolocation(result.Geolocation)
 	}
+
+	for _, scriptResult := range result.NmapResults {
+		f.printNmapResult(&scriptResult)
+	}
+}
+
+// printNmapResult prints the output of an nmap script
+func (f *Formatter) printNmapResult(result *models.NmapScriptResult) {
+	if result.Status == "skipped" {
+		if f.config.Verbose {
+			fmt.Printf("    %s Nmap %s: %sdid not apply to this port%s\n", SymInfo, result.Script, ColorGray, ColorReset)
+		}
+		return
+	}
+	if result.Status != "success" {
+		fmt.Printf("    %s Nmap %s: %s%s%s\n", SymWarning, result.Script, ColorRed, result.Error, ColorReset)
+		return
+	}
+
+	fmt.Printf("    %s Nmap %s (%.1fs):\n", SymNetwork, result.Script, result.Duration.Seconds())
+	for _, line := range strings.Split(strings.TrimRight(result.Output, "\n"), "\n") {
+		fmt.Printf("      %s\n", line)
+	}
 }
 
 // printSSLInfo prints SSL certificate information
@@ -207,6 +290,289 @@ func (f *Formatter) printSSLInfo(info *models.SSLCertInfo) {
 		fmt.Printf("      DNS Names: %s\n", strings.Join(info.DNSNames, ", "))
 	}
 	fmt.Printf("      Fingerprint (SHA-256): %s\n", info.Fingerprint)
+	if f.config.Verbose && info.TLSVersion != "" {
+		fmt.Printf("      Negotiated: %s, %s\n", info.TLSVersion, info.CipherSuite)
+	}
+	if info.ALPN != "" {
+		fmt.Printf("      ALPN: %s\n", info.ALPN)
+	}
+	if info.JARM != "" {
+		if info.JARMLabel != "" {
+			fmt.Printf("      JARM: %s (%s%s%s)\n", info.JARM, ColorYellow, info.JARMLabel, ColorReset)
+		} else {
+			fmt.Printf("      JARM: %s\n", info.JARM)
+		}
+	}
+	if v := info.Validation; v != nil {
+		if v.Trusted {
+			fmt.Printf("      Validation: %sTrusted%s\n", ColorGreen, ColorReset)
+		} else {
+			fmt.Printf("      Validation: %s%s%s (%s)\n", ColorRed, v.Verdict, ColorReset, v.Reason)
+		}
+	}
+	if staple := info.OCSPStaple; staple != nil {
+		switch {
+		case staple.Error != "":
+			fmt.Printf("      OCSP staple: %s%s%s\n", ColorRed, staple.Error, ColorReset)
+		case staple.CertStatus == "":
+			fmt.Printf("      OCSP staple: %s\n", staple.ResponseStatus)
+		case staple.NextUpdate != nil:
+			fmt.Printf("      OCSP staple: %s (produced %s, next update %s)\n", staple.CertStatus,
+				staple.ProducedAt.Format("2006-01-02 15:04"), staple.NextUpdate.Format("2006-01-02 15:04"))
+		default:
+			fmt.Printf("      OCSP staple: %s (produced %s)\n", staple.CertStatus, staple.ProducedAt.Format("2006-01-02 15:04"))
+		}
+	} else if f.config.Verbose {
+		fmt.Printf("      OCSP staple: none\n")
+	}
+	if len(info.SCTs) > 0 {
+		fmt.Printf("      SCTs: %d\n", len(info.SCTs))
+	}
+	if f.config.Verbose {
+		if len(info.OCSPServers) > 0 {
+			fmt.Printf("      OCSP: %s\n", strings.Join(info.OCSPServers, ", "))
+		}
+		if len(info.CRLDistributionPoints) > 0 {
+			fmt.Printf("      CRL: %s\n", strings.Join(info.CRLDistributionPoints, ", "))
+		}
+	}
+	if len(info.VHosts) > 0 {
+		fmt.Printf("      Virtual hosts (SNI):\n")
+		for _, vhost := range info.VHosts {
+			switch {
+			case vhost.Error != "":
+				fmt.Printf("        %s: %s%s%s\n", vhost.Name, ColorRed, vhost.Error, ColorReset)
+			case vhost.Different:
+				fmt.Printf("        %s: %s%s%s (%s)%s\n", vhost.Name, ColorYellow, vhost.Subject, ColorReset, vhost.Fingerprint[:16], nameMismatch(vhost.NameMatches))
+			default:
+				fmt.Printf("        %s: default certificate%s\n", vhost.Name, nameMismatch(vhost.NameMatches))
+			}
+		}
+	}
+	if f.config.Verbose && len(info.Chain) > 1 {
+		fmt.Printf("      Chain:\n")
+		for i, cert := range info.Chain {
+			fmt.Printf("        %d. %s\n", i+1, cert.Subject)
+			fmt.Printf("           Issuer: %s\n", cert.Issuer)
+			fmt.Printf("           SHA-256: %s\n", cert.Fingerprint)
+		}
+	}
+}
+
+// printProduct prints an identified product, with its CPE when verbose
+func (f *Formatter) printProduct(product *models.Product) {
+	fmt.Printf("    %s %s%s%s", SymProduct, ColorBold, product.Name, ColorReset)
+	if product.Version != "" {
+		fmt.Printf(" %s", product.Version)
+	}
+	if f.config.Verbose {
+		fmt.Printf(" %s%s%s", ColorGray, product.CPE, ColorReset)
+	}
+	fmt.Println()
+}
+
+// printHTTPInfo prints what HTTP enumeration found
+func (f *Formatter) printHTTPInfo(info *models.HTTPInfo) {
+	if info.Error != "" {
+		fmt.Printf("    %s %s: %s%s%s\n", SymWeb, info.URL, ColorRed, info.Error, ColorReset)
+		return
+	}
+
+	fmt.Printf("    %s %s %d", SymWeb, info.URL, info.StatusCode)
+	if info.Title != "" {
+		fmt.Printf(" %s%q%s", ColorBold, info.Title, ColorReset)
+	}
+	var software []string
+	if info.Server != "" {
+		software = append(software, info.Server)
+	}
+	if info.PoweredBy != "" {
+		software = append(software, info.PoweredBy)
+	}
+	if len(software) > 0 {
+		fmt.Printf(" [%s]", strings.Join(software, ", "))
+	}
+	fmt.Println()
+
+	for _, hop := range info.Redirects {
+		fmt.Printf("      %s %d %s\n", SymArrow, hop.StatusCode, hop.Location)
+	}
+	if !f.config.Verbose {
+		return
+	}
+	fmt.Printf("      Content: %s, %d bytes\n", info.ContentType, info.ContentLength)
+	if info.FaviconHash != nil {
+		fmt.Printf("      Favicon: %d (%s)\n", *info.FaviconHash, info.FaviconURL)
+	}
+	if len(info.Methods) > 0 {
+		fmt.Printf("      Methods: %s\n", strings.Join(info.Methods, ", "))
+	}
+}
+
+// printSSHInfo prints the SSH version and host keys, and in verbose mode
+// the offered algorithms
+func (f *Formatter) printSSHInfo(info *models.SSHInfo) {
+	if info.Software != "" {
+		fmt.Printf("    %s SSH %s %s%s%s", SymKey, info.ProtocolVersion, ColorBold, info.Software, ColorReset)
+		if info.Comments != "" {
+			fmt.Printf(" (%s)", info.Comments)
+		}
+		fmt.Println()
+	}
+	for _, key := range info.HostKeys {
+		fmt.Printf("      %s %d %s\n", key.Type, key.Bits, key.FingerprintSHA256)
+	}
+	if info.Error != "" && (f.config.Verbose || len(info.HostKeys) == 0) {
+		fmt.Printf("      %sSSH: %s%s\n", ColorRed, info.Error, ColorReset)
+	}
+	if !f.config.Verbose {
+		return
+	}
+	for _, list := range []struct {
+		name  string
+		names []string
+	}{
+		{"Key exchange", info.KexAlgorithms},
+		{"Host keys", info.HostKeyAlgorithms},
+		{"Ciphers", info.CiphersClientToServer},
+		{"MACs", info.MACsClientToServer},
+		{"Compression", info.CompressionClientToServer},
+	} {
+		if len(list.names) > 0 {
+			fmt.Printf("      %s: %s\n", list.name, strings.Join(list.names, ", "))
+		}
+	}
+}
+
+// printSMBInfo prints the SMB dialects, signing mode and what the NTLMSSP
+// challenge revealed, and in verbose mode the server GUID and capabilities
+func (f *Formatter) printSMBInfo(info *models.SMBInfo) {
+	if info.Dialect != "" || info.SMB1 {
+		dialects := info.Dialects
+		if info.SMB1 {
+			dialects = append([]string{"NT LM 0.12"}, dialects...)
+		}
+		signing := "signing required"
+		switch {
+		case !info.SigningEnabled:
+			signing = "signing disabled"
+		case !info.SigningRequired:
+			signing = "signing not required"
+		}
+		fmt.Printf("    %s SMB %s%s%s (%s)\n", SymShare, ColorBold, strings.Join(dialects, ", "), ColorReset, signing)
+	}
+	if info.OS != "" || info.NativeOS != "" {
+		osName := info.NativeOS
+		if osName == "" {
+			osName = fmt.Sprintf("%s (%s)", info.OS, info.OSVersion)
+		}
+		fmt.Printf("      OS: %s\n", osName)
+	}
+	name := info.DNSComputerName
+	if name == "" {
+		name = info.NetBIOSComputerName
+	}
+	if name != "" {
+		fmt.Printf("      Computer: %s", name)
+		if info.NetBIOSDomainName != "" {
+			fmt.Printf(", domain %s", info.NetBIOSDomainName)
+		}
+		if info.DNSDomainName != "" && info.DNSDomainName != info.NetBIOSDomainName {
+			fmt.Printf(" (%s)", info.DNSDomainName)
+		}
+		fmt.Println()
+	}
+	if info.Error != "" && (f.config.Verbose || (info.Dialect == "" && !info.SMB1)) {
+		fmt.Printf("      %sSMB: %s%s\n", ColorRed, info.Error, ColorReset)
+	}
+	if !f.config.Verbose {
+		return
+	}
+	if info.ServerGUID != "" {
+		fmt.Printf("      Server GUID: %s\n", info.ServerGUID)
+	}
+	if info.SystemTime != nil {
+		fmt.Printf("      System time: %s\n", info.SystemTime.Format(time.RFC3339))
+	}
+	if len(info.Capabilities) > 0 {
+		fmt.Printf("      Capabilities: %s\n", strings.Join(info.Capabilities, ", "))
+	}
+	if info.Cipher != "" {
+		fmt.Printf("      Cipher: %s\n", info.Cipher)
+	}
+	if info.NativeLanManager != "" {
+		fmt.Printf("      LAN manager: %s\n", info.NativeLanManager)
+	}
+}
+
+// printFinding prints a finding with its severity
+func (f *Formatter) printFinding(finding *models.Finding) {
+	fmt.Printf("    %s %s%-8s%s %s", SymWarning, severityColor(finding.Severity), strings.ToUpper(finding.Severity), ColorReset, finding.Title)
+	if finding.Detail != "" {
+		fmt.Printf(": %s", finding.Detail)
+	}
+	fmt.Println()
+	if finding.Evidence != "" {
+		fmt.Printf("             %s%s%s\n", ColorGray, finding.Evidence, ColorReset)
+	}
+}
+
+// severityColor picks the color for a severity level
+func severityColor(severity string) string {
+	switch severity {
+	case models.SeverityCritical, models.SeverityHigh:
+		return ColorRed
+	case models.SeverityMedium:
+		return ColorYellow
+	case models.SeverityLow:
+		return ColorCyan
+	default:
+		return ColorGray
+	}
+}
+
+// nameMismatch marks a certificate that is not valid for the name sent
+func nameMismatch(matches bool) string {
+	if matches {
+		return ""
+	}
+	return " " + ColorRed + "[name mismatch]" + ColorReset
+}
+
+// printTLSEnumeration prints accepted TLS versions and grade warnings, and
+// the cipher suites in verbose mode
+func (f *Formatter) printTLSEnumeration(enum *models.TLSEnumeration) {
+	fmt.Printf("    %s TLS: grade %s%s%s\n", SymCert, gradeColor(enum.Grade), enum.Grade, ColorReset)
+	for _, warning := range enum.Warnings {
+		fmt.Printf("      %s %s\n", SymWarning, warning)
+	}
+	for _, version := range enum.Versions {
+		order := "client order"
+		if version.ServerPreference {
+			order = "server order"
+		}
+		fmt.Printf("      %s: %d cipher suites (%s)\n", version.Version, len(version.Ciphers), order)
+		if f.config.Verbose {
+			for _, cipher := range version.Ciphers {
+				fmt.Printf("        %-48s %s\n", cipher.Name, cipher.Rating)
+			}
+		}
+	}
+	if len(enum.Groups) > 0 {
+		fmt.Printf("      Groups: %s\n", strings.Join(enum.Groups, ", "))
+	}
+}
+
+// gradeColor picks the color for a TLS grade
+func gradeColor(grade string) string {
+	switch grade {
+	case "A":
+		return ColorGreen
+	case "B", "C":
+		return ColorYellow
+	default:
+		return ColorRed
+	}
 }
 
 // printGeolocation prints geolocation information
@@ -232,13 +598,12 @@ func (f *Formatter) PrintStatistics(stats *models.ScanStats) {
 		return
 	}
 
-	duration := time.Since(stats.StartTime)
 	fmt.Printf("\n%s%s SCAN STATISTICS %s\n", ColorBold, ColorCyan, ColorReset)
 	fmt.Printf("  %s Total Ports Scanned : %d\n", SymInfo, stats.TotalPorts)
 	fmt.Printf("  %s Open Ports          : %s%d%s\n", SymCheck, ColorGreen, stats.OpenPorts, ColorReset)
 	fmt.Printf("  %s Closed Ports        : %s%d%s\n", SymCross, ColorRed, stats.ClosedPorts, ColorReset)
 	fmt.Printf("  %s Filtered Ports      : %s%d%s\n", SymWarning, ColorYellow, stats.FilteredPorts, ColorReset)
-	fmt.Printf("  %s Scan Duration       : %.2fs\n", SymBolt, duration.Seconds())
+	fmt.Printf("  %s Scan Duration       : %.2fs\n", SymBolt, stats.DurationSeconds)
 	fmt.Println()
 }
 
@@ -0,0 +1,308 @@
+// Package plugin runs checks written as external executables. A manifest
+// names the command and the ports it applies to; for each matching open
+// port the command gets a JSON request on stdin and answers with JSON
+// findings on stdout.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/check"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// ProtocolVersion is sent in every request; plugins can refuse versions
+// they do not know
+const ProtocolVersion = 1
+
+// Limits on plugin runs
+const (
+	DefaultTimeout     = 30 * time.Second
+	DefaultConcurrency = 4
+	maxOutput          = 1 << 20
+)
+
+// Manifest describes an external plugin
+type Manifest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Command     []string `json:"command"` // executable and arguments
+	Protocol    string   `json:"protocol,omitempty"`
+	Ports       []int    `json:"ports,omitempty"`
+	Services    []string `json:"services,omitempty"`
+	Banner      string   `json:"banner,omitempty"` // regexp matched against the banner
+
+	// TimeoutSeconds bounds each run; 0 means DefaultTimeout
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+
+	dir    string // holds the manifest; relative commands resolve against it
+	banner *regexp.Regexp
+}
+
+// Request is the JSON a plugin reads from stdin
+type Request struct {
+	Version    int                 `json:"version"`
+	Host       string              `json:"host"`
+	Address    string              `json:"address"` // host:port to connect to
+	Port       int                 `json:"port"`
+	Protocol   string              `json:"protocol"`
+	Service    string              `json:"service,omitempty"`
+	Banner     string              `json:"banner,omitempty"`
+	BannerRaw  []byte              `json:"banner_raw,omitempty"`
+	TLS        bool                `json:"tls"` // the port speaks TLS from the first byte
+	ServerName string              `json:"server_name,omitempty"`
+	SSLInfo    *models.SSLCertInfo `json:"ssl_info,omitempty"`
+	Products   []models.Product    `json:"products,omitempty"`
+}
+
+// Response is the JSON a plugin writes to stdout
+type Response struct {
+	Findings []models.Finding `json:"findings"`
+	Error    string           `json:"error,omitempty"` // the check could not run
+}
+
+// LoadManifests reads manifest files, or every .json file in the given
+// directories
+func LoadManifests(paths []string) ([]*Manifest, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+
+	var manifests []*Manifest
+	for _, file := range files {
+		manifest, err := loadManifest(file)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+	return manifests, nil
+}
+
+// loadManifest reads and validates one manifest
+func loadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse %s: %v", path, err)
+	}
+
+	switch {
+	case m.Name == "":
+		return nil, fmt.Errorf("%s: name is required", path)
+	case len(m.Command) == 0 || m.Command[0] == "":
+		return nil, fmt.Errorf("%s: command is required", path)
+	case len(m.Ports) == 0 && len(m.Services) == 0 && m.Banner == "":
+		return nil, fmt.Errorf("%s: no ports, services or banner to apply to", path)
+	case m.TimeoutSeconds < 0:
+		return nil, fmt.Errorf("%s: negative timeout", path)
+	}
+	switch m.Protocol {
+	case "", check.TCP, check.UDP:
+	default:
+		return nil, fmt.Errorf("%s: unknown protocol %q", path, m.Protocol)
+	}
+	if m.Banner != "" {
+		if m.banner, err = regexp.Compile(m.Banner); err != nil {
+			return nil, fmt.Errorf("%s: banner: %v", path, err)
+		}
+	}
+	if m.dir, err = filepath.Abs(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Checks turns manifests into checks. At most concurrency plugin
+// processes run at once across all of them.
+func Checks(manifests []*Manifest, concurrency int) []check.Check {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	slots := make(chan struct{}, concurrency)
+
+	checks := make([]check.Check, len(manifests))
+	for i, m := range manifests {
+		checks[i] = &pluginCheck{manifest: m, slots: slots}
+	}
+	return checks
+}
+
+// pluginCheck runs a manifest's command as a check
+type pluginCheck struct {
+	manifest *Manifest
+	slots    chan struct{}
+}
+
+func (c *pluginCheck) Info() check.Info {
+	m := c.manifest
+	timeout := DefaultTimeout
+	if m.TimeoutSeconds > 0 {
+		timeout = time.Duration(m.TimeoutSeconds) * time.Second
+	}
+	return check.Info{
+		Name:        m.Name,
+		Description: m.Description,
+		Protocol:    m.Protocol,
+		Timeout:     timeout,
+		Ports:       m.Ports,
+		Services:    m.Services,
+		Banner:      m.banner,
+	}
+}
+
+func (c *pluginCheck) Run(ctx context.Context, target *check.Target) ([]models.Finding, error) {
+	select {
+	case c.slots <- struct{}{}:
+		defer func() { <-c.slots }()
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for a plugin slot: %v", ctx.Err())
+	}
+
+	request, err := json.Marshal(newRequest(target))
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := c.run(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return parseResponse(stdout)
+}
+
+// run starts the command, feeds it the request and collects its output
+func (c *pluginCheck) run(ctx context.Context, request []byte) ([]byte, error) {
+	m := c.manifest
+	name := m.Command[0]
+	if strings.ContainsRune(name, filepath.Separator) && !filepath.IsAbs(name) {
+		name = filepath.Join(m.dir, name)
+	}
+
+	cmd := exec.CommandContext(ctx, name, m.Command[1:]...)
+	cmd.Dir = m.dir
+	cmd.Stdin = bytes.NewReader(request)
+	var stdout, stderr limitedBuffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Do not wait forever for children that inherited the pipes
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("killed: %v", ctx.Err())
+	}
+	if err != nil {
+		if msg := firstLine(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	if stdout.truncated {
+		return nil, fmt.Errorf("output exceeds %d bytes", maxOutput)
+	}
+	return stdout.Bytes(), nil
+}
+
+// newRequest describes a target to a plugin
+func newRequest(target *check.Target) *Request {
+	request := &Request{
+		Version:    ProtocolVersion,
+		Host:       target.Host,
+		Address:    target.Address,
+		Port:       target.Port,
+		Protocol:   target.Protocol,
+		TLS:        target.TLS,
+		ServerName: target.ServerName,
+	}
+	if result := target.Result; result != nil {
+		request.Service = result.Service
+		request.Banner = result.Banner
+		request.BannerRaw = result.BannerRaw
+		request.SSLInfo = result.SSLInfo
+		request.Products = result.Products
+	}
+	return request
+}
+
+// parseResponse decodes and validates a plugin's output
+func parseResponse(stdout []byte) ([]models.Finding, error) {
+	if len(bytes.TrimSpace(stdout)) == 0 {
+		return nil, nil // nothing found
+	}
+	var response Response
+	if err := json.Unmarshal(stdout, &response); err != nil {
+		return nil, fmt.Errorf("invalid output: %v", err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("%s", response.Error)
+	}
+
+	for i := range response.Findings {
+		finding := &response.Findings[i]
+		finding.Severity = strings.ToLower(finding.Severity)
+		if finding.ID == "" || finding.Title == "" {
+			return nil, fmt.Errorf("finding %d: id and title are required", i+1)
+		}
+		if models.SeverityRank(finding.Severity) == 0 {
+			return nil, fmt.Errorf("finding %s: unknown severity %q", finding.ID, finding.Severity)
+		}
+		finding.Check = "" // set by the registry
+	}
+	return response.Findings, nil
+}
+
+// limitedBuffer keeps the first maxOutput bytes written to it. The buffer
+// is a field rather than embedded so that io.Copy cannot bypass Write
+// through its ReadFrom.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := maxOutput - b.buf.Len(); len(p) > room {
+		b.truncated = true
+		b.buf.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) Bytes() []byte { return b.buf.Bytes() }
+
+func (b *limitedBuffer) String() string { return b.buf.String() }
+
+// firstLine returns the first non-empty line of s
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,181 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/check"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// TestHelperProcess is not a test: it is the plugin the other tests run,
+// as the test binary started again with the behaviour after "--"
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GOSCAN_PLUGIN_HELPER") != "1" {
+		return
+	}
+	mode := os.Args[len(os.Args)-1]
+
+	var request Request
+	if err := json.NewDecoder(os.Stdin).Decode(&request); err != nil {
+		fmt.Fprintf(os.Stderr, "bad request: %v\n", err)
+		os.Exit(2)
+	}
+
+	switch mode {
+	case "good":
+		fmt.Printf(`{"findings": [{"id": "echo", "title": "%s %s:%d v%d", "severity": "HIGH", "check": "spoofed"}]}`,
+			request.Service, request.Host, request.Port, request.Version)
+	case "empty":
+	case "invalid-json":
+		fmt.Print("findings: none")
+	case "oversized":
+		fmt.Print(`{"findings": [], "padding": "` + strings.Repeat("x", maxOutput) + `"}`)
+	case "bad-severity":
+		fmt.Print(`{"findings": [{"id": "x", "title": "x", "severity": "urgent"}]}`)
+	case "no-title":
+		fmt.Print(`{"findings": [{"id": "x", "severity": "low"}]}`)
+	case "error":
+		fmt.Print(`{"error": "cannot reach the admin port"}`)
+	case "crash":
+		fmt.Fprintln(os.Stderr, "\npanic: boom")
+		os.Exit(3)
+	case "hang":
+		time.Sleep(time.Minute)
+	}
+	os.Exit(0)
+}
+
+// helperCheck is a check running the helper process in mode
+func helperCheck(t *testing.T, mode string) check.Check {
+	t.Setenv("GOSCAN_PLUGIN_HELPER", "1")
+	manifest := &Manifest{
+		Name:    "helper-" + mode,
+		Command: []string{os.Args[0], "-test.run=^TestHelperProcess$", "--", mode},
+		Ports:   []int{8080},
+		dir:     t.TempDir(),
+	}
+	return Checks([]*Manifest{manifest}, 1)[0]
+}
+
+var target = &check.Target{
+	Host:     "example.com",
+	Address:  "192.0.2.10:8080",
+	Port:     8080,
+	Protocol: check.TCP,
+	Result:   &models.ScanResult{Port: 8080, Protocol: check.TCP, Service: "http-alt"},
+}
+
+func TestRun(t *testing.T) {
+	findings, err := helperCheck(t, "good").Run(context.Background(), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.Finding{ID: "echo", Title: "http-alt example.com:8080 v1", Severity: "high"}
+	if len(findings) != 1 || findings[0].ID != want.ID || findings[0].Title != want.Title ||
+		findings[0].Severity != want.Severity || findings[0].Check != "" {
+		t.Errorf("got %+v, want %+v", findings, want)
+	}
+
+	findings, err = helperCheck(t, "empty").Run(context.Background(), target)
+	if err != nil || findings != nil {
+		t.Errorf("empty output: got %+v, %v", findings, err)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{"invalid-json", "invalid output: invalid character"},
+		{"oversized", fmt.Sprintf("output exceeds %d bytes", maxOutput)},
+		{"bad-severity", `finding x: unknown severity "urgent"`},
+		{"no-title", "finding 1: id and title are required"},
+		{"error", "cannot reach the admin port"},
+		{"crash", "exit status 3: panic: boom"},
+	}
+	for _, tt := range tests {
+		findings, err := helperCheck(t, tt.mode).Run(context.Background(), target)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) || findings != nil {
+			t.Errorf("%s: got %+v, %v, want error %q", tt.mode, findings, err, tt.want)
+		}
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := helperCheck(t, "hang").Run(ctx, target)
+	if err == nil || err.Error() != "killed: context deadline exceeded" {
+		t.Errorf("got error %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the plugin was not killed: returned after %v", elapsed)
+	}
+
+	// A check whose slot is taken gives up when its context ends
+	c := helperCheck(t, "good").(*pluginCheck)
+	c.slots <- struct{}{}
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.Run(ctx, target); err == nil || !strings.HasPrefix(err.Error(), "waiting for a plugin slot") {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestLoadManifests(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, manifest string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(manifest), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	good := write("good.json", `{"name": "acme", "command": ["./acme-check", "-v"], "ports": [8000], "banner": "^Acme", "timeout_seconds": 5}`)
+
+	manifests, err := LoadManifests([]string{good})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := Checks(manifests, 0)[0].Info()
+	if info.Name != "acme" || info.Timeout != 5*time.Second || info.Banner == nil || manifests[0].dir != dir {
+		t.Errorf("got %+v from %+v", info, manifests[0])
+	}
+
+	tests := []struct {
+		manifest string
+		want     string
+	}{
+		{`{"command": ["x"], "ports": [1]}`, "name is required"},
+		{`{"name": "x", "ports": [1]}`, "command is required"},
+		{`{"name": "x", "command": ["x"]}`, "no ports, services or banner to apply to"},
+		{`{"name": "x", "command": ["x"], "ports": [1], "timeout_seconds": -1}`, "negative timeout"},
+		{`{"name": "x", "command": ["x"], "ports": [1], "protocol": "sctp"}`, `unknown protocol "sctp"`},
+		{`{"name": "x", "command": ["x"], "banner": "("}`, "banner: error parsing regexp"},
+		{`{"name": `, "parse "},
+	}
+	for _, tt := range tests {
+		path := write("bad.json", tt.manifest)
+		if _, err := LoadManifests([]string{path}); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got error %v, want %q", tt.manifest, err, tt.want)
+		}
+	}
+
+	// A directory loads every manifest in it, here the good and the last bad one
+	if _, err := LoadManifests([]string{dir}); err == nil {
+		t.Error("loading a directory with a bad manifest succeeded")
+	}
+	if _, err := LoadManifests([]string{filepath.Join(dir, "missing.json")}); err == nil {
+		t.Error("loading a missing manifest succeeded")
+	}
+}
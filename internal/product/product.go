@@ -0,0 +1,168 @@
+// Package product turns banners, HTTP headers and SSH version strings into
+// normalized vendor, product and version names with CPE 2.3 strings
+package product
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// Rule recognizes one product in one source. The version is the
+// "version" named group of Pattern, or its first group; an optional
+// "update" group fills the CPE update field (e.g. OpenSSH's "p1").
+type Rule struct {
+	Source  string `json:"source"`
+	Pattern string `json:"pattern"`
+	Name    string `json:"name,omitempty"` // display name, default Product
+	Vendor  string `json:"vendor"`
+	Product string `json:"product"`
+	Service string `json:"service,omitempty"` // protocol, e.g. "ssh"
+	Part    string `json:"part,omitempty"`    // CPE part: a (default), o or h
+
+	re *regexp.Regexp
+}
+
+// Rules are tried in order; the first match of a product wins
+type Rules []*Rule
+
+// DefaultRules returns the built-in rules, compiled
+func DefaultRules() Rules {
+	rules := make(Rules, len(defaultRules))
+	for i := range defaultRules {
+		rule := defaultRules[i]
+		rule.re = regexp.MustCompile(rule.Pattern)
+		rules[i] = &rule
+	}
+	return rules
+}
+
+// LoadRules reads a JSON array of rules. They are tried before the
+// built-in rules, so they can refine or override them.
+func LoadRules(path string) (Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var custom []*Rule
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, fmt.Errorf("parse %s: %v", path, err)
+	}
+	for i, rule := range custom {
+		switch rule.Source {
+		case SourceBanner, SourceHTTPServer, SourceHTTPPoweredBy, SourceSSH:
+		default:
+			return nil, fmt.Errorf("%s: rule %d: unknown source %q", path, i+1, rule.Source)
+		}
+		if rule.Vendor == "" || rule.Product == "" {
+			return nil, fmt.Errorf("%s: rule %d: vendor and product are required", path, i+1)
+		}
+		if rule.re, err = regexp.Compile(rule.Pattern); err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %v", path, i+1, err)
+		}
+	}
+	return append(Rules(custom), DefaultRules()...), nil
+}
+
+// Identify lists the products a scan result reveals
+func (rules Rules) Identify(result *models.ScanResult) []models.Product {
+	sources := sourceValues(result)
+
+	var products []models.Product
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		value := sources[rule.Source]
+		key := rule.Vendor + ":" + rule.Product
+		if value == "" || seen[key] {
+			continue
+		}
+		match := rule.re.FindStringSubmatch(value)
+		if match == nil {
+			continue
+		}
+		seen[key] = true
+		products = append(products, rule.product(match))
+	}
+	return products
+}
+
+// product builds the identified product from a match
+func (rule *Rule) product(match []string) models.Product {
+	var version, update string
+	for i, name := range rule.re.SubexpNames() {
+		switch {
+		case name == "version" || (name == "" && i == 1 && version == ""):
+			version = match[i]
+		case name == "update":
+			update = match[i]
+		}
+	}
+	version = strings.TrimRight(version, ".")
+
+	name := rule.Name
+	if name == "" {
+		name = rule.Product
+	}
+	return models.Product{
+		Name:    name,
+		Vendor:  rule.Vendor,
+		Product: rule.Product,
+		Version: version + update,
+		Service: rule.Service,
+		CPE:     CPE(rule.Part, rule.Vendor, rule.Product, version, update),
+		Source:  rule.Source,
+	}
+}
+
+// CPE formats a CPE 2.3 string. Empty fields are "*" and the part
+// defaults to "a" (application).
+func CPE(part, vendor, product, version, update string) string {
+	if part == "" {
+		part = "a"
+	}
+	fields := []string{part, vendor, product, version, update}
+	for i, field := range fields {
+		fields[i] = cpeEscape(strings.ToLower(field))
+	}
+	return "cpe:2.3:" + strings.Join(fields, ":") + ":*:*:*:*:*:*"
+}
+
+// cpeEscape quotes a CPE formatted-string value; empty means any
+func cpeEscape(value string) string {
+	if value == "" {
+		return "*"
+	}
+	var b strings.Builder
+	for _, r := range value {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '.') {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// sourceValues collects the text each rule source matches against
+func sourceValues(result *models.ScanResult) map[string]string {
+	values := map[string]string{SourceBanner: result.Banner}
+
+	if result.HTTP != nil {
+		values[SourceHTTPServer] = result.HTTP.Server
+		values[SourceHTTPPoweredBy] = result.HTTP.PoweredBy
+	} else if _, server, ok := strings.Cut(result.Banner, " (Server: "); ok && strings.HasPrefix(result.Banner, "HTTP/") {
+		// Banners drawn by the HTTP probes end with the Server header
+		values[SourceHTTPServer] = strings.TrimSuffix(server, ")")
+	}
+
+	if result.SSH != nil && result.SSH.VersionString != "" {
+		values[SourceSSH] = result.SSH.VersionString
+	} else if strings.HasPrefix(result.Banner, "SSH-") {
+		values[SourceSSH] = result.Banner
+	}
+	return values
+}
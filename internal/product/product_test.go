@@ -0,0 +1,174 @@
+package product
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+func TestIdentify(t *testing.T) {
+	tests := []struct {
+		name   string
+		result models.ScanResult
+		want   []string // CPEs
+	}{
+		{
+			name:   "OpenSSH with update",
+			result: models.ScanResult{Banner: "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13.5"},
+			want:   []string{"cpe:2.3:a:openbsd:openssh:9.6:p1:*:*:*:*:*:*"},
+		},
+		{
+			name: "SSH scan preferred over banner",
+			result: models.ScanResult{
+				Banner: "SSH-2.0-OpenSSH_8.0",
+				SSH:    &models.SSHInfo{VersionString: "SSH-2.0-dropbear_2022.83"},
+			},
+			want: []string{"cpe:2.3:a:dropbear_ssh_project:dropbear_ssh:2022.83:*:*:*:*:*:*:*"},
+		},
+		{
+			name: "HTTP headers",
+			result: models.ScanResult{HTTP: &models.HTTPInfo{
+				Server:    "Apache/2.4.58 (Unix) OpenSSL/3.0.13",
+				PoweredBy: "PHP/8.2.12",
+			}},
+			want: []string{
+				"cpe:2.3:a:apache:http_server:2.4.58:*:*:*:*:*:*:*",
+				"cpe:2.3:a:openssl:openssl:3.0.13:*:*:*:*:*:*:*",
+				"cpe:2.3:a:php:php:8.2.12:*:*:*:*:*:*:*",
+			},
+		},
+		{
+			name:   "nginx without version",
+			result: models.ScanResult{HTTP: &models.HTTPInfo{Server: "nginx"}},
+			want:   []string{"cpe:2.3:a:nginx:nginx:*:*:*:*:*:*:*:*"},
+		},
+		{
+			name:   "Server header in an HTTP probe banner",
+			result: models.ScanResult{Banner: "HTTP/1.1 200 OK (Server: nginx/1.24.0)"},
+			want:   []string{"cpe:2.3:a:nginx:nginx:1.24.0:*:*:*:*:*:*:*"},
+		},
+		{
+			name:   "MySQL greeting",
+			result: models.ScanResult{Banner: `J\x00\x00\x00\n8.0.36\x00\x0c\x00\x00\x00`},
+			want:   []string{"cpe:2.3:a:oracle:mysql:8.0.36:*:*:*:*:*:*:*"},
+		},
+		{
+			name:   "MariaDB greeting",
+			result: models.ScanResult{Banner: `Z\x00\x00\x00\n5.5.5-10.11.6-MariaDB-0+deb12u1\x00`},
+			want:   []string{"cpe:2.3:a:mariadb:mariadb:10.11.6:*:*:*:*:*:*:*"},
+		},
+		{
+			name:   "Cisco is an operating system",
+			result: models.ScanResult{Banner: "SSH-2.0-Cisco-1.25"},
+			want:   []string{"cpe:2.3:o:cisco:ios:1.25:*:*:*:*:*:*:*"},
+		},
+		{
+			name:   "nothing known",
+			result: models.ScanResult{Banner: "220 mystery service ready"},
+		},
+	}
+	rules := DefaultRules()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range rules.Identify(&tt.result) {
+				got = append(got, p.CPE)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdentifyProduct(t *testing.T) {
+	result := &models.ScanResult{Banner: "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13.5"}
+	got := DefaultRules().Identify(result)
+	want := []models.Product{{
+		Name: "OpenSSH", Vendor: "openbsd", Product: "openssh", Version: "9.6p1", Service: "ssh",
+		CPE: "cpe:2.3:a:openbsd:openssh:9.6:p1:*:*:*:*:*:*", Source: SourceSSH,
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	path := writeRules(t, `[{"source": "banner", "pattern": "^220 (?P<version>\\d+\\.\\d+) AcmeFTP", "vendor": "acme", "product": "acmeftp"},
+		{"source": "http_server", "pattern": "^nginx", "vendor": "acme", "product": "edge"}]`)
+	rules, err := LoadRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != len(defaultRules)+2 {
+		t.Fatalf("got %d rules, want the custom ones before %d built-in", len(rules), len(defaultRules))
+	}
+
+	// Custom rules come first; the built-in nginx rule still matches too
+	got := rules.Identify(&models.ScanResult{HTTP: &models.HTTPInfo{Server: "nginx/1.24.0"}})
+	if len(got) != 2 || got[0].Product != "edge" || got[1].Product != "nginx" {
+		t.Errorf("got %+v", got)
+	}
+	got = rules.Identify(&models.ScanResult{Banner: "220 3.1 AcmeFTP ready"})
+	if len(got) != 1 || got[0].Version != "3.1" || got[0].Name != "acmeftp" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestLoadRulesErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+		want  string
+	}{
+		{"not JSON", `{"source":`, "parse "},
+		{"unknown source", `[{"source": "smtp", "pattern": "x", "vendor": "a", "product": "b"}]`, `rule 1: unknown source "smtp"`},
+		{"no vendor", `[{"source": "banner", "pattern": "x", "product": "b"}]`, "rule 1: vendor and product are required"},
+		{"bad pattern", `[{"source": "banner", "pattern": "x", "vendor": "a", "product": "b"},
+			{"source": "banner", "pattern": "(", "vendor": "a", "product": "b"}]`, "rule 2: error parsing regexp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadRules(writeRules(t, tt.rules))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := LoadRules(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("loading a missing file succeeded")
+	}
+}
+
+func TestCPE(t *testing.T) {
+	tests := []struct {
+		part, vendor, product, version, update string
+		want                                   string
+	}{
+		{"", "openbsd", "openssh", "9.6", "p1", "cpe:2.3:a:openbsd:openssh:9.6:p1:*:*:*:*:*:*"},
+		{"o", "cisco", "ios", "", "", "cpe:2.3:o:cisco:ios:*:*:*:*:*:*:*:*"},
+		{"", "Microsoft", "ASP.NET", "", "", "cpe:2.3:a:microsoft:asp.net:*:*:*:*:*:*:*:*"},
+		{"", "filezilla-project", "filezilla_server", "1.8.0", "", "cpe:2.3:a:filezilla-project:filezilla_server:1.8.0:*:*:*:*:*:*:*"},
+		{"", "vercel", "next.js", "14.0.1", "", "cpe:2.3:a:vercel:next.js:14.0.1:*:*:*:*:*:*:*"},
+		{"", "acme", "a b:c", "1.0+build", "", `cpe:2.3:a:acme:a\ b\:c:1.0\+build:*:*:*:*:*:*:*`},
+	}
+	for _, tt := range tests {
+		if got := CPE(tt.part, tt.vendor, tt.product, tt.version, tt.update); got != tt.want {
+			t.Errorf("CPE(%q, %q, %q, %q, %q) = %s, want %s", tt.part, tt.vendor, tt.product, tt.version, tt.update, got, tt.want)
+		}
+	}
+}
+
+func writeRules(t *testing.T, rules string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(rules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
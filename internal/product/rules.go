@@ -0,0 +1,59 @@
+package product
+
+// Sources a rule can match
+const (
+	SourceBanner        = "banner"          // ScanResult.Banner, printable form
+	SourceHTTPServer    = "http_server"     // Server header
+	SourceHTTPPoweredBy = "http_powered_by" // X-Powered-By header
+	SourceSSH           = "ssh"             // SSH identification string
+)
+
+// defaultRules cover common servers. Within a source, more specific rules
+// come first.
+var defaultRules = []Rule{
+	// SSH
+	{Source: SourceSSH, Pattern: `OpenSSH[_-](?P<version>\d[\d.]*)(?P<update>p\d+)?`, Name: "OpenSSH", Vendor: "openbsd", Product: "openssh", Service: "ssh"},
+	{Source: SourceSSH, Pattern: `dropbear[_-](?P<version>\d[\d.]*)`, Name: "Dropbear SSH", Vendor: "dropbear_ssh_project", Product: "dropbear_ssh", Service: "ssh"},
+	{Source: SourceSSH, Pattern: `libssh[_-](?P<version>\d[\d.]*)`, Name: "libssh", Vendor: "libssh", Product: "libssh", Service: "ssh"},
+	{Source: SourceSSH, Pattern: `Cisco-(?P<version>\d[\d.]*)`, Name: "Cisco SSH", Vendor: "cisco", Product: "ios", Service: "ssh", Part: "o"},
+
+	// Web servers and frameworks
+	{Source: SourceHTTPServer, Pattern: `^nginx(?:/(?P<version>\d[\d.]*))?`, Name: "nginx", Vendor: "nginx", Product: "nginx", Service: "http"},
+	{Source: SourceHTTPServer, Pattern: `^openresty(?:/(?P<version>\d[\d.]*))?`, Name: "OpenResty", Vendor: "openresty", Product: "openresty", Service: "http"},
+	{Source: SourceHTTPServer, Pattern: `^Apache(?:/(?P<version>\d[\d.]*))?(?:\s|$)`, Name: "Apache HTTP Server", Vendor: "apache", Product: "http_server", Service: "http"},
+	{Source: SourceHTTPServer, Pattern: `^Apache-Coyote`, Name: "Apache Tomcat", Vendor: "apache", Product: "tomcat", Service: "http"},
+	{Source: SourceHTTPServer, Pattern: `^Microsoft-IIS/(?P<version>\d[\d.]*)`, Name: "Microsoft IIS", Vendor: "microsoft", Product: "internet_information_services", Service: "http"},
+	{Source: SourceHTTPServer, Pattern: `^lighttpd(?:/(?P<version>\d[\d.]*))?`, Name: "lighttpd", Vendor: "lighttpd", Product: "lighttpd", Service: "http"},
+	{Source: SourceHTTPServer, Pattern: `^Caddy`, Name: "Caddy", Vendor: "caddyserver", Product: "caddy", Service: "http"},
+	{Source: SourceHTTPServer, Pattern: `Jetty\((?P<version>\d[\d.]*)`, Name: "Eclipse Jetty", Vendor: "eclipse", Product: "jetty", Service: "http"},
+	{Source: SourceHTTPServer, Pattern: `^gunicorn(?:/(?P<version>\d[\d.]*))?`, Name: "Gunicorn", Vendor: "gunicorn", Product: "gunicorn", Service: "http"},
+	{Source: SourceHTTPServer, Pattern: `^Werkzeug/(?P<version>\d[\d.]*)`, Name: "Werkzeug", Vendor: "palletsprojects", Product: "werkzeug", Service: "http"},
+	{Source: SourceHTTPServer, Pattern: `OpenSSL/(?P<version>\d[\d.]*[a-z]?)`, Name: "OpenSSL", Vendor: "openssl", Product: "openssl"},
+	{Source: SourceHTTPServer, Pattern: `PHP/(?P<version>\d[\d.]*)`, Name: "PHP", Vendor: "php", Product: "php"},
+	{Source: SourceHTTPPoweredBy, Pattern: `PHP/(?P<version>\d[\d.]*)`, Name: "PHP", Vendor: "php", Product: "php"},
+	{Source: SourceHTTPPoweredBy, Pattern: `^ASP\.NET`, Name: "ASP.NET", Vendor: "microsoft", Product: "asp.net"},
+	{Source: SourceHTTPPoweredBy, Pattern: `^Express$`, Name: "Express", Vendor: "expressjs", Product: "express"},
+	{Source: SourceHTTPPoweredBy, Pattern: `^Next\.js(?: (?P<version>\d[\d.]*))?`, Name: "Next.js", Vendor: "vercel", Product: "next.js"},
+
+	// FTP
+	{Source: SourceBanner, Pattern: `vsFTPd (?P<version>\d[\d.]*)`, Name: "vsftpd", Vendor: "beasts", Product: "vsftpd", Service: "ftp"},
+	{Source: SourceBanner, Pattern: `ProFTPD (?P<version>\d[\d.]*[a-z]?)`, Name: "ProFTPD", Vendor: "proftpd", Product: "proftpd", Service: "ftp"},
+	{Source: SourceBanner, Pattern: `Pure-FTPd`, Name: "Pure-FTPd", Vendor: "pureftpd", Product: "pure-ftpd", Service: "ftp"},
+	{Source: SourceBanner, Pattern: `FileZilla Server(?: version)? (?P<version>\d[\d.]*)`, Name: "FileZilla Server", Vendor: "filezilla-project", Product: "filezilla_server", Service: "ftp"},
+
+	// Mail
+	{Source: SourceBanner, Pattern: `^220.*\bExim (?P<version>\d[\d.]*)`, Name: "Exim", Vendor: "exim", Product: "exim", Service: "smtp"},
+	{Source: SourceBanner, Pattern: `^220.*\bESMTP Postfix`, Name: "Postfix", Vendor: "postfix", Product: "postfix", Service: "smtp"},
+	{Source: SourceBanner, Pattern: `^220.*\bSendmail (?P<version>\d[\d.]*)`, Name: "Sendmail", Vendor: "sendmail", Product: "sendmail", Service: "smtp"},
+	{Source: SourceBanner, Pattern: `^220.*Microsoft ESMTP MAIL Service`, Name: "Microsoft Exchange", Vendor: "microsoft", Product: "exchange_server", Service: "smtp"},
+	{Source: SourceBanner, Pattern: `^\* OK.*\bDovecot`, Name: "Dovecot", Vendor: "dovecot", Product: "dovecot", Service: "imap"},
+	{Source: SourceBanner, Pattern: `^\+OK.*\bDovecot`, Name: "Dovecot", Vendor: "dovecot", Product: "dovecot", Service: "pop3"},
+
+	// Databases and caches. MySQL and MariaDB greet with a handshake
+	// packet: protocol 10, then the NUL-terminated server version. The
+	// banner text escapes the protocol byte as \n and NUL as \x00.
+	{Source: SourceBanner, Pattern: `\\n5\.5\.5-(?P<version>\d+\.\d+\.\d+)-MariaDB`, Name: "MariaDB", Vendor: "mariadb", Product: "mariadb", Service: "mysql"},
+	{Source: SourceBanner, Pattern: `\\x00\\n(?P<version>\d+\.\d+\.\d+)(?:-[a-z0-9.]+)?\\x00`, Name: "MySQL", Vendor: "oracle", Product: "mysql", Service: "mysql"},
+	{Source: SourceBanner, Pattern: `^Redis (?P<version>\d[\d.]*)`, Name: "Redis", Vendor: "redis", Product: "redis", Service: "redis"},
+	{Source: SourceBanner, Pattern: `^VERSION (?P<version>\d[\d.]*)`, Name: "Memcached", Vendor: "memcached", Product: "memcached", Service: "memcached"},
+}
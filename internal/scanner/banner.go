@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// Banner timing: how long a server may take to greet, and how long a pause
+// ends a multi-packet greeting
+const (
+	bannerWait = time.Second
+	bannerIdle = 200 * time.Millisecond
+)
+
+// bannerCapture is a banner as received, with its display form
+type bannerCapture struct {
+	text      string // printable form, see escapeBanner
+	probe     string // active probe that drew it, "" when passive
+	raw       []byte
+	firstByte time.Duration
+}
+
+// set records the capture on a result, keeping at most limit raw bytes.
+// Truncating the raw bytes rebuilds the text from what is kept, so the two
+// never disagree.
+func (c bannerCapture) set(result *models.ScanResult, limit int) {
+	if c.text == "" && len(c.raw) == 0 {
+		return
+	}
+	if len(c.raw) > limit {
+		c.raw = c.raw[:limit]
+		c.text = escapeBanner(c.raw)
+	}
+	result.Banner = c.text
+	result.BannerProbe = c.probe
+	result.BannerRaw = c.raw
+	result.BannerFirstByteMs = float64(c.firstByte.Microseconds()) / 1000
+}
+
+// grabBanner waits for the server to speak first and captures up to limit
+// bytes of its greeting. The bytes are only peeked, so they stay buffered
+// in reader for a later STARTTLS exchange.
+func grabBanner(conn net.Conn, reader *bufio.Reader, limit int) bannerCapture {
+	defer conn.SetReadDeadline(time.Time{})
+
+	start := time.Now()
+	conn.SetReadDeadline(start.Add(bannerWait))
+	if _, err := reader.Peek(1); err != nil {
+		return bannerCapture{}
+	}
+	firstByte := time.Since(start)
+
+	// Greetings may span several packets; read until the server pauses
+	for reader.Buffered() < limit {
+		conn.SetReadDeadline(time.Now().Add(bannerIdle))
+		if _, err := reader.Peek(reader.Buffered() + 1); err != nil {
+			break
+		}
+	}
+
+	raw, _ := reader.Peek(min(reader.Buffered(), limit))
+	raw = bytes.Clone(raw)
+	return bannerCapture{text: escapeBanner(raw), raw: raw, firstByte: firstByte}
+}
+
+// escapeBanner renders raw banner bytes on one line: printable ASCII as is,
+// CR, LF and tab as \r, \n and \t, and any other byte as \xNN. Trailing
+// line breaks and spaces are dropped.
+func escapeBanner(raw []byte) string {
+	raw = bytes.TrimRight(raw, "\r\n\t ")
+	var b strings.Builder
+	for _, c := range raw {
+		switch {
+		case c == '\\':
+			b.WriteString(`\\`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c < 0x20 || c > 0x7e:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,87 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/Sh4Ryuu/go-scan/internal/product"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+func TestEscapeBanner(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"SSH-2.0-OpenSSH_9.6\r\n", "SSH-2.0-OpenSSH_9.6"},
+		{"220-first\r\n220 second\r\n", `220-first\r\n220 second`},
+		{"a\tb\\c", `a\tb\\c`},
+		{"\x00\x7f\xff", `\x00\x7f\xff`},
+		{"  \r\n", ""},
+	}
+	for _, tt := range tests {
+		if got := escapeBanner([]byte(tt.raw)); got != tt.want {
+			t.Errorf("escapeBanner(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+// Real greetings, escaped the way the scanner stores them, must still be
+// recognized by the built-in product rules
+func TestBannerProducts(t *testing.T) {
+	tests := []struct {
+		name     string
+		greeting []byte
+		want     string
+	}{
+		{"MySQL 8", mysqlGreeting("8.0.36"), "cpe:2.3:a:oracle:mysql:8.0.36:*:*:*:*:*:*:*"},
+		{"MySQL on Ubuntu", mysqlGreeting("8.0.36-0ubuntu0.22.04.1"), "cpe:2.3:a:oracle:mysql:8.0.36:*:*:*:*:*:*:*"},
+		{"MySQL 5.7", mysqlGreeting("5.7.44-log"), "cpe:2.3:a:oracle:mysql:5.7.44:*:*:*:*:*:*:*"},
+		{"MariaDB", mysqlGreeting("5.5.5-10.11.6-MariaDB-0+deb12u1"), "cpe:2.3:a:mariadb:mariadb:10.11.6:*:*:*:*:*:*:*"},
+		{"vsftpd", []byte("220 (vsFTPd 3.0.5)\r\n"), "cpe:2.3:a:beasts:vsftpd:3.0.5:*:*:*:*:*:*:*"},
+		{"Exim", []byte("220 mail.example.com ESMTP Exim 4.97 Mon, 01 Jan 2024 00:00:00 +0000\r\n"), "cpe:2.3:a:exim:exim:4.97:*:*:*:*:*:*:*"},
+		{"OpenSSH", []byte("SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13.5\r\n"), "cpe:2.3:a:openbsd:openssh:9.6:p1:*:*:*:*:*:*"},
+		{"memcached", []byte("VERSION 1.6.21\r\n"), "cpe:2.3:a:memcached:memcached:1.6.21:*:*:*:*:*:*:*"},
+	}
+	rules := product.DefaultRules()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &models.ScanResult{}
+			bannerCapture{text: escapeBanner(tt.greeting), raw: tt.greeting}.set(result, 1024)
+
+			var got []string
+			for _, p := range rules.Identify(result) {
+				got = append(got, p.CPE)
+			}
+			if !reflect.DeepEqual(got, []string{tt.want}) {
+				t.Errorf("banner %q: got %q, want %s", result.Banner, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBannerCaptureTruncation(t *testing.T) {
+	result := &models.ScanResult{}
+	raw := []byte("SSH-2.0-OpenSSH_9.6\r\n")
+	bannerCapture{text: escapeBanner(raw), raw: raw}.set(result, 7)
+	if result.Banner != "SSH-2.0" || string(result.BannerRaw) != "SSH-2.0" {
+		t.Errorf("banner %q, raw %q", result.Banner, result.BannerRaw)
+	}
+}
+
+// mysqlGreeting builds a protocol 10 handshake packet as MySQL and MariaDB
+// send it: a 3-byte length and sequence 0, then the protocol version, the
+// NUL-terminated server version, thread ID, salt and capabilities
+func mysqlGreeting(version string) []byte {
+	body := append([]byte{10}, version...)
+	body = append(body, 0)
+	body = binary.LittleEndian.AppendUint32(body, 12) // thread ID
+	body = append(body, "AbCdEfGh"...)                // salt, first part
+	body = append(body, 0, 0xff, 0xff, 0xff, 0x02, 0, 0xff, 0xdf, 21)
+	body = append(body, make([]byte, 10)...) // reserved
+	body = append(body, "IjKlMnOpQrSt\x00caching_sha2_password\x00"...)
+
+	packet := []byte{byte(len(body)), byte(len(body) >> 8), byte(len(body) >> 16), 0}
+	return append(packet, body...)
+}
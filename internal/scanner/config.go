@@ -1,39 +1,123 @@
 package scanner
 
 import (
+	"crypto/x509"
 	"fmt"
+	"os"
 	"strings"
 	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/plugin"
+	"github.com/Sh4Ryuu/go-scan/internal/product"
+	"github.com/Sh4Ryuu/go-scan/internal/ssl"
+	"github.com/Sh4Ryuu/go-scan/internal/vuln"
+	"github.com/Sh4Ryuu/go-scan/pkg/check"
 )
 
 // Config holds all scanner configuration
 type Config struct {
 	// Basic settings
-	Host           string
-	StartPort      int
-	EndPort        int
-	MaxWorkers     int
-	TimeoutSeconds int
-	RateLimitMs    int
+	Host           string `json:"host"`
+	StartPort      int    `json:"start_port"`
+	EndPort        int    `json:"end_port"`
+	MaxWorkers     int    `json:"workers"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+	RateLimitMs    int    `json:"rate_limit_ms"`
 
 	// Feature flags
-	BannerGrabbing    bool
-	EnableSSL         bool
-	EnableUDP         bool
-	EnableGeolocation bool
+	BannerGrabbing    bool `json:"banners"`
+	EnableSSL         bool `json:"ssl"`
+	EnableUDP         bool `json:"udp"`
+	EnableGeolocation bool `json:"geo"`
+	TLSEnum           bool `json:"tls_enum"` // enumerate TLS versions, ciphers and groups
+	JARM              bool `json:"jarm"`     // fingerprint TLS servers with JARM
+	HTTPEnum          bool `json:"http"`     // enumerate web services
+	ExposureChecks    bool `json:"exposure"` // confirm unauthenticated access to data services
+	SSHScan           bool `json:"ssh"`      // analyze SSH servers natively
+	SMBScan           bool `json:"smb"`      // analyze SMB servers natively
 
 	// Output settings
-	Verbose    bool
-	Quiet      bool
-	JSONOutput bool
+	Verbose    bool `json:"verbose"`
+	Quiet      bool `json:"quiet"`
+	JSONOutput bool `json:"json"`
+
+	// CABundle is a PEM file of roots used instead of the system roots
+	// when validating certificate chains
+	CABundle string `json:"ca_bundle,omitempty"`
+
+	// SNI overrides the server name sent in TLS handshakes and checked
+	// against certificates; it defaults to Host
+	SNI string `json:"sni,omitempty"`
+
+	// VHosts are extra names to send as SNI, recording the certificate
+	// each returns; VHostsFromCert adds the DNS names of the default
+	// certificate
+	VHosts         []string `json:"vhosts,omitempty"`
+	VHostsFromCert bool     `json:"vhosts_from_cert,omitempty"`
+
+	// ProductRules is a JSON file of extra product identification rules
+	ProductRules string `json:"product_rules,omitempty"`
+
+	// VulnDatabase is a local vulnerability feed, or a directory of them,
+	// matched against identified products
+	VulnDatabase string `json:"vuln_db,omitempty"`
+
+	// Plugins are external plugin manifests, or directories of them;
+	// PluginConcurrency caps how many plugin processes run at once
+	Plugins           []string `json:"plugins,omitempty"`
+	PluginConcurrency int      `json:"plugin_concurrency,omitempty"`
+
+	// BannerMaxBytes caps how much of each banner is kept; 0 means
+	// DefaultBannerMaxBytes
+	BannerMaxBytes int `json:"banner_max_bytes,omitempty"`
+
+	// JARMDatabase is a JSON file of extra JARM fingerprint labels
+	JARMDatabase string `json:"jarm_db,omitempty"`
 
 	// Profile and nmap
-	Profile     string
-	NmapScripts string
+	Profile     string `json:"profile"`
+	NmapScripts string `json:"nmap_scripts"`
 
 	// Internal - computed values
-	Timeout       time.Duration
-	WorkerTimeout time.Duration
+	Timeout       time.Duration    `json:"-"`
+	WorkerTimeout time.Duration    `json:"-"`
+	RootCAs       *x509.CertPool   `json:"-"` // loaded from CABundle
+	JARMLabels    ssl.JARMDatabase `json:"-"` // loaded from JARMDatabase
+	Products      product.Rules    `json:"-"` // loaded from ProductRules
+	Vulns         *vuln.Database   `json:"-"` // loaded from VulnDatabase
+	PluginChecks  []check.Check    `json:"-"` // loaded from Plugins
+}
+
+// DefaultBannerMaxBytes is the default banner size limit
+const DefaultBannerMaxBytes = 1024
+
+// DefaultConfig returns a configuration holding the default settings
+func DefaultConfig() *Config {
+	return &Config{
+		Host:              "scanme.nmap.org",
+		StartPort:         1,
+		EndPort:           1024,
+		MaxWorkers:        100,
+		TimeoutSeconds:    1,
+		RateLimitMs:       10,
+		BannerGrabbing:    true,
+		BannerMaxBytes:    DefaultBannerMaxBytes,
+		EnableSSL:         true,
+		EnableGeolocation: true,
+		SSHScan:           true,
+		SMBScan:           true,
+		Profile:           "default",
+	}
+}
+
+// ProfileNames lists the profiles in display order
+var ProfileNames = []string{"aggressive", "default", "conservative"}
+
+// ProfileDescriptions describe the intended use of each profile
+var ProfileDescriptions = map[string]string{
+	"aggressive":   "Fast scanning of trusted networks",
+	"default":      "Balanced speed and reliability",
+	"conservative": "Slower but safer scanning",
 }
 
 // ProfileSettings define preset configurations
@@ -81,6 +165,10 @@ func (c *Config) Validate() error {
 		c.TimeoutSeconds = 1
 	}
 
+	if c.BannerMaxBytes <= 0 {
+		c.BannerMaxBytes = DefaultBannerMaxBytes
+	}
+
 	// Apply profile settings if valid
 	if profile, exists := ProfileSettings[c.Profile]; exists {
 		if workers, ok := profile["workers"].(int); ok {
@@ -94,6 +182,60 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.CABundle != "" && c.RootCAs == nil {
+		pem, err := os.ReadFile(c.CABundle)
+		if err != nil {
+			return fmt.Errorf("ca bundle: %v", err)
+		}
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("ca bundle %s: no PEM certificates found", c.CABundle)
+		}
+	}
+
+	if c.Products == nil {
+		if c.ProductRules == "" {
+			c.Products = product.DefaultRules()
+		} else {
+			rules, err := product.LoadRules(c.ProductRules)
+			if err != nil {
+				return fmt.Errorf("product rules: %v", err)
+			}
+			c.Products = rules
+		}
+	}
+
+	if c.VulnDatabase != "" && c.Vulns == nil {
+		db, err := vuln.Load(c.VulnDatabase)
+		if err != nil {
+			return fmt.Errorf("vulnerability database: %v", err)
+		}
+		c.Vulns = db
+	}
+
+	if len(c.Plugins) > 0 && c.PluginChecks == nil {
+		manifests, err := plugin.LoadManifests(c.Plugins)
+		if err != nil {
+			return fmt.Errorf("plugins: %v", err)
+		}
+		c.PluginChecks = plugin.Checks(manifests, c.PluginConcurrency)
+	}
+	if _, err := Checks(c); err != nil {
+		return err
+	}
+
+	if c.JARMLabels == nil {
+		if c.JARMDatabase == "" {
+			c.JARMLabels = ssl.DefaultJARMDatabase()
+		} else {
+			labels, err := ssl.LoadJARMDatabase(c.JARMDatabase)
+			if err != nil {
+				return fmt.Errorf("jarm database: %v", err)
+			}
+			c.JARMLabels = labels
+		}
+	}
+
 	// Set computed values
 	c.Timeout = time.Duration(c.TimeoutSeconds) * time.Second
 	c.WorkerTimeout = c.Timeout
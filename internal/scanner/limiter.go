@@ -0,0 +1,38 @@
+package scanner
+
+import "context"
+
+// Limiter caps the number of probes in flight, optionally across several
+// scanners running at once. A nil Limiter imposes no limit.
+type Limiter struct {
+	slots chan struct{}
+}
+
+// NewLimiter creates a limiter allowing n concurrent probes
+func NewLimiter(n int) *Limiter {
+	if n < 1 {
+		n = 1
+	}
+	return &Limiter{slots: make(chan struct{}, n)}
+}
+
+// Acquire blocks until a probe slot is free or ctx is done
+func (l *Limiter) Acquire(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire
+func (l *Limiter) Release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+}
@@ -0,0 +1,313 @@
+package scanner
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/ssl"
+)
+
+// bannerProbe is a request sent to a port that stays silent after connect,
+// so that services which wait for the client still yield a banner
+type bannerProbe struct {
+	name      string
+	ports     []int // tried first on these ports
+	onlyPorts bool  // never tried on other ports
+	skipPorts []int // never tried on these ports
+	tls       bool  // send the payload inside a TLS session
+	alpn      []string
+	timeout   time.Duration
+	payload   func(host string) []byte // nil waits for the server to speak
+	// match reports whether the response is from the probed protocol;
+	// the first matching response wins and stops further probes
+	match func(resp []byte) bool
+	// banner summarises a response; nil takes its first line
+	banner func(resp []byte) string
+}
+
+// TLS ports whose service greets the client once the session is up
+var tlsGreetingPorts = []int{465, 563, 990, 993, 995}
+
+// TLS ports whose service is known not to be HTTP, so a GET is wasted on them
+var nonHTTPTLSPorts = append([]int{636, 853, 5061, 6697}, tlsGreetingPorts...)
+
+// bannerProbes are tried in order, after any probe listing the port
+var bannerProbes = []*bannerProbe{
+	{
+		name:    "http",
+		ports:   []int{80, 591, 3000, 5000, 8000, 8008, 8080, 8081, 8888, 9000, 9090},
+		timeout: 2 * time.Second,
+		payload: httpGet,
+		match:   httpResponse,
+		banner:  httpBanner,
+	},
+	{
+		name:    "redis",
+		ports:   []int{6379, 6380},
+		timeout: time.Second,
+		payload: func(string) []byte { return []byte("*1\r\n$4\r\nPING\r\n*1\r\n$4\r\nINFO\r\n") },
+		match:   redisResponse,
+		banner:  redisBanner,
+	},
+	{
+		name:    "generic",
+		timeout: time.Second,
+		payload: func(string) []byte { return []byte("\r\n\r\n") },
+	},
+	{
+		name:      "tls-greeting",
+		ports:     tlsGreetingPorts,
+		onlyPorts: true,
+		tls:       true,
+		timeout:   3 * time.Second,
+		match:     lineResponse,
+	},
+	{
+		name:      "tls",
+		ports:     []int{443, 4443, 8443, 9443},
+		skipPorts: nonHTTPTLSPorts,
+		tls:       true,
+		alpn:      []string{"http/1.1"},
+		timeout:   3 * time.Second,
+		payload:   httpGet,
+		match:     httpResponse,
+		banner:    httpBanner,
+	},
+}
+
+// activeBanner runs the banner probes against a silent port, each on its
+// own connection, and returns the best banner, read up to the configured
+// banner size. A port known to speak TLS only gets the TLS probes. The
+// probes stop once one cannot connect or is met with silence until its
+// timeout, as the port will not answer the others either.
+func (ps *PortScanner) activeBanner(address string, port int, isTLS bool) bannerCapture {
+	var best bannerCapture
+	for _, probe := range orderProbes(port) {
+		// Plaintext is pointless on TLS ports, and TLS was already tried
+		// on the rest when TLS detection is on
+		if probe.tls != isTLS && (isTLS || ps.config.EnableSSL) {
+			continue
+		}
+
+		resp, firstByte, err := ps.runProbe(address, probe, ps.config.BannerMaxBytes)
+		if err != nil {
+			break
+		}
+		if len(resp) == 0 {
+			continue
+		}
+		if !probe.tls && looksLikeTLS(resp) {
+			// A TLS server rejecting plaintext; only the TLS probe can help
+			isTLS = true
+			continue
+		}
+
+		capture := bannerCapture{text: probeBanner(probe, resp), probe: probe.name, raw: resp, firstByte: firstByte}
+		if matches(probe, resp, ps.config.BannerMaxBytes) && capture.text != "" {
+			return capture
+		}
+		if len(capture.text) > len(best.text) {
+			best = capture
+		}
+	}
+	return best
+}
+
+// matches reports whether resp is from the probed protocol. A response cut
+// off at limit may end before what match looks for, so one the probe can
+// still summarise counts too.
+func matches(probe *bannerProbe, resp []byte, limit int) bool {
+	if probe.match == nil {
+		return false
+	}
+	if probe.match(resp) {
+		return true
+	}
+	return len(resp) >= limit && probe.banner != nil && probe.banner(resp) != ""
+}
+
+// orderProbes returns the probes that apply to port, those listing it
+// first, keeping their order
+func orderProbes(port int) []*bannerProbe {
+	var first, rest []*bannerProbe
+	for _, probe := range bannerProbes {
+		switch {
+		case containsPort(probe.skipPorts, port):
+		case containsPort(probe.ports, port):
+			first = append(first, probe)
+		case !probe.onlyPorts:
+			rest = append(rest, probe)
+		}
+	}
+	return append(first, rest...)
+}
+
+// runProbe sends a probe on a new connection and reads up to limit bytes
+// of the response, until the probe matches, the server closes or the probe
+// times out. It also returns how long the first response byte took, and
+// an error when the port refused the connection or stayed silent until
+// the probe timed out.
+func (ps *PortScanner) runProbe(address string, probe *bannerProbe, limit int) ([]byte, time.Duration, error) {
+	conn, err := net.DialTimeout("tcp", address, ps.config.Timeout)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(probe.timeout))
+	if probe.tls {
+		tlsConn := ssl.Client(conn, ps.serverName(), probe.alpn)
+		if err := tlsConn.Handshake(); err != nil {
+			if isTimeout(err) {
+				return nil, 0, err
+			}
+			return nil, 0, nil
+		}
+		conn = tlsConn
+	}
+
+	start := time.Now()
+	if probe.payload != nil {
+		if _, err := conn.Write(probe.payload(ps.serverName())); err != nil {
+			return nil, 0, nil
+		}
+	}
+
+	var resp []byte
+	var firstByte time.Duration
+	buf := make([]byte, 1024)
+	for len(resp) < limit {
+		n, err := conn.Read(buf)
+		if n > 0 && len(resp) == 0 {
+			firstByte = time.Since(start)
+		}
+		resp = append(resp, buf[:n]...)
+		if err != nil {
+			if len(resp) == 0 && isTimeout(err) {
+				return nil, 0, err
+			}
+			break
+		}
+		if probe.match != nil && probe.match(resp) {
+			break
+		}
+	}
+	if len(resp) > limit {
+		resp = resp[:limit]
+	}
+	return resp, firstByte, nil
+}
+
+// isTimeout reports whether err is a network timeout
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// probeBanner summarises a probe response as a banner
+func probeBanner(probe *bannerProbe, resp []byte) string {
+	if probe.banner != nil {
+		if banner := probe.banner(resp); banner != "" {
+			return banner
+		}
+	}
+	return escapeBanner(resp)
+}
+
+// httpGet is a minimal HTTP/1.0 request for the root page
+func httpGet(host string) []byte {
+	return []byte("GET / HTTP/1.0\r\nHost: " + host + "\r\nUser-Agent: go-scan\r\nAccept: */*\r\n\r\n")
+}
+
+// httpResponse reports whether resp holds complete HTTP response headers
+func httpResponse(resp []byte) bool {
+	return bytes.HasPrefix(resp, []byte("HTTP/")) && bytes.Contains(resp, []byte("\r\n\r\n"))
+}
+
+// httpBanner is the status line and Server header of an HTTP response
+func httpBanner(resp []byte) string {
+	if !bytes.HasPrefix(resp, []byte("HTTP/")) {
+		return ""
+	}
+	headers, _, _ := strings.Cut(string(resp), "\r\n\r\n")
+	lines := strings.Split(headers, "\r\n")
+	banner := printable(lines[0])
+	for _, line := range lines[1:] {
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Server") {
+			banner += " (Server: " + printable(strings.TrimSpace(value)) + ")"
+			break
+		}
+	}
+	return banner
+}
+
+// lineResponse reports whether resp holds a complete line
+func lineResponse(resp []byte) bool {
+	return bytes.Contains(resp, []byte("\n"))
+}
+
+// redisResponse reports whether resp answers PING and INFO
+func redisResponse(resp []byte) bool {
+	switch {
+	case bytes.Contains(resp, []byte("redis_version:")):
+		return true
+	case bytes.HasPrefix(resp, []byte("-NOAUTH")), bytes.HasPrefix(resp, []byte("-DENIED")):
+		return true
+	case bytes.HasPrefix(resp, []byte("+PONG")):
+		return bytes.Count(resp, []byte("\r\n")) > 1 && !bytes.Contains(resp, []byte("\r\n$"))
+	}
+	return false
+}
+
+// redisBanner names the Redis version, or the authentication error
+func redisBanner(resp []byte) string {
+	text := string(resp)
+	if i := strings.Index(text, "redis_version:"); i >= 0 {
+		version, _, _ := strings.Cut(text[i+len("redis_version:"):], "\r\n")
+		return "Redis " + printable(version)
+	}
+	if strings.HasPrefix(text, "-") || strings.HasPrefix(text, "+PONG") {
+		return "Redis " + firstLine(resp)
+	}
+	return ""
+}
+
+// looksLikeTLS reports whether resp starts with a TLS alert or handshake
+// record, the usual answer of a TLS server to plaintext
+func looksLikeTLS(resp []byte) bool {
+	return len(resp) >= 3 && (resp[0] == 0x15 || resp[0] == 0x16) && resp[1] == 0x03
+}
+
+// firstLine returns the first non-empty line of resp, printable only
+func firstLine(resp []byte) string {
+	for _, line := range strings.Split(string(resp), "\n") {
+		if line = strings.TrimSpace(printable(line)); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// printable drops control and non-ASCII bytes
+func printable(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// containsPort reports whether ports holds port
+func containsPort(ports []int, port int) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package scanner
+
+import (
+	"bufio"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/testutil"
+)
+
+func TestOrderProbes(t *testing.T) {
+	tests := []struct {
+		port int
+		want string
+	}{
+		{80, "http redis generic tls"},
+		{6379, "redis http generic tls"},
+		{12345, "http redis generic tls"},
+		{443, "tls http redis generic"},
+		{8443, "tls http redis generic"},
+		// Implicit TLS mail ports greet inside TLS and never get a GET
+		{993, "tls-greeting http redis generic"},
+		{995, "tls-greeting http redis generic"},
+		{465, "tls-greeting http redis generic"},
+		{636, "http redis generic"},
+		{853, "http redis generic"},
+	}
+	for _, tt := range tests {
+		var names []string
+		for _, probe := range orderProbes(tt.port) {
+			names = append(names, probe.name)
+		}
+		if got := strings.Join(names, " "); got != tt.want {
+			t.Errorf("port %d: got %s, want %s", tt.port, got, tt.want)
+		}
+	}
+}
+
+func TestRedisResponse(t *testing.T) {
+	tests := []struct {
+		resp   string
+		match  bool
+		banner string
+	}{
+		{"+PONG\r\n$3\r\n# Server\r\nredis_version:7.2.4\r\nredis_mode:standalone\r\n", true, "Redis 7.2.4"},
+		{"-NOAUTH Authentication required.\r\n-NOAUTH Authentication required.\r\n", true, "Redis -NOAUTH Authentication required."},
+		{"-DENIED Redis is running in protected mode\r\n", true, "Redis -DENIED Redis is running in protected mode"},
+		{"+PONG\r\n-ERR unknown command 'INFO'\r\n", true, "Redis +PONG"},
+		// INFO's bulk reply is still on its way
+		{"+PONG\r\n", false, "Redis +PONG"},
+		{"+PONG\r\n$3012\r\n# Server\r\n", false, "Redis +PONG"},
+		{"-ERR unknown command 'GET'\r\n", false, "Redis -ERR unknown command 'GET'"},
+		{"HTTP/1.1 400 Bad Request\r\n\r\n", false, ""},
+		{"", false, ""},
+	}
+	for _, tt := range tests {
+		if got := redisResponse([]byte(tt.resp)); got != tt.match {
+			t.Errorf("redisResponse(%q) = %v, want %v", tt.resp, got, tt.match)
+		}
+		if got := redisBanner([]byte(tt.resp)); got != tt.banner {
+			t.Errorf("redisBanner(%q) = %q, want %q", tt.resp, got, tt.banner)
+		}
+	}
+}
+
+// probeScanner is a scanner set up only for the banner probes
+func probeScanner(enableSSL bool) *PortScanner {
+	return &PortScanner{config: &Config{
+		Host:           "127.0.0.1",
+		EnableSSL:      enableSSL,
+		BannerMaxBytes: DefaultBannerMaxBytes,
+		Timeout:        time.Second,
+	}}
+}
+
+// portOf returns the port of a loopback address
+func portOf(t *testing.T, address string) int {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestActiveBannerRedis(t *testing.T) {
+	// A Redis requiring a password, which answers anything else with an error
+	address := testutil.Serve(t, func(conn net.Conn) {
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		if strings.HasPrefix(line, "*") {
+			conn.Write([]byte("-NOAUTH Authentication required.\r\n-NOAUTH Authentication required.\r\n"))
+		} else {
+			conn.Write([]byte("-ERR unknown command\r\n"))
+		}
+	})
+	capture := probeScanner(false).activeBanner(address, portOf(t, address), false)
+	if capture.probe != "redis" || capture.text != "Redis -NOAUTH Authentication required." {
+		t.Errorf("got %+v", capture)
+	}
+}
+
+func TestActiveBannerStopsOnSilence(t *testing.T) {
+	var conns atomic.Int32
+	stop := make(chan struct{})
+	address := testutil.Serve(t, func(conn net.Conn) {
+		conns.Add(1)
+		<-stop
+	})
+	t.Cleanup(func() { close(stop) })
+
+	// Without TLS detection every probe would apply, TLS included
+	capture := probeScanner(false).activeBanner(address, portOf(t, address), false)
+	if capture.text != "" {
+		t.Errorf("got %+v from a silent port", capture)
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("%d connections, want 1", n)
+	}
+}
+
+func TestActiveBannerTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "stand-in/1.0")
+	}))
+	defer server.Close()
+
+	address := server.Listener.Addr().String()
+	capture := probeScanner(true).activeBanner(address, portOf(t, address), true)
+	if capture.probe != "tls" || capture.text != "HTTP/1.0 200 OK (Server: stand-in/1.0)" {
+		t.Errorf("got %+v", capture)
+	}
+
+	// Implicit TLS mail services speak first and are only listened to
+	config := &tls.Config{Certificates: server.TLS.Certificates}
+	var requests atomic.Int32
+	greeter := testutil.Serve(t, func(conn net.Conn) {
+		tlsConn := tls.Server(conn, config)
+		tlsConn.Write([]byte("* OK [CAPABILITY IMAP4rev1] ready\r\n"))
+		if n, _ := tlsConn.Read(make([]byte, 64)); n > 0 {
+			requests.Add(1)
+		}
+	})
+	probe := orderProbes(993)[0]
+	resp, _, err := probeScanner(true).runProbe(greeter, probe, DefaultBannerMaxBytes)
+	if err != nil || string(resp) != "* OK [CAPABILITY IMAP4rev1] ready\r\n" {
+		t.Errorf("got %q, %v", resp, err)
+	}
+	if probeBanner(probe, resp) != "* OK [CAPABILITY IMAP4rev1] ready" {
+		t.Errorf("banner %q", probeBanner(probe, resp))
+	}
+	if requests.Load() != 0 {
+		t.Error("the greeting probe sent a request")
+	}
+}
@@ -2,16 +2,28 @@ package scanner
 
 import (
 	"bufio"
-	"fmt"
+	"context"
+	"errors"
 	"net"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/Sh4Ryuu/go-scan/internal/exposure"
 	"github.com/Sh4Ryuu/go-scan/internal/geolocation"
+	"github.com/Sh4Ryuu/go-scan/internal/metrics"
+	"github.com/Sh4Ryuu/go-scan/internal/nmap"
 	"github.com/Sh4Ryuu/go-scan/internal/output"
+	"github.com/Sh4Ryuu/go-scan/internal/smb"
+	"github.com/Sh4Ryuu/go-scan/internal/ssh"
 	"github.com/Sh4Ryuu/go-scan/internal/ssl"
+	"github.com/Sh4Ryuu/go-scan/internal/vuln"
+	"github.com/Sh4Ryuu/go-scan/internal/web"
+	"github.com/Sh4Ryuu/go-scan/pkg/check"
 	"github.com/Sh4Ryuu/go-scan/pkg/models"
 )
 
@@ -20,26 +32,89 @@ type PortScanner struct {
 	config    *Config
 	formatter *output.Formatter
 	stats     *models.ScanStats
+	limiter   *Limiter
+	checks    *check.Registry
+	nmap      *nmap.Runner
+
+	// counters, updated atomically while a scan runs
+	scanned int64
+	total   int64
+	errors  int64
 }
 
-// NewPortScanner creates a new port scanner
-func NewPortScanner(config *Config, formatter *output.Formatter) *PortScanner {
+// NewPortScanner creates a new port scanner. It fails when the checks of
+// config cannot be put together, such as two with the same name.
+func NewPortScanner(config *Config, formatter *output.Formatter) (*PortScanner, error) {
+	checks, err := Checks(config)
+	if err != nil {
+		return nil, err
+	}
 	return &PortScanner{
 		config:    config,
 		formatter: formatter,
+		checks:    checks,
+		nmap:      nmap.NewRunner(nmap.DefaultParallelHosts),
 		stats: &models.ScanStats{
 			TargetHost: config.Host,
 			StartTime:  time.Now(),
 		},
+	}, nil
+}
+
+// Checks returns the checks a scan with config runs: every check in the
+// default registry, the exposure checks when enabled, and the external
+// plugins. Two checks with the same name are an error.
+func Checks(config *Config) (*check.Registry, error) {
+	all := check.Default.Checks()
+	if config.ExposureChecks {
+		all = append(all, exposure.Checks()...)
+	}
+	all = append(all, config.PluginChecks...)
+
+	checks := check.NewRegistry()
+	for _, c := range all {
+		if err := checks.Register(c); err != nil {
+			return nil, err
+		}
 	}
+	return checks, nil
+}
+
+// SetLimiter shares a probe limiter with other scanners
+func (ps *PortScanner) SetLimiter(limiter *Limiter) {
+	ps.limiter = limiter
+}
+
+// SetNmapRunner shares an nmap runner, and its limit on hosts scanned at
+// once, with other scanners
+func (ps *PortScanner) SetNmapRunner(runner *nmap.Runner) {
+	ps.nmap = runner
+}
+
+// Progress returns the number of probes completed and the total planned
+func (ps *PortScanner) Progress() (int, int) {
+	return int(atomic.LoadInt64(&ps.scanned)), int(atomic.LoadInt64(&ps.total))
 }
 
 // Scan performs the port scan
 func (ps *PortScanner) Scan() ([]models.ScanResult, *models.ScanStats, error) {
+	return ps.ScanContext(context.Background())
+}
+
+// ScanContext performs the port scan, stopping early when ctx is cancelled.
+// On cancellation the results gathered so far are returned with ctx.Err().
+func (ps *PortScanner) ScanContext(ctx context.Context) ([]models.ScanResult, *models.ScanStats, error) {
 	startTime := time.Now()
 	ps.stats.StartTime = startTime
 
 	totalPorts := ps.config.GetPortCount()
+	planned := totalPorts
+	if ps.config.EnableUDP {
+		planned *= 2
+	}
+	atomic.StoreInt64(&ps.scanned, 0)
+	atomic.StoreInt64(&ps.total, int64(planned))
+	atomic.StoreInt64(&ps.errors, 0)
 
 	// Resolve target IP if needed for geolocation
 	var targetIP string
@@ -51,14 +126,19 @@ func (ps *PortScanner) Scan() ([]models.ScanResult, *models.ScanStats, error) {
 	}
 
 	// TCP Scanning
-	results := ps.scanTCP(totalPorts)
+	results := ps.scanTCP(ctx, totalPorts)
 
 	// UDP Scanning if enabled
-	if ps.config.EnableUDP {
-		udpResults := ps.scanUDP(totalPorts)
+	if ps.config.EnableUDP && ctx.Err() == nil {
+		udpResults := ps.scanUDP(ctx)
 		results = append(results, udpResults...)
 	}
 
+	// Nmap scripts on open TCP ports
+	if scripts := ps.config.GetNmapScriptsList(); len(scripts) > 0 && ctx.Err() == nil {
+		ps.runNmapScripts(ctx, results, scripts)
+	}
+
 	// Sort results
 	sort.Slice(results, func(i, j int) bool {
 		if results[i].Port == results[j].Port {
@@ -75,20 +155,25 @@ func (ps *PortScanner) Scan() ([]models.ScanResult, *models.ScanStats, error) {
 	// Update stats
 	ps.stats.EndTime = time.Now()
 	ps.stats.TotalPorts = totalPorts
-	ps.stats.OpenPorts = countOpen(results, "tcp")
-	ps.stats.ClosedPorts = totalPorts - ps.stats.OpenPorts
+	ps.stats.OpenPorts = countStatus(results, "tcp", "open")
+	ps.stats.FilteredPorts = countStatus(results, "tcp", "filtered")
+	ps.stats.ClosedPorts = totalPorts - ps.stats.OpenPorts - ps.stats.FilteredPorts
+	ps.stats.ErrorCount = int(atomic.LoadInt64(&ps.errors))
 	ps.stats.DurationSeconds = ps.stats.EndTime.Sub(startTime).Seconds()
 	ps.stats.PortsPerSec = float64(totalPorts) / ps.stats.DurationSeconds
 
-	return results, ps.stats, nil
+	if ctx.Err() == nil {
+		metrics.ObserveScan(ps.stats, results)
+	}
+
+	return results, ps.stats, ctx.Err()
 }
 
 // scanTCP performs TCP port scanning
-func (ps *PortScanner) scanTCP(totalPorts int) []models.ScanResult {
+func (ps *PortScanner) scanTCP(ctx context.Context, totalPorts int) []models.ScanResult {
 	ports := make(chan int, ps.config.MaxWorkers)
 	results := make(chan models.ScanResult, totalPorts)
 	var wg sync.WaitGroup
-	var scanned int64
 
 	// Start workers
 	for i := 0; i < ps.config.MaxWorkers; i++ {
@@ -96,14 +181,19 @@ func (ps *PortScanner) scanTCP(totalPorts int) []models.ScanResult {
 		go func() {
 			defer wg.Done()
 			for port := range ports {
-				result := ps.probeTCP(port)
+				if err := ps.limiter.Acquire(ctx); err != nil {
+					continue
+				}
+				result := ps.probeTCP(ctx, port)
+				ps.limiter.Release()
+				metrics.ObserveProbe(result.Protocol, result.Status)
+
 				if result.Status != "" {
 					results <- result
 				}
-				atomic.AddInt64(&scanned, 1)
 
 				// Show progress
-				current := int(atomic.LoadInt64(&scanned))
+				current := int(atomic.AddInt64(&ps.scanned, 1))
 				if current%10 == 0 {
 					ps.formatter.PrintProgress(current, totalPorts)
 				}
@@ -118,10 +208,14 @@ func (ps *PortScanner) scanTCP(totalPorts int) []models.ScanResult {
 
 	// Send ports to scan
 	go func() {
+		defer close(ports)
 		for port := ps.config.StartPort; port <= ps.config.EndPort; port++ {
-			ports <- port
+			select {
+			case ports <- port:
+			case <-ctx.Done():
+				return
+			}
 		}
-		close(ports)
 	}()
 
 	// Wait for workers to finish
@@ -138,14 +232,21 @@ func (ps *PortScanner) scanTCP(totalPorts int) []models.ScanResult {
 }
 
 // scanUDP performs UDP port scanning
-func (ps *PortScanner) scanUDP(totalPorts int) []models.ScanResult {
+func (ps *PortScanner) scanUDP(ctx context.Context) []models.ScanResult {
 	var results []models.ScanResult
 
 	for port := ps.config.StartPort; port <= ps.config.EndPort; port++ {
-		result := ps.probeUDP(port)
+		if err := ps.limiter.Acquire(ctx); err != nil {
+			break
+		}
+		result := ps.probeUDP(ctx, port)
+		ps.limiter.Release()
+		metrics.ObserveProbe(result.Protocol, result.Status)
+
 		if result.Status != "" {
 			results = append(results, result)
 		}
+		atomic.AddInt64(&ps.scanned, 1)
 
 		if ps.config.RateLimitMs > 0 {
 			time.Sleep(time.Duration(ps.config.RateLimitMs) * time.Millisecond)
@@ -155,8 +256,16 @@ func (ps *PortScanner) scanUDP(totalPorts int) []models.ScanResult {
 	return results
 }
 
+// Least time an HTTP request, an SSH key exchange or an SMB negotiation
+// gets; all are slower than connects
+const (
+	httpTimeout = 5 * time.Second
+	sshTimeout  = 5 * time.Second
+	smbTimeout  = 5 * time.Second
+)
+
 // probeTCP probes a single TCP port
-func (ps *PortScanner) probeTCP(port int) models.ScanResult {
+func (ps *PortScanner) probeTCP(ctx context.Context, port int) models.ScanResult {
 	result := models.ScanResult{
 		Host:     ps.config.Host,
 		Port:     port,
@@ -164,38 +273,192 @@ func (ps *PortScanner) probeTCP(port int) models.ScanResult {
 		Status:   "closed",
 	}
 
-	address := fmt.Sprintf("%s:%d", ps.config.Host, port)
+	address := net.JoinHostPort(ps.config.Host, strconv.Itoa(port))
 	conn, err := net.DialTimeout("tcp", address, ps.config.Timeout)
 	if err != nil {
-		result.Status = "closed"
+		result.Status = ps.classifyDialError(err)
 		return result
 	}
 	defer conn.Close()
 
 	result.Status = "open"
 
+	// The reader is shared so a STARTTLS upgrade sees the greeting that
+	// banner grabbing peeked at
+	reader := bufio.NewReaderSize(conn, max(4096, ps.config.BannerMaxBytes))
+
 	// Banner grabbing
 	if ps.config.BannerGrabbing {
-		banner := grabBanner(conn)
-		if banner != "" {
-			result.Banner = banner
-		}
+		grabBanner(conn, reader, ps.config.BannerMaxBytes).set(&result, ps.config.BannerMaxBytes)
 	}
 
-	// SSL/TLS certificate grabbing
-	if ps.config.EnableSSL && (port == 443 || port == 8443) {
-		certInfo := ssl.GrabCertificate(address, ps.config.Timeout)
-		if certInfo != nil {
+	// SSL/TLS detection: silent ports get a direct handshake, STARTTLS
+	// services are upgraded first, other talkative services are skipped
+	if ps.config.EnableSSL {
+		if certInfo := ps.probeTLS(conn, reader, port, result.Banner); certInfo != nil {
 			result.IsSSL = true
 			result.SSLInfo = certInfo
 		}
 	}
 
+	// The first connection is done with; close it before opening more so
+	// services that handle one client at a time can answer
+	conn.Close()
+
+	// Silent services get active probes, each on its own connection
+	if ps.config.BannerGrabbing && result.Banner == "" {
+		ps.activeBanner(address, port, result.IsSSL).set(&result, ps.config.BannerMaxBytes)
+	}
+
+	if ps.config.SSHScan && (port == 22 || strings.HasPrefix(result.Banner, "SSH-")) {
+		result.SSH = ssh.Scan(address, max(ps.config.Timeout, sshTimeout))
+		result.AddFindings(ssh.Findings(result.SSH)...)
+	}
+
+	if ps.config.SMBScan && (port == 445 || port == 139) {
+		result.SMB = smb.Scan(address, port, max(ps.config.Timeout, smbTimeout))
+		result.AddFindings(smb.Findings(result.SMB)...)
+		if result.Service == "" && (result.SMB.Dialect != "" || result.SMB.SMB1) {
+			result.Service = "smb"
+		}
+	}
+
+	if ps.config.HTTPEnum {
+		if scheme := httpScheme(&result); scheme != "" {
+			result.HTTP = web.Enumerate(address, scheme, web.Options{
+				Host:    ps.serverName(),
+				Timeout: max(ps.config.Timeout, httpTimeout),
+			})
+		}
+	}
+
+	// TLS enumeration and JARM use fresh connections, one per ClientHello
+	if result.IsSSL && (ps.config.TLSEnum || ps.config.JARM) {
+		opts := ssl.ProbeOptions{
+			ServerName: ps.serverName(),
+			StartTLS:   result.SSLInfo.StartTLS,
+			Timeout:    ps.config.Timeout,
+		}
+		if ps.config.TLSEnum {
+			if enum, err := ssl.Enumerate(address, opts); err == nil {
+				result.TLS = enum
+			}
+		}
+		if ps.config.JARM {
+			result.SSLInfo.JARM = ssl.JARM(address, opts)
+			result.SSLInfo.JARMLabel = ps.config.JARMLabels.Label(result.SSLInfo.JARM)
+		}
+	}
+
+	// Virtual host enumeration: one handshake per name sent as SNI
+	if result.IsSSL && (len(ps.config.VHosts) > 0 || ps.config.VHostsFromCert) {
+		names := ssl.VHostNames(ps.config.VHosts, result.SSLInfo, ps.config.VHostsFromCert, ps.serverName())
+		if len(names) > 0 {
+			result.SSLInfo.VHosts = ssl.VHostCertificates(address, names, result.SSLInfo.Fingerprint, ssl.ProbeOptions{
+				StartTLS: result.SSLInfo.StartTLS,
+				Timeout:  ps.config.Timeout,
+			})
+		}
+	}
+
+	if result.SSLInfo != nil {
+		result.AddFindings(ssl.AnalyzeCertificate(result.SSLInfo, time.Now())...)
+	}
+
+	result.Products = ps.config.Products.Identify(&result)
+	for _, p := range result.Products {
+		if result.Service == "" && p.Service != "" {
+			result.Service = p.Service
+		}
+	}
+	ps.runChecks(ctx, address, &result)
+
+	if ps.config.Vulns != nil {
+		result.Vulnerabilities = ps.config.Vulns.Match(result.Products)
+		result.AddFindings(vuln.Findings(result.Vulnerabilities)...)
+	}
+
 	return result
 }
 
+// probeTLS tries to complete a TLS handshake on an open connection
+func (ps *PortScanner) probeTLS(conn net.Conn, reader *bufio.Reader, port int, banner string) *models.SSLCertInfo {
+	protocol := ssl.DetectStartTLS(port, banner)
+	if protocol == "" && banner != "" {
+		return nil
+	}
+
+	// A silent server-first port is not that protocol; try TLS directly
+	if banner == "" && ps.config.BannerGrabbing && ssl.ServerSpeaksFirst(protocol) {
+		protocol = ""
+	}
+
+	if protocol != "" {
+		conn.SetDeadline(time.Now().Add(ps.config.Timeout))
+		// The greeting is still buffered in reader, so StartTLS reads it
+		err := ssl.StartTLS(conn, reader, protocol, ps.serverName(), "")
+		conn.SetDeadline(time.Time{})
+		if err != nil || reader.Buffered() > 0 {
+			return nil
+		}
+	}
+
+	serverName := ps.serverName()
+	state, err := ssl.Handshake(conn, serverName, ssl.ALPNProtocols, ps.config.Timeout)
+	if ssl.ALPNRejected(err) && protocol == "" {
+		// The failed handshake used up conn; retry on a new one
+		retry, dialErr := net.DialTimeout("tcp", net.JoinHostPort(ps.config.Host, strconv.Itoa(port)), ps.config.Timeout)
+		if dialErr != nil {
+			return nil
+		}
+		defer retry.Close()
+		state, err = ssl.Handshake(retry, serverName, nil, ps.config.Timeout)
+	}
+	if err != nil {
+		return nil
+	}
+
+	certInfo := ssl.CertInfoFromState(state, serverName, ps.config.RootCAs)
+	if certInfo != nil {
+		certInfo.StartTLS = protocol
+		if net.ParseIP(serverName) == nil {
+			certInfo.ServerName = serverName
+		}
+	}
+	return certInfo
+}
+
+// httpScheme is "http" or "https" when a port was seen speaking HTTP,
+// from its banner or the ALPN protocol it negotiated
+func httpScheme(result *models.ScanResult) string {
+	isHTTP := strings.HasPrefix(result.Banner, "HTTP/")
+	if result.IsSSL {
+		switch result.SSLInfo.ALPN {
+		case "h2", "http/1.1", "http/1.0":
+			isHTTP = true
+		}
+		if isHTTP {
+			return "https"
+		}
+		return ""
+	}
+	if isHTTP {
+		return "http"
+	}
+	return ""
+}
+
+// serverName is the name sent as SNI and in the Host header of banner
+// probes, and checked against certificates
+func (ps *PortScanner) serverName() string {
+	if ps.config.SNI != "" {
+		return ps.config.SNI
+	}
+	return ps.config.Host
+}
+
 // probeUDP probes a single UDP port
-func (ps *PortScanner) probeUDP(port int) models.ScanResult {
+func (ps *PortScanner) probeUDP(ctx context.Context, port int) models.ScanResult {
 	result := models.ScanResult{
 		Host:     ps.config.Host,
 		Port:     port,
@@ -203,7 +466,7 @@ func (ps *PortScanner) probeUDP(port int) models.ScanResult {
 		Status:   "closed",
 	}
 
-	address := fmt.Sprintf("%s:%d", ps.config.Host, port)
+	address := net.JoinHostPort(ps.config.Host, strconv.Itoa(port))
 	conn, err := net.DialTimeout("udp", address, ps.config.Timeout)
 	if err != nil {
 		result.Status = "closed"
@@ -219,28 +482,78 @@ func (ps *PortScanner) probeUDP(port int) models.ScanResult {
 	}
 
 	result.Status = "open"
+	ps.runChecks(ctx, address, &result)
 	return result
 }
 
-// grabBanner attempts to grab banner from a connection
-func grabBanner(conn net.Conn) string {
-	conn.SetReadDeadline(time.Now().Add(1 * time.Second))
-	defer conn.SetReadDeadline(time.Time{})
+// runChecks hands an open port to the registered checks that match it
+func (ps *PortScanner) runChecks(ctx context.Context, address string, result *models.ScanResult) {
+	target := &check.Target{
+		Host:       ps.config.Host,
+		Address:    address,
+		Port:       result.Port,
+		Protocol:   result.Protocol,
+		TLS:        result.IsSSL && result.SSLInfo.StartTLS == "",
+		ServerName: ps.serverName(),
+		Result:     result,
+	}
+	findings, errs := ps.checks.Run(ctx, target, check.RunOptions{
+		Timeout: max(ps.config.Timeout, check.DefaultTimeout),
+	})
+	result.AddFindings(findings...)
+	result.CheckErrors = append(result.CheckErrors, errs...)
+}
+
+// classifyDialError maps a failed TCP dial to a port status. Refused
+// connections mean closed and timeouts mean filtered; anything else is
+// counted as a scan error and reported as closed.
+func (ps *PortScanner) classifyDialError(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "filtered"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "closed"
+	default:
+		atomic.AddInt64(&ps.errors, 1)
+		return "closed"
+	}
+}
 
-	reader := bufio.NewReader(conn)
-	banner, err := reader.ReadString('\n')
-	if err != nil {
-		return ""
+// runNmapScripts runs the configured nmap scripts against the open TCP
+// ports, all in one nmap run
+func (ps *PortScanner) runNmapScripts(ctx context.Context, results []models.ScanResult, scripts []string) {
+	var ports []int
+	for _, result := range results {
+		if result.Protocol == "tcp" && result.Status == "open" {
+			ports = append(ports, result.Port)
+		}
+	}
+	if len(ports) == 0 {
+		return
 	}
 
-	return banner[:len(banner)-1]
+	run := ps.nmap.RunHost(ctx, ps.config.Host, ports, scripts, ps.config.Timeout)
+	// runs that never started (e.g. nmap missing) have no run time
+	if run.Duration > 0 {
+		status := nmap.StatusSuccess
+		if run.Err != nil {
+			status = nmap.StatusError
+		}
+		metrics.ObserveNmapRun(run.Duration, status)
+	}
+	for i := range results {
+		if results[i].Protocol == "tcp" && results[i].Status == "open" {
+			results[i].NmapResults = run.Results[results[i].Port]
+		}
+	}
 }
 
-// countOpen counts open ports of a given protocol
-func countOpen(results []models.ScanResult, protocol string) int {
+// countStatus counts ports of a given protocol and status
+func countStatus(results []models.ScanResult, protocol, status string) int {
 	count := 0
 	for _, r := range results {
-		if r.Protocol == protocol && r.Status == "open" {
+		if r.Protocol == protocol && r.Status == status {
 			count++
 		}
 	}
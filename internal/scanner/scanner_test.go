@@ -0,0 +1,66 @@
+package scanner
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/Sh4Ryuu/go-scan/internal/output"
+	"github.com/Sh4Ryuu/go-scan/internal/testutil"
+	"github.com/Sh4Ryuu/go-scan/pkg/check"
+)
+
+// localScanner scans the port of a loopback address with the default
+// settings, changed by modify
+func localScanner(t *testing.T, address string, modify func(*Config)) (*PortScanner, int) {
+	t.Helper()
+	port := portOf(t, address)
+	config := DefaultConfig()
+	config.Host = "127.0.0.1"
+	config.StartPort, config.EndPort = port, port
+	config.EnableSSL = false
+	config.EnableGeolocation = false
+	modify(config)
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	ps, err := NewPortScanner(config, output.NewFormatter(&output.FormatterConfig{Quiet: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ps, port
+}
+
+func TestSSHScanOption(t *testing.T) {
+	address := testutil.Serve(t, func(conn net.Conn) {
+		conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+	})
+
+	for _, enabled := range []bool{true, false} {
+		ps, port := localScanner(t, address, func(c *Config) { c.SSHScan = enabled })
+		result := ps.probeTCP(context.Background(), port)
+		if result.Status != "open" || result.Banner != "SSH-2.0-OpenSSH_9.6" {
+			t.Fatalf("got %+v", result)
+		}
+		if enabled && (result.SSH == nil || result.SSH.Software != "OpenSSH_9.6") {
+			t.Errorf("SSH analysis on: got %+v", result.SSH)
+		}
+		if !enabled && result.SSH != nil {
+			t.Errorf("SSH analysis off: got %+v", result.SSH)
+		}
+	}
+
+	if config := DefaultConfig(); !config.SSHScan || !config.SMBScan {
+		t.Errorf("native analysis is off by default: ssh %v, smb %v", config.SSHScan, config.SMBScan)
+	}
+}
+
+func TestNewPortScannerCheckCollision(t *testing.T) {
+	config := DefaultConfig()
+	config.ExposureChecks = true
+	config.PluginChecks = []check.Check{check.Func(check.Info{Name: "redis-no-auth"}, nil)}
+	ps, err := NewPortScanner(config, output.NewFormatter(&output.FormatterConfig{Quiet: true}))
+	if ps != nil || err == nil || err.Error() != "check redis-no-auth: already registered" {
+		t.Errorf("got %v, %v", ps, err)
+	}
+}
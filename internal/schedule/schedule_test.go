@@ -0,0 +1,86 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04:05", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		spec string
+		from string
+		want string // empty when the schedule never fires
+	}{
+		{"15m", "2024-01-22 10:07:30", "2024-01-22 10:22:30"},
+		{"@every 90s", "2024-01-22 10:07:30", "2024-01-22 10:09:00"},
+		{"*/15 * * * *", "2024-01-22 10:07:30", "2024-01-22 10:15:00"},
+		{"*/15 * * * *", "2024-01-22 10:15:00", "2024-01-22 10:30:00"},
+		{"5/20 * * * *", "2024-01-22 10:06:00", "2024-01-22 10:25:00"},
+		{"0,30 8-9 * * *", "2024-01-22 09:45:00", "2024-01-23 08:00:00"},
+		{"@hourly", "2024-01-22 10:59:59", "2024-01-22 11:00:00"},
+		{"@daily", "2024-12-31 23:59:00", "2025-01-01 00:00:00"},
+		{"@weekly", "2024-01-22 10:00:00", "2024-01-28 00:00:00"},
+		{"@monthly", "2024-01-31 10:00:00", "2024-02-01 00:00:00"},
+		{"@yearly", "2024-01-22 10:00:00", "2025-01-01 00:00:00"},
+		// Friday evening to Monday morning
+		{"30 9 * * 1-5", "2024-01-19 10:00:00", "2024-01-22 09:30:00"},
+		// 7 is Sunday too
+		{"0 0 * * 7", "2024-01-22 10:00:00", "2024-01-28 00:00:00"},
+		{"0 6 * jun SUN", "2024-01-01 00:00:00", "2024-06-02 06:00:00"},
+		// Day of month and day of week combine with OR when both are set
+		{"0 12 1 * mon", "2024-01-02 00:00:00", "2024-01-08 12:00:00"},
+		{"0 12 1 * mon", "2024-01-29 13:00:00", "2024-02-01 12:00:00"},
+		{"0 0 31 * *", "2024-04-01 00:00:00", "2024-05-31 00:00:00"},
+		{"0 0 29 2 *", "2024-03-01 00:00:00", "2028-02-29 00:00:00"},
+		{"0 0 30 2 *", "2024-01-01 00:00:00", ""},
+	}
+	for _, tt := range tests {
+		sched, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.spec, err)
+			continue
+		}
+		got := sched.Next(at(tt.from))
+		var want time.Time
+		if tt.want != "" {
+			want = at(tt.want)
+		}
+		if !got.Equal(want) {
+			t.Errorf("%q after %s: got %s, want %s", tt.spec, tt.from, got, want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"", "empty schedule"},
+		{"soon", `invalid interval "soon"`},
+		{"500ms", "must be at least 1s"},
+		{"@every -5m", "must be at least 1s"},
+		{"* * * *", "must have 5 fields, got 4"},
+		{"60 * * * *", "minute: value 60 out of range 0-59"},
+		{"* 24 * * *", "hour: value 24 out of range 0-23"},
+		{"* * 0 * *", "day of month: value 0 out of range 1-31"},
+		{"* * * foo *", `month: invalid value "foo"`},
+		{"* * * * 8", "day of week: value 8 out of range 0-7"},
+		{"*/0 * * * *", `minute: invalid step in "*/0"`},
+		{"30-10 * * * *", `minute: invalid range "30-10"`},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.spec); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q): got error %v, want %q", tt.spec, err, tt.want)
+		}
+	}
+}
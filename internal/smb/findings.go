@@ -0,0 +1,37 @@
+package smb
+
+import (
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// Findings flags SMB1 support and servers that do not require signing
+func Findings(info *models.SMBInfo) []models.Finding {
+	if info == nil || (info.Dialect == "" && !info.SMB1) {
+		return nil
+	}
+	var findings []models.Finding
+
+	if info.SMB1 {
+		findings = append(findings, models.Finding{
+			ID:       "smb1-enabled",
+			Title:    "SMBv1 supported",
+			Severity: models.SeverityHigh,
+			Detail:   "the server accepts the NT LM 0.12 dialect, which EternalBlue and other SMB1 exploits need",
+		})
+	}
+
+	if !info.SigningRequired {
+		detail := "signing is enabled but not required, so sessions can be relayed"
+		if !info.SigningEnabled {
+			detail = "signing is disabled, so sessions can be relayed"
+		}
+		findings = append(findings, models.Finding{
+			ID:       "smb-signing-not-required",
+			Title:    "SMB signing not required",
+			Severity: models.SeverityMedium,
+			Detail:   detail,
+		})
+	}
+
+	return findings
+}
@@ -0,0 +1,350 @@
+package smb
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"time"
+	"unicode/utf16"
+)
+
+// SMB2 commands and status codes (MS-SMB2)
+const (
+	smb2Negotiate    = 0x0000
+	smb2SessionSetup = 0x0001
+
+	statusSuccess         = 0x00000000
+	statusMoreProcessing  = 0xc0000016
+	smb2HeaderLen         = 64
+	smb2NegotiateRespSize = 65
+)
+
+// SMB2 dialects, lowest first
+var dialects = []struct {
+	revision uint16
+	name     string
+}{
+	{0x0202, "2.0.2"},
+	{0x0210, "2.1"},
+	{0x0300, "3.0"},
+	{0x0302, "3.0.2"},
+	{0x0311, "3.1.1"},
+}
+
+// dialectName names a dialect revision
+func dialectName(revision uint16) string {
+	for _, d := range dialects {
+		if d.revision == revision {
+			return d.name
+		}
+	}
+	return fmt.Sprintf("0x%04x", revision)
+}
+
+// SMB2 capability bits
+var capabilities = []struct {
+	bit  uint32
+	name string
+}{
+	{0x01, "DFS"},
+	{0x02, "LEASING"},
+	{0x04, "LARGE_MTU"},
+	{0x08, "MULTI_CHANNEL"},
+	{0x10, "PERSISTENT_HANDLES"},
+	{0x20, "DIRECTORY_LEASING"},
+	{0x40, "ENCRYPTION"},
+}
+
+// SMB 3.1.1 negotiate contexts and encryption ciphers
+const (
+	contextPreauth    = 0x0001
+	contextEncryption = 0x0002
+	hashSHA512        = 0x0001
+)
+
+var ciphers = map[uint16]string{
+	0x0001: "AES-128-CCM",
+	0x0002: "AES-128-GCM",
+	0x0003: "AES-256-CCM",
+	0x0004: "AES-256-GCM",
+}
+
+// Security mode bits, SMB2 and SMB1
+const (
+	smb2SigningEnabled  = 0x01
+	smb2SigningRequired = 0x02
+	smb1SigningEnabled  = 0x04
+	smb1SigningRequired = 0x08
+)
+
+// negotiation is what an SMB2 NEGOTIATE response says
+type negotiation struct {
+	dialect      uint16
+	securityMode uint16
+	guid         [16]byte
+	capabilities uint32
+	systemTime   uint64
+	cipher       uint16
+}
+
+// smb2Header builds an SMB2 header for a request
+func smb2Header(command uint16, messageID uint64) []byte {
+	header := make([]byte, smb2HeaderLen)
+	copy(header, "\xfeSMB")
+	binary.LittleEndian.PutUint16(header[4:], smb2HeaderLen)
+	binary.LittleEndian.PutUint16(header[12:], command)
+	binary.LittleEndian.PutUint16(header[14:], 1) // credits requested
+	binary.LittleEndian.PutUint64(header[24:], messageID)
+	return header
+}
+
+// negotiateRequest offers the given dialects. Offering 3.1.1 adds the
+// negotiate contexts it requires.
+func negotiateRequest(offer []uint16) []byte {
+	msg := smb2Header(smb2Negotiate, 0)
+	body := make([]byte, 36)
+	binary.LittleEndian.PutUint16(body, 36)
+	binary.LittleEndian.PutUint16(body[2:], uint16(len(offer)))
+	binary.LittleEndian.PutUint16(body[4:], smb2SigningEnabled)
+	binary.LittleEndian.PutUint32(body[8:], 0x7f) // every capability
+	rand.Read(body[12:28])                        // client GUID
+	for _, revision := range offer {
+		body = binary.LittleEndian.AppendUint16(body, revision)
+	}
+	msg = append(msg, body...)
+
+	if offer[len(offer)-1] != 0x0311 {
+		return msg
+	}
+
+	salt := make([]byte, 32)
+	rand.Read(salt)
+	preauth := binary.LittleEndian.AppendUint16(nil, 1) // hash algorithms
+	preauth = binary.LittleEndian.AppendUint16(preauth, uint16(len(salt)))
+	preauth = binary.LittleEndian.AppendUint16(preauth, hashSHA512)
+	preauth = append(preauth, salt...)
+
+	encryption := binary.LittleEndian.AppendUint16(nil, uint16(len(ciphers)))
+	for _, id := range []uint16{0x0004, 0x0003, 0x0002, 0x0001} {
+		encryption = binary.LittleEndian.AppendUint16(encryption, id)
+	}
+
+	msg = pad8(msg)
+	binary.LittleEndian.PutUint32(msg[smb2HeaderLen+28:], uint32(len(msg)))
+	binary.LittleEndian.PutUint16(msg[smb2HeaderLen+32:], 2)
+	msg = appendContext(msg, contextPreauth, preauth)
+	msg = pad8(msg)
+	return appendContext(msg, contextEncryption, encryption)
+}
+
+// appendContext appends a negotiate context
+func appendContext(msg []byte, contextType uint16, data []byte) []byte {
+	msg = binary.LittleEndian.AppendUint16(msg, contextType)
+	msg = binary.LittleEndian.AppendUint16(msg, uint16(len(data)))
+	msg = append(msg, 0, 0, 0, 0)
+	return append(msg, data...)
+}
+
+// pad8 pads msg to a multiple of 8 bytes
+func pad8(msg []byte) []byte {
+	for len(msg)%8 != 0 {
+		msg = append(msg, 0)
+	}
+	return msg
+}
+
+// negotiate sends an SMB2 NEGOTIATE offering the given dialects and
+// parses the response
+func (t *transport) negotiate(offer []uint16) (*negotiation, error) {
+	if err := t.send(negotiateRequest(offer)); err != nil {
+		return nil, err
+	}
+	reply, err := t.receive()
+	if err != nil {
+		return nil, err
+	}
+	status, err := smb2Status(reply, smb2Negotiate)
+	if err != nil {
+		return nil, err
+	}
+	if status != statusSuccess {
+		return nil, fmt.Errorf("negotiate failed: status 0x%08x", status)
+	}
+	return parseNegotiate(reply)
+}
+
+// smb2Status checks an SMB2 reply header and returns its status
+func smb2Status(reply []byte, command uint16) (uint32, error) {
+	if len(reply) < smb2HeaderLen || !bytes.HasPrefix(reply, []byte("\xfeSMB")) {
+		return 0, fmt.Errorf("not an SMB2 reply")
+	}
+	if got := binary.LittleEndian.Uint16(reply[12:]); got != command {
+		return 0, fmt.Errorf("unexpected SMB2 command 0x%04x", got)
+	}
+	return binary.LittleEndian.Uint32(reply[8:]), nil
+}
+
+// parseNegotiate decodes a NEGOTIATE response, including the cipher from
+// its encryption context
+func parseNegotiate(reply []byte) (*negotiation, error) {
+	body := reply[smb2HeaderLen:]
+	if len(body) < 64 || binary.LittleEndian.Uint16(body) != smb2NegotiateRespSize {
+		return nil, fmt.Errorf("malformed negotiate response")
+	}
+	n := &negotiation{
+		securityMode: binary.LittleEndian.Uint16(body[2:]),
+		dialect:      binary.LittleEndian.Uint16(body[4:]),
+		capabilities: binary.LittleEndian.Uint32(body[24:]),
+		systemTime:   binary.LittleEndian.Uint64(body[40:]),
+	}
+	copy(n.guid[:], body[8:24])
+
+	count := int(binary.LittleEndian.Uint16(body[6:]))
+	offset := int(binary.LittleEndian.Uint32(body[60:]))
+	if n.dialect != 0x0311 || count == 0 {
+		return n, nil
+	}
+	for i := 0; i < count && offset+8 <= len(reply); i++ {
+		contextType := binary.LittleEndian.Uint16(reply[offset:])
+		length := int(binary.LittleEndian.Uint16(reply[offset+2:]))
+		data := reply[offset+8:]
+		if length > len(data) {
+			break
+		}
+		data = data[:length]
+		if contextType == contextEncryption && len(data) >= 4 {
+			n.cipher = binary.LittleEndian.Uint16(data[2:])
+		}
+		offset = (offset + 8 + length + 7) &^ 7
+	}
+	return n, nil
+}
+
+// guidString formats a GUID as Windows does; the first three fields are
+// little-endian
+func guidString(guid [16]byte) string {
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(guid[0:]),
+		binary.LittleEndian.Uint16(guid[4:]),
+		binary.LittleEndian.Uint16(guid[6:]),
+		guid[8:10], guid[10:])
+}
+
+// filetime converts a Windows FILETIME, 100ns intervals since 1601, to a
+// time; zero stays zero
+func filetime(ft uint64) time.Time {
+	if ft == 0 {
+		return time.Time{}
+	}
+	const epochDelta = 116444736000000000 // 1601 to 1970 in 100ns
+	return time.Unix(0, (int64(ft)-epochDelta)*100).UTC()
+}
+
+// SMB1 commands and flags (MS-CIFS, MS-SMB)
+const (
+	smb1Negotiate        = 0x72
+	smb1SessionSetupAndX = 0x73
+	smb1HeaderLen        = 32
+
+	smb1Flags  = 0x18   // case insensitive, canonical paths
+	smb1Flags2 = 0xc843 // unicode, NT status, extended security, long names
+
+	smb1Unicode             = 0x8000
+	smb1ExtendedSecurity    = 0x0800
+	smb1CapExtendedSecurity = 0x80000000
+)
+
+// smb1Negotiation is what an SMB1 NEGOTIATE response says
+type smb1Negotiation struct {
+	securityMode byte
+	flags2       uint16
+	capabilities uint32
+}
+
+// smb1Header builds an SMB1 header for a request
+func smb1Header(command byte, mid uint16) []byte {
+	header := make([]byte, smb1HeaderLen)
+	copy(header, "\xffSMB")
+	header[4] = command
+	header[9] = smb1Flags
+	binary.LittleEndian.PutUint16(header[10:], smb1Flags2)
+	binary.LittleEndian.PutUint16(header[26:], 0xfeff) // process ID
+	binary.LittleEndian.PutUint16(header[30:], mid)
+	return header
+}
+
+// negotiateSMB1 offers only the NT LM 0.12 dialect. A server without SMB1
+// refuses it, drops the connection or answers with SMB2.
+func (t *transport) negotiateSMB1() (*smb1Negotiation, error) {
+	dialect := append([]byte{0x02}, "NT LM 0.12\x00"...)
+	msg := smb1Header(smb1Negotiate, 0)
+	msg = append(msg, 0) // no parameter words
+	msg = binary.LittleEndian.AppendUint16(msg, uint16(len(dialect)))
+	msg = append(msg, dialect...)
+	if err := t.send(msg); err != nil {
+		return nil, err
+	}
+
+	reply, err := t.receive()
+	if err != nil {
+		return nil, err
+	}
+	status, err := smb1Status(reply, smb1Negotiate)
+	if err != nil {
+		return nil, err
+	}
+	if status != statusSuccess {
+		return nil, fmt.Errorf("SMB1 negotiate failed: status 0x%08x", status)
+	}
+
+	words := int(reply[smb1HeaderLen])
+	params := reply[smb1HeaderLen+1:]
+	if words < 1 || len(params) < 2*words {
+		return nil, fmt.Errorf("malformed SMB1 negotiate response")
+	}
+	if binary.LittleEndian.Uint16(params) == 0xffff {
+		return nil, fmt.Errorf("SMB1 dialect refused")
+	}
+	n := &smb1Negotiation{flags2: binary.LittleEndian.Uint16(reply[10:])}
+	// NT LM 0.12 responses have 17 words: the security mode follows the
+	// dialect index, the capabilities are at byte 19
+	if words >= 17 {
+		n.securityMode = params[2]
+		n.capabilities = binary.LittleEndian.Uint32(params[19:])
+	}
+	return n, nil
+}
+
+// smb1Status checks an SMB1 reply header and returns its status
+func smb1Status(reply []byte, command byte) (uint32, error) {
+	if len(reply) < smb1HeaderLen+3 || !bytes.HasPrefix(reply, []byte("\xffSMB")) {
+		return 0, fmt.Errorf("not an SMB1 reply")
+	}
+	if reply[4] != command {
+		return 0, fmt.Errorf("unexpected SMB1 command 0x%02x", reply[4])
+	}
+	return binary.LittleEndian.Uint32(reply[5:]), nil
+}
+
+// utf16String decodes a UTF-16LE string, stopping at a NUL
+func utf16String(b []byte) string {
+	s, _ := readUTF16(b)
+	return s
+}
+
+// readUTF16 decodes a NUL-terminated UTF-16LE string and returns it with
+// the number of bytes read, terminator included
+func readUTF16(b []byte) (string, int) {
+	units := make([]uint16, 0, len(b)/2)
+	n := 0
+	for ; n+1 < len(b); n += 2 {
+		u := binary.LittleEndian.Uint16(b[n:])
+		if u == 0 {
+			n += 2
+			break
+		}
+		units = append(units, u)
+	}
+	return string(utf16.Decode(units)), n
+}
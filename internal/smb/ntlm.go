@@ -0,0 +1,238 @@
+package smb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// NTLMSSP (MS-NLMP)
+const (
+	ntlmNegotiate = 1
+	ntlmChallenge = 2
+
+	// Unicode, OEM, request target, NTLM, always sign, extended session
+	// security, target info, version, 128-bit, key exchange, 56-bit
+	ntlmNegotiateFlags = 0xe2088297
+	ntlmFlagVersion    = 0x02000000
+)
+
+var ntlmSignature = []byte("NTLMSSP\x00")
+
+// TargetInfo AV pair IDs
+const (
+	avEOL                 = 0
+	avNetBIOSComputerName = 1
+	avNetBIOSDomainName   = 2
+	avDNSComputerName     = 3
+	avDNSDomainName       = 4
+	avDNSTreeName         = 5
+)
+
+// challenge is what an NTLMSSP CHALLENGE message reveals about a server
+type challenge struct {
+	targetInfo map[uint16]string
+	major      byte
+	minor      byte
+	build      uint16
+	hasVersion bool
+}
+
+// ntlmNegotiateMessage builds an NTLMSSP NEGOTIATE with no domain or
+// workstation
+func ntlmNegotiateMessage() []byte {
+	msg := append([]byte(nil), ntlmSignature...)
+	msg = binary.LittleEndian.AppendUint32(msg, ntlmNegotiate)
+	msg = binary.LittleEndian.AppendUint32(msg, ntlmNegotiateFlags)
+	msg = append(msg, make([]byte, 16)...)              // domain and workstation fields
+	return append(msg, 6, 1, 0xb1, 0x1d, 0, 0, 0, 0x0f) // version 6.1.7601, NTLM revision 15
+}
+
+// OIDs of SPNEGO and NTLMSSP, DER encoded
+var (
+	oidSPNEGO  = []byte{0x06, 0x06, 0x2b, 0x06, 0x01, 0x05, 0x05, 0x02}
+	oidNTLMSSP = []byte{0x06, 0x0a, 0x2b, 0x06, 0x01, 0x04, 0x01, 0x82, 0x37, 0x02, 0x02, 0x0a}
+)
+
+// spnegoInit wraps an NTLMSSP token in a SPNEGO NegTokenInit that offers
+// only NTLMSSP
+func spnegoInit(token []byte) []byte {
+	mechTypes := der(0xa0, der(0x30, oidNTLMSSP))
+	mechToken := der(0xa2, der(0x04, token))
+	negTokenInit := der(0xa0, der(0x30, append(mechTypes, mechToken...)))
+	return der(0x60, append(append([]byte(nil), oidSPNEGO...), negTokenInit...))
+}
+
+// der encodes a DER tag, length and value
+func der(tag byte, value []byte) []byte {
+	out := []byte{tag}
+	switch n := len(value); {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n < 0x100:
+		out = append(out, 0x81, byte(n))
+	default:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	}
+	return append(out, value...)
+}
+
+// parseChallenge finds the NTLMSSP CHALLENGE in a security blob, skipping
+// whatever SPNEGO wrapping surrounds it
+func parseChallenge(blob []byte) (*challenge, error) {
+	start := bytes.Index(blob, ntlmSignature)
+	if start < 0 {
+		return nil, fmt.Errorf("no NTLMSSP challenge")
+	}
+	msg := blob[start:]
+	if len(msg) < 48 || binary.LittleEndian.Uint32(msg[8:]) != ntlmChallenge {
+		return nil, fmt.Errorf("malformed NTLMSSP challenge")
+	}
+
+	c := &challenge{targetInfo: make(map[uint16]string)}
+	flags := binary.LittleEndian.Uint32(msg[20:])
+	if flags&ntlmFlagVersion != 0 && len(msg) >= 56 {
+		c.major, c.minor = msg[48], msg[49]
+		c.build = binary.LittleEndian.Uint16(msg[50:])
+		c.hasVersion = true
+	}
+
+	length := int(binary.LittleEndian.Uint16(msg[40:]))
+	offset := int(binary.LittleEndian.Uint32(msg[44:]))
+	if offset > len(msg) || length > len(msg)-offset {
+		return c, nil
+	}
+	info := msg[offset : offset+length]
+	for len(info) >= 4 {
+		id := binary.LittleEndian.Uint16(info)
+		size := int(binary.LittleEndian.Uint16(info[2:]))
+		if id == avEOL || 4+size > len(info) {
+			break
+		}
+		if id <= avDNSTreeName {
+			c.targetInfo[id] = utf16String(info[4 : 4+size])
+		}
+		info = info[4+size:]
+	}
+	return c, nil
+}
+
+// sessionSetup sends an SMB2 SESSION_SETUP carrying an NTLMSSP NEGOTIATE
+// and returns the server's CHALLENGE. The session is never completed.
+func (t *transport) sessionSetup() (*challenge, error) {
+	blob := spnegoInit(ntlmNegotiateMessage())
+	msg := smb2Header(smb2SessionSetup, 1)
+	body := make([]byte, 24)
+	binary.LittleEndian.PutUint16(body, 25)
+	body[3] = smb2SigningEnabled
+	binary.LittleEndian.PutUint16(body[12:], smb2HeaderLen+24)
+	binary.LittleEndian.PutUint16(body[14:], uint16(len(blob)))
+	msg = append(append(msg, body...), blob...)
+	if err := t.send(msg); err != nil {
+		return nil, err
+	}
+
+	reply, err := t.receive()
+	if err != nil {
+		return nil, err
+	}
+	status, err := smb2Status(reply, smb2SessionSetup)
+	if err != nil {
+		return nil, err
+	}
+	if status != statusMoreProcessing {
+		return nil, fmt.Errorf("session setup: status 0x%08x", status)
+	}
+	if len(reply) < smb2HeaderLen+8 {
+		return nil, fmt.Errorf("malformed session setup response")
+	}
+	offset := int(binary.LittleEndian.Uint16(reply[smb2HeaderLen+4:]))
+	length := int(binary.LittleEndian.Uint16(reply[smb2HeaderLen+6:]))
+	if offset > len(reply) || length > len(reply)-offset {
+		return nil, fmt.Errorf("malformed session setup response")
+	}
+	return parseChallenge(reply[offset : offset+length])
+}
+
+// smb1Session is what an SMB1 SESSION_SETUP_ANDX reply reveals
+type smb1Session struct {
+	challenge    *challenge
+	nativeOS     string
+	nativeLanMan string
+}
+
+// sessionSetupSMB1 sends an SMB1 SESSION_SETUP_ANDX with an NTLMSSP
+// NEGOTIATE on a connection that negotiated NT LM 0.12 with extended
+// security, and returns the challenge and the server's native OS strings
+func (t *transport) sessionSetupSMB1() (*smb1Session, error) {
+	blob := spnegoInit(ntlmNegotiateMessage())
+	msg := smb1Header(smb1SessionSetupAndX, 1)
+	msg = append(msg, 12) // parameter words
+	params := make([]byte, 24)
+	params[0] = 0xff                                // no AndX command
+	binary.LittleEndian.PutUint16(params[4:], 4356) // max buffer size
+	binary.LittleEndian.PutUint16(params[6:], 2)    // max multiplexed requests
+	binary.LittleEndian.PutUint16(params[8:], 1)    // virtual circuit
+	binary.LittleEndian.PutUint16(params[14:], uint16(len(blob)))
+	binary.LittleEndian.PutUint32(params[20:], smb1CapExtendedSecurity|0x5c) // NT SMBs, status32, unicode, large files
+	msg = append(msg, params...)
+
+	data := append([]byte(nil), blob...)
+	if (len(msg)+2+len(data))%2 != 0 {
+		data = append(data, 0) // unicode strings are 2-byte aligned
+	}
+	data = append(data, 0, 0, 0, 0) // empty native OS and LAN manager
+	msg = binary.LittleEndian.AppendUint16(msg, uint16(len(data)))
+	msg = append(msg, data...)
+	if err := t.send(msg); err != nil {
+		return nil, err
+	}
+
+	reply, err := t.receive()
+	if err != nil {
+		return nil, err
+	}
+	status, err := smb1Status(reply, smb1SessionSetupAndX)
+	if err != nil {
+		return nil, err
+	}
+	if status != statusMoreProcessing {
+		return nil, fmt.Errorf("SMB1 session setup: status 0x%08x", status)
+	}
+
+	// Four parameter words, the last the security blob length, then the
+	// byte count, the blob and the native OS and LAN manager strings
+	if reply[smb1HeaderLen] != 4 || len(reply) < smb1HeaderLen+11 {
+		return nil, fmt.Errorf("malformed SMB1 session setup response")
+	}
+	blobLen := int(binary.LittleEndian.Uint16(reply[smb1HeaderLen+7:]))
+	start := smb1HeaderLen + 11
+	if blobLen > len(reply)-start {
+		return nil, fmt.Errorf("malformed SMB1 session setup response")
+	}
+	session := &smb1Session{}
+	if session.challenge, err = parseChallenge(reply[start : start+blobLen]); err != nil {
+		return nil, err
+	}
+
+	pos := start + blobLen
+	if binary.LittleEndian.Uint16(reply[10:])&smb1Unicode == 0 {
+		return session, nil
+	}
+	if pos%2 != 0 {
+		pos++
+	}
+	var strs []string
+	for len(strs) < 2 && pos < len(reply) {
+		s, n := readUTF16(reply[pos:])
+		strs = append(strs, s)
+		pos += n
+	}
+	if len(strs) > 0 {
+		session.nativeOS = strs[0]
+	}
+	if len(strs) > 1 {
+		session.nativeLanMan = strs[1]
+	}
+	return session, nil
+}
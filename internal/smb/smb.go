@@ -0,0 +1,165 @@
+// Package smb collects SMB server facts natively: the SMB2/3 dialects,
+// signing requirements and server GUID from NEGOTIATE, whether SMB1 is
+// still accepted, and the OS and domain names a server reveals in the
+// NTLMSSP challenge of a session setup that is never completed
+package smb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// Scan negotiates with the SMB server at address, over a NetBIOS session
+// when port is 139. Each dialect probe uses its own connection, bounded
+// by timeout.
+func Scan(address string, port int, timeout time.Duration) *models.SMBInfo {
+	info := &models.SMBInfo{}
+
+	// Offering every dialect finds the highest and the server's details;
+	// the same connection then carries the session setup
+	err := scanSMB2(info, address, port, timeout)
+	if info.Dialect != "" {
+		probeDialects(info, address, port, timeout)
+	}
+	scanSMB1(info, address, port, timeout)
+
+	// A server that only speaks SMB1 is expected to fail SMB2
+	if err != nil && (info.Dialect != "" || !info.SMB1) {
+		info.Error = err.Error()
+	}
+	return info
+}
+
+// scanSMB2 negotiates with every dialect offered and, if that works, reads
+// the NTLMSSP challenge of a session setup on the same connection
+func scanSMB2(info *models.SMBInfo, address string, port int, timeout time.Duration) error {
+	t, err := dial(address, port, timeout)
+	if err != nil {
+		return err
+	}
+	defer t.close()
+
+	offer := make([]uint16, len(dialects))
+	for i, d := range dialects {
+		offer[i] = d.revision
+	}
+	n, err := t.negotiate(offer)
+	if err != nil {
+		return err
+	}
+
+	info.Dialect = dialectName(n.dialect)
+	info.ServerGUID = guidString(n.guid)
+	info.SigningEnabled = n.securityMode&smb2SigningEnabled != 0
+	info.SigningRequired = n.securityMode&smb2SigningRequired != 0
+	for _, c := range capabilities {
+		if n.capabilities&c.bit != 0 {
+			info.Capabilities = append(info.Capabilities, c.name)
+		}
+	}
+	if n.cipher != 0 {
+		info.Cipher = ciphers[n.cipher]
+	}
+	if systemTime := filetime(n.systemTime); !systemTime.IsZero() {
+		info.SystemTime = &systemTime
+	}
+
+	c, err := t.sessionSetup()
+	if err != nil {
+		return err
+	}
+	setChallenge(info, c)
+	return nil
+}
+
+// probeDialects offers each dialect below the highest on its own, since
+// servers only ever pick one; the list ends up lowest first
+func probeDialects(info *models.SMBInfo, address string, port int, timeout time.Duration) {
+	for _, d := range dialects {
+		if d.name == info.Dialect {
+			info.Dialects = append(info.Dialects, d.name)
+			return
+		}
+		t, err := dial(address, port, timeout)
+		if err != nil {
+			continue
+		}
+		if n, err := t.negotiate([]uint16{d.revision}); err == nil && n.dialect == d.revision {
+			info.Dialects = append(info.Dialects, d.name)
+		}
+		t.close()
+	}
+}
+
+// scanSMB1 offers the NT LM 0.12 dialect on a new connection. When SMB2
+// did not answer, SMB1 also supplies the signing mode and the challenge,
+// and in any case the native OS strings of its session setup.
+func scanSMB1(info *models.SMBInfo, address string, port int, timeout time.Duration) {
+	t, err := dial(address, port, timeout)
+	if err != nil {
+		return
+	}
+	defer t.close()
+
+	n, err := t.negotiateSMB1()
+	if err != nil {
+		return
+	}
+	info.SMB1 = true
+	if info.Dialect == "" {
+		info.SigningEnabled = n.securityMode&smb1SigningEnabled != 0
+		info.SigningRequired = n.securityMode&smb1SigningRequired != 0
+	}
+	if n.flags2&smb1ExtendedSecurity == 0 && n.capabilities&smb1CapExtendedSecurity == 0 {
+		return // challenge/response without NTLMSSP
+	}
+
+	session, err := t.sessionSetupSMB1()
+	if err != nil {
+		return
+	}
+	info.NativeOS = session.nativeOS
+	info.NativeLanManager = session.nativeLanMan
+	if info.OSVersion == "" && info.NetBIOSComputerName == "" {
+		setChallenge(info, session.challenge)
+	}
+}
+
+// setChallenge records what an NTLMSSP challenge reveals
+func setChallenge(info *models.SMBInfo, c *challenge) {
+	info.NetBIOSComputerName = c.targetInfo[avNetBIOSComputerName]
+	info.NetBIOSDomainName = c.targetInfo[avNetBIOSDomainName]
+	info.DNSComputerName = c.targetInfo[avDNSComputerName]
+	info.DNSDomainName = c.targetInfo[avDNSDomainName]
+	info.DNSTreeName = c.targetInfo[avDNSTreeName]
+	if c.hasVersion && c.major != 0 {
+		info.OSVersion = fmt.Sprintf("%d.%d.%d", c.major, c.minor, c.build)
+		info.OS = windowsName(c.major, c.minor)
+	}
+}
+
+// windowsName names the Windows release of an NTLMSSP version. Samba
+// reports a fixed version too, so this is a hint, not an identification.
+func windowsName(major, minor byte) string {
+	switch fmt.Sprintf("%d.%d", major, minor) {
+	case "5.0":
+		return "Windows 2000"
+	case "5.1":
+		return "Windows XP"
+	case "5.2":
+		return "Windows XP x64 / Server 2003"
+	case "6.0":
+		return "Windows Vista / Server 2008"
+	case "6.1":
+		return "Windows 7 / Server 2008 R2"
+	case "6.2":
+		return "Windows 8 / Server 2012"
+	case "6.3":
+		return "Windows 8.1 / Server 2012 R2"
+	case "10.0":
+		return "Windows 10 / Server 2016 or later"
+	}
+	return fmt.Sprintf("Windows %d.%d", major, minor)
+}
@@ -0,0 +1,248 @@
+package smb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/testutil"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// The testdata replies follow the layout of a Windows Server 2019 host
+// (SMB2) and a Windows Server 2003 host (SMB1): SPNEGO wrapping, NTLMSSP
+// CHALLENGE with target info AV pairs and version 10.0.17763, and the SMB1
+// native OS strings. Their FILETIMEs are all 2024-01-01 00:00:00 UTC
+
+var fixtureTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const fixtureGUID = "12345678-9abc-def0-1122-334455667788"
+
+func TestParseNegotiate(t *testing.T) {
+	tests := []struct {
+		file string
+		want negotiation
+	}{
+		{
+			file: "negotiate-smb311.bin",
+			want: negotiation{dialect: 0x0311, securityMode: smb2SigningEnabled, capabilities: 0x2f, cipher: 0x0002},
+		},
+		{
+			file: "negotiate-smb21.bin",
+			want: negotiation{dialect: 0x0210, securityMode: smb2SigningEnabled | smb2SigningRequired, capabilities: 0x07},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got, err := parseNegotiate(testutil.Fixture(t, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := guidString(got.guid); s != fixtureGUID {
+				t.Errorf("GUID %s, want %s", s, fixtureGUID)
+			}
+			if ts := filetime(got.systemTime); !ts.Equal(fixtureTime) {
+				t.Errorf("system time %v, want %v", ts, fixtureTime)
+			}
+			got.guid, got.systemTime = [16]byte{}, 0
+			if *got != tt.want {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+
+	reply := testutil.Fixture(t, "negotiate-smb21.bin")
+	for _, bad := range [][]byte{reply[:smb2HeaderLen+40], append(reply[:smb2HeaderLen:smb2HeaderLen], 9, 0)} {
+		if _, err := parseNegotiate(bad); err == nil {
+			t.Errorf("parsing a %d byte reply succeeded", len(bad))
+		}
+	}
+}
+
+func TestSessionSetup(t *testing.T) {
+	tr := replay(t, testutil.Fixture(t, "session-setup-smb2.bin"))
+	c, err := tr.sessionSetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &challenge{
+		targetInfo: map[uint16]string{
+			avNetBIOSComputerName: "FILESRV01",
+			avNetBIOSDomainName:   "CORP",
+			avDNSComputerName:     "filesrv01.corp.example.com",
+			avDNSDomainName:       "corp.example.com",
+			avDNSTreeName:         "example.com",
+		},
+		major: 10, minor: 0, build: 17763, hasVersion: true,
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("got %+v\nwant %+v", c, want)
+	}
+}
+
+func TestSessionSetupSMB1(t *testing.T) {
+	tr := replay(t, testutil.Fixture(t, "session-setup-smb1.bin"))
+	session, err := tr.sessionSetupSMB1()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.nativeOS != "Windows Server 2003 3790 Service Pack 2" || session.nativeLanMan != "Windows Server 2003 5.2" {
+		t.Errorf("native OS %q, LAN manager %q", session.nativeOS, session.nativeLanMan)
+	}
+	if session.challenge.targetInfo[avDNSComputerName] != "filesrv01.corp.example.com" {
+		t.Errorf("challenge target info %v", session.challenge.targetInfo)
+	}
+}
+
+func TestNegotiateSMB1(t *testing.T) {
+	n, err := replay(t, testutil.Fixture(t, "negotiate-smb1.bin")).negotiateSMB1()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := smb1Negotiation{securityMode: 0x07, flags2: 0xc853, capabilities: 0x8000f3fd}
+	if *n != want {
+		t.Errorf("got %+v, want %+v", *n, want)
+	}
+}
+
+func TestParseChallengeMalformed(t *testing.T) {
+	reply := testutil.Fixture(t, "session-setup-smb2.bin")
+	start := bytes.Index(reply, ntlmSignature)
+
+	tests := []struct {
+		name string
+		blob []byte
+		want string
+	}{
+		{"no NTLMSSP", reply[:start], "no NTLMSSP challenge"},
+		{"truncated", reply[:start+40], "malformed NTLMSSP challenge"},
+		{"negotiate message", ntlmNegotiateMessage(), "malformed NTLMSSP challenge"},
+	}
+	for _, tt := range tests {
+		if _, err := parseChallenge(tt.blob); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	// Target info that runs past the message is dropped, not an error
+	msg := append([]byte(nil), reply[start:]...)
+	binary.LittleEndian.PutUint32(msg[44:], uint32(len(msg)))
+	c, err := parseChallenge(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.targetInfo) != 0 || c.build != 17763 {
+		t.Errorf("got %+v", c)
+	}
+}
+
+func TestNetbiosName(t *testing.T) {
+	// The encoding of *SMBSERVER<20> from RFC 1001 implementations
+	want := append(append([]byte{32}, "CKFDENECFDEFFCFGEFFCCACACACACACA"...), 0)
+	if got := netbiosName("*SMBSERVER", 0x20); !bytes.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := netbiosName("averyveryverylongname", 0); len(got) != 34 {
+		t.Errorf("long name encoded to %d bytes, want 34", len(got))
+	}
+}
+
+func TestScan(t *testing.T) {
+	for _, port := range []int{445, 139} {
+		address := fakeServer(t, port == 139, nil)
+		info := Scan(address, port, time.Second)
+
+		systemTime := fixtureTime
+		want := &models.SMBInfo{
+			Dialects:            []string{"3.1.1"},
+			Dialect:             "3.1.1",
+			SMB1:                true,
+			SigningEnabled:      true,
+			ServerGUID:          fixtureGUID,
+			Capabilities:        []string{"DFS", "LEASING", "LARGE_MTU", "MULTI_CHANNEL", "DIRECTORY_LEASING"},
+			Cipher:              "AES-128-GCM",
+			SystemTime:          &systemTime,
+			OS:                  "Windows 10 / Server 2016 or later",
+			OSVersion:           "10.0.17763",
+			NativeOS:            "Windows Server 2003 3790 Service Pack 2",
+			NativeLanManager:    "Windows Server 2003 5.2",
+			NetBIOSComputerName: "FILESRV01",
+			NetBIOSDomainName:   "CORP",
+			DNSComputerName:     "filesrv01.corp.example.com",
+			DNSDomainName:       "corp.example.com",
+			DNSTreeName:         "example.com",
+		}
+		if !reflect.DeepEqual(info, want) {
+			t.Errorf("port %d:\n got %+v\nwant %+v", port, info, want)
+		}
+	}
+}
+
+func TestScanNotSMB(t *testing.T) {
+	address := fakeServer(t, false, []byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+	info := Scan(address, 445, time.Second)
+	if info.Error != "not an SMB server" || info.SMB1 || info.Dialect != "" {
+		t.Errorf("got %+v", info)
+	}
+}
+
+// replay returns a transport whose peer answers one request with reply
+func replay(t *testing.T, reply []byte) *transport {
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+	go func() {
+		defer server.Close()
+		peer := &transport{conn: server}
+		if _, err := peer.receive(); err == nil {
+			peer.send(reply)
+		}
+	}()
+	client.SetDeadline(time.Now().Add(2 * time.Second))
+	return &transport{conn: client}
+}
+
+// fakeServer answers each request with the fixture for its command. The
+// SMB2 NEGOTIATE reply always picks 3.1.1, so probes of lower dialects
+// fail. With raw set, it writes raw instead and hangs up.
+func fakeServer(t *testing.T, netbios bool, raw []byte) string {
+	replies := map[string][]byte{
+		"\xfeSMB\x00": testutil.Fixture(t, "negotiate-smb311.bin"),
+		"\xfeSMB\x01": testutil.Fixture(t, "session-setup-smb2.bin"),
+		"\xffSMB\x72": testutil.Fixture(t, "negotiate-smb1.bin"),
+		"\xffSMB\x73": testutil.Fixture(t, "session-setup-smb1.bin"),
+	}
+	return testutil.Serve(t, func(conn net.Conn) {
+		if raw != nil {
+			conn.Write(raw)
+			return
+		}
+		if netbios {
+			header := make([]byte, 4)
+			if _, err := io.ReadFull(conn, header); err != nil || header[0] != nbSessionRequest {
+				return
+			}
+			io.CopyN(io.Discard, conn, int64(header[3]))
+			conn.Write([]byte{nbPositiveSession, 0, 0, 0})
+		}
+		peer := &transport{conn: conn}
+		for {
+			msg, err := peer.receive()
+			if err != nil || len(msg) < 16 {
+				return
+			}
+			command := msg[4:5]
+			if msg[0] == 0xfe {
+				command = msg[12:13]
+			}
+			reply, ok := replies[string(msg[:4])+string(command)]
+			if !ok {
+				return
+			}
+			peer.send(reply)
+		}
+	})
+}
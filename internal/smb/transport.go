@@ -0,0 +1,129 @@
+package smb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// maxMessage keeps a misbehaving server from making the scan buffer
+// arbitrarily large replies
+const maxMessage = 1 << 20
+
+// NetBIOS session service packet types (RFC 1002)
+const (
+	nbSessionMessage  = 0x00
+	nbSessionRequest  = 0x81
+	nbPositiveSession = 0x82
+	nbNegativeSession = 0x83
+	nbKeepAlive       = 0x85
+)
+
+// transport carries SMB messages over a connection. Direct TCP (445) and
+// NetBIOS sessions (139) frame messages the same way once a NetBIOS
+// session is set up.
+type transport struct {
+	conn net.Conn
+}
+
+// dial connects and, for port 139, sets up a NetBIOS session. timeout
+// bounds the whole connection.
+func dial(address string, port int, timeout time.Duration) (*transport, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	t := &transport{conn: conn}
+	if port == 139 {
+		if err := t.sessionRequest(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("netbios session: %v", err)
+		}
+	}
+	return t, nil
+}
+
+// sessionRequest asks for a NetBIOS session with *SMBSERVER, the name
+// every SMB server answers to
+func (t *transport) sessionRequest() error {
+	payload := append(netbiosName("*SMBSERVER", 0x20), netbiosName("GOSCAN", 0x00)...)
+	packet := append([]byte{nbSessionRequest, 0, 0, byte(len(payload))}, payload...)
+	if _, err := t.conn.Write(packet); err != nil {
+		return err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(t.conn, header); err != nil {
+		return err
+	}
+	length := int(header[1]&1)<<16 | int(binary.BigEndian.Uint16(header[2:]))
+	if _, err := io.CopyN(io.Discard, t.conn, int64(length)); err != nil {
+		return err
+	}
+	switch header[0] {
+	case nbPositiveSession:
+		return nil
+	case nbNegativeSession:
+		return fmt.Errorf("session refused")
+	}
+	return fmt.Errorf("unexpected packet type 0x%02x", header[0])
+}
+
+// netbiosName encodes a NetBIOS name (RFC 1001 first-level encoding):
+// padded to 15 characters plus a suffix byte, each nibble as a letter
+func netbiosName(name string, suffix byte) []byte {
+	padded := []byte(strings.ToUpper(name))
+	for len(padded) < 15 {
+		padded = append(padded, ' ')
+	}
+	padded = append(padded[:15], suffix)
+
+	encoded := []byte{32}
+	for _, c := range padded {
+		encoded = append(encoded, 'A'+c>>4, 'A'+c&0x0f)
+	}
+	return append(encoded, 0)
+}
+
+// send writes one SMB message with its 4-byte length prefix
+func (t *transport) send(msg []byte) error {
+	frame := make([]byte, 4, 4+len(msg))
+	binary.BigEndian.PutUint32(frame, uint32(len(msg)))
+	_, err := t.conn.Write(append(frame, msg...))
+	return err
+}
+
+// receive reads the next SMB message, skipping NetBIOS keep-alives
+func (t *transport) receive() ([]byte, error) {
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(t.conn, header); err != nil {
+			return nil, err
+		}
+		if header[0] != nbSessionMessage && header[0] != nbKeepAlive {
+			return nil, fmt.Errorf("not an SMB server")
+		}
+		length := binary.BigEndian.Uint32(header) & 0x00ffffff
+		if length > maxMessage {
+			return nil, fmt.Errorf("message too large: %d bytes", length)
+		}
+		msg := make([]byte, length)
+		if _, err := io.ReadFull(t.conn, msg); err != nil {
+			return nil, err
+		}
+		if header[0] == nbKeepAlive {
+			continue
+		}
+		return msg, nil
+	}
+}
+
+// close ends the connection
+func (t *transport) close() {
+	t.conn.Close()
+}
@@ -0,0 +1,116 @@
+package ssh
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// Weak algorithms and the severity of offering them
+var (
+	weakKex = map[string]string{
+		"diffie-hellman-group1-sha1":         models.SeverityHigh,
+		"rsa1024-sha1":                       models.SeverityHigh,
+		"diffie-hellman-group-exchange-sha1": models.SeverityMedium,
+		"diffie-hellman-group14-sha1":        models.SeverityLow,
+	}
+	weakHostKey = map[string]string{
+		"ssh-dss": models.SeverityHigh,
+		"ssh-rsa": models.SeverityLow, // SHA-1 signatures
+	}
+	weakCiphers = map[string]string{
+		"none":                        models.SeverityCritical,
+		"des-cbc":                     models.SeverityHigh,
+		"arcfour":                     models.SeverityHigh,
+		"arcfour128":                  models.SeverityHigh,
+		"arcfour256":                  models.SeverityHigh,
+		"3des-cbc":                    models.SeverityMedium,
+		"blowfish-cbc":                models.SeverityMedium,
+		"cast128-cbc":                 models.SeverityMedium,
+		"aes128-cbc":                  models.SeverityLow,
+		"aes192-cbc":                  models.SeverityLow,
+		"aes256-cbc":                  models.SeverityLow,
+		"rijndael-cbc@lysator.liu.se": models.SeverityLow,
+	}
+	weakMACs = map[string]string{
+		"none":                         models.SeverityCritical,
+		"hmac-md5":                     models.SeverityMedium,
+		"hmac-md5-96":                  models.SeverityMedium,
+		"hmac-md5-etm@openssh.com":     models.SeverityMedium,
+		"hmac-md5-96-etm@openssh.com":  models.SeverityMedium,
+		"hmac-sha1-96":                 models.SeverityMedium,
+		"hmac-sha1-96-etm@openssh.com": models.SeverityMedium,
+		"umac-64@openssh.com":          models.SeverityLow,
+		"umac-64-etm@openssh.com":      models.SeverityLow,
+		"hmac-sha1":                    models.SeverityLow,
+		"hmac-sha1-etm@openssh.com":    models.SeverityLow,
+	}
+)
+
+// Minimum acceptable RSA and DSA host key size, in bits
+const minKeyBits = 2048
+
+// Findings flags protocol 1, weak key exchanges, host key algorithms,
+// ciphers and MACs, and small host keys. Each category is one finding
+// at the severity of its worst algorithm.
+func Findings(info *models.SSHInfo) []models.Finding {
+	if info == nil {
+		return nil
+	}
+	var findings []models.Finding
+
+	if strings.HasPrefix(info.ProtocolVersion, "1.") {
+		findings = append(findings, models.Finding{
+			ID:       "ssh-protocol-1",
+			Title:    "SSH protocol 1 supported",
+			Severity: models.SeverityHigh,
+			Detail:   "the server identifies as " + info.VersionString,
+		})
+	}
+
+	add := func(id, title string, weak map[string]string, lists ...[]string) {
+		var names []string
+		severity := ""
+		for _, list := range lists {
+			for _, name := range list {
+				level, ok := weak[name]
+				if !ok || contains(names, name) {
+					continue
+				}
+				names = append(names, name)
+				if models.SeverityRank(level) > models.SeverityRank(severity) {
+					severity = level
+				}
+			}
+		}
+		if len(names) > 0 {
+			findings = append(findings, models.Finding{
+				ID: id, Title: title, Severity: severity,
+				Detail: "offered: " + strings.Join(names, ", "),
+			})
+		}
+	}
+	add("ssh-weak-kex", "Weak SSH key exchange", weakKex, info.KexAlgorithms)
+	add("ssh-weak-hostkey-algorithm", "Weak SSH host key algorithm", weakHostKey, info.HostKeyAlgorithms)
+	add("ssh-weak-cipher", "Weak SSH cipher", weakCiphers, info.CiphersClientToServer, info.CiphersServerToClient)
+	add("ssh-weak-mac", "Weak SSH MAC", weakMACs, info.MACsClientToServer, info.MACsServerToClient)
+
+	for _, key := range info.HostKeys {
+		if (key.Type != "ssh-rsa" && key.Type != "ssh-dss") || key.Bits == 0 || key.Bits >= minKeyBits {
+			continue
+		}
+		severity := models.SeverityHigh
+		if key.Bits < 1024 {
+			severity = models.SeverityCritical
+		}
+		findings = append(findings, models.Finding{
+			ID:       "ssh-weak-key",
+			Title:    "Weak SSH host key",
+			Severity: severity,
+			Detail:   fmt.Sprintf("%d-bit %s key is below the %d-bit minimum", key.Bits, key.Type, minKeyBits),
+		})
+	}
+
+	return findings
+}
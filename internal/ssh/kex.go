@@ -0,0 +1,94 @@
+package ssh
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+// group14 is the 2048-bit MODP group of RFC 3526, used by
+// diffie-hellman-group14-sha1 and -sha256
+var group14, _ = new(big.Int).SetString(
+	"FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74"+
+		"020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B302B0A6DF25F1437"+
+		"4FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED"+
+		"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3DC2007CB8A163BF05"+
+		"98DA48361C55D39A69163FA8FD24CF5F83655D23DCA3AD961C62F356208552BB"+
+		"9ED529077096966D670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B"+
+		"E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9DE2BCBF695581718"+
+		"3995497CEA956AE515D2261898FA051015728E5A8AACAA68FFFFFFFFFFFFFFFF", 16)
+
+// kexAlgorithms are the key exchanges used to fetch host keys, most
+// preferred first. Only the server's reply is needed, so no shared secret
+// is derived.
+var kexAlgorithms = map[string]func() ([]byte, error){
+	"curve25519-sha256":             ecdhInit(ecdh.X25519()),
+	"curve25519-sha256@libssh.org":  ecdhInit(ecdh.X25519()),
+	"ecdh-sha2-nistp256":            ecdhInit(ecdh.P256()),
+	"ecdh-sha2-nistp384":            ecdhInit(ecdh.P384()),
+	"ecdh-sha2-nistp521":            ecdhInit(ecdh.P521()),
+	"diffie-hellman-group14-sha256": dhInit,
+	"diffie-hellman-group14-sha1":   dhInit,
+}
+
+// kexPreference orders kexAlgorithms
+var kexPreference = []string{
+	"curve25519-sha256", "curve25519-sha256@libssh.org",
+	"ecdh-sha2-nistp256", "ecdh-sha2-nistp384", "ecdh-sha2-nistp521",
+	"diffie-hellman-group14-sha256", "diffie-hellman-group14-sha1",
+}
+
+// chooseKex picks the first preferred key exchange the server offers
+func chooseKex(offered []string) string {
+	for _, name := range kexPreference {
+		if contains(offered, name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// ecdhInit builds a KEX_ECDH_INIT carrying an ephemeral public key
+func ecdhInit(curve ecdh.Curve) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		key, err := curve.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return appendString([]byte{msgKexInitDH}, key.PublicKey().Bytes()), nil
+	}
+}
+
+// dhInit builds a KEXDH_INIT for group 14
+func dhInit() ([]byte, error) {
+	x, err := rand.Int(rand.Reader, new(big.Int).Sub(group14, big.NewInt(2)))
+	if err != nil {
+		return nil, err
+	}
+	x.Add(x, big.NewInt(1))
+	e := new(big.Int).Exp(big.NewInt(2), x, group14)
+	return appendString([]byte{msgKexInitDH}, mpint(e)), nil
+}
+
+// mpint encodes a positive integer as an SSH mpint body
+func mpint(n *big.Int) []byte {
+	b := n.Bytes()
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return b
+}
+
+// parseKexReply returns the host key blob of a KEXDH or KEX_ECDH reply
+func parseKexReply(payload []byte) ([]byte, error) {
+	if payload[0] != msgKexReplyDH {
+		return nil, fmt.Errorf("expected key exchange reply, got message %d", payload[0])
+	}
+	r := &reader{data: payload[1:]}
+	blob := r.bytes(int(r.uint32()))
+	if r.err != nil || len(blob) == 0 {
+		return nil, fmt.Errorf("short key exchange reply")
+	}
+	return blob, nil
+}
@@ -0,0 +1,214 @@
+// Package ssh collects SSH server facts natively: the version string, the
+// algorithms offered in KEXINIT and the host keys, taken from key exchange
+// replies without authenticating
+package ssh
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// Scan connects to the SSH server at address, records its version and
+// KEXINIT algorithm lists, then fetches one host key of every type it
+// offers, each with its own key exchange. timeout bounds each connection.
+func Scan(address string, timeout time.Duration) *models.SSHInfo {
+	info := &models.SSHInfo{}
+
+	t, version, server, err := connect(address, timeout)
+	if version != "" {
+		info.VersionString = version
+		info.ProtocolVersion, info.Software, info.Comments = ParseVersion(version)
+	}
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+
+	info.KexAlgorithms = server.kex
+	info.HostKeyAlgorithms = server.hostKey
+	info.CiphersClientToServer = server.ciphersClient
+	info.CiphersServerToClient = server.ciphersServer
+	info.MACsClientToServer = server.macsClient
+	info.MACsServerToClient = server.macsServer
+	info.CompressionClientToServer = server.compressClient
+	info.CompressionServerToClient = server.compressServer
+
+	keyTypes := hostKeyTypes(server.hostKey)
+	if len(keyTypes) == 0 {
+		t.conn.Close()
+		return info
+	}
+
+	// The first key reuses the connection that read KEXINIT
+	for i, algorithms := range keyTypes {
+		if i > 0 {
+			if t, _, server, err = connect(address, timeout); err != nil {
+				info.Error = err.Error()
+				break
+			}
+		}
+
+		blob, err := t.hostKey(server, algorithms)
+		t.conn.Close()
+		if err != nil {
+			if info.Error == "" {
+				info.Error = fmt.Sprintf("%s host key: %v", algorithms[0], err)
+			}
+			continue
+		}
+		info.HostKeys = append(info.HostKeys, hostKeyInfo(blob))
+	}
+
+	return info
+}
+
+// ParseVersion splits an identification string such as
+// "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13" into protocol version, software
+// version and comments
+func ParseVersion(version string) (protocol, software, comments string) {
+	rest := strings.TrimPrefix(version, "SSH-")
+	protocol, rest, _ = strings.Cut(rest, "-")
+	software, comments, _ = strings.Cut(rest, " ")
+	return protocol, software, comments
+}
+
+// connect opens a connection bounded by timeout, exchanges versions and
+// reads the server's KEXINIT. The connection is closed on error.
+func connect(address string, timeout time.Duration) (*transport, string, *kexInit, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	t := &transport{conn: conn, r: bufio.NewReader(conn)}
+
+	version, err := t.exchangeVersions()
+	if err != nil {
+		conn.Close()
+		return nil, "", nil, err
+	}
+	if !strings.HasPrefix(version, "SSH-2.0-") && !strings.HasPrefix(version, "SSH-1.99-") {
+		conn.Close()
+		return nil, version, nil, fmt.Errorf("unsupported protocol version")
+	}
+
+	payload, err := t.readPacket()
+	if err == nil {
+		var server *kexInit
+		if server, err = parseKexInit(payload); err == nil {
+			return t, version, server, nil
+		}
+	}
+	conn.Close()
+	return nil, version, nil, err
+}
+
+// hostKey runs a key exchange that only allows the given host key
+// algorithms and returns the host key blob from the server's reply. The
+// other lists echo the server's own so negotiation cannot fail on them.
+func (t *transport) hostKey(server *kexInit, algorithms []string) ([]byte, error) {
+	kex := chooseKex(server.kex)
+	if kex == "" {
+		return nil, fmt.Errorf("no supported key exchange among %s", strings.Join(server.kex, ","))
+	}
+
+	client := *server
+	client.kex = []string{kex}
+	client.hostKey = algorithms
+	if err := t.writePacket(client.marshal()); err != nil {
+		return nil, err
+	}
+
+	init, err := kexAlgorithms[kex]()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.writePacket(init); err != nil {
+		return nil, err
+	}
+
+	payload, err := t.readPacket()
+	if err != nil {
+		return nil, err
+	}
+	return parseKexReply(payload)
+}
+
+// hostKeyTypes groups offered host key algorithms by key type, in the
+// server's order. RSA keys sign with several algorithms, so those share a
+// group; certificate algorithms are skipped.
+func hostKeyTypes(offered []string) [][]string {
+	var groups [][]string
+	index := make(map[string]int)
+	for _, algorithm := range offered {
+		if strings.Contains(algorithm, "-cert-") {
+			continue
+		}
+		keyType := algorithm
+		if algorithm == "rsa-sha2-256" || algorithm == "rsa-sha2-512" {
+			keyType = "ssh-rsa"
+		}
+		if i, ok := index[keyType]; ok {
+			groups[i] = append(groups[i], algorithm)
+			continue
+		}
+		index[keyType] = len(groups)
+		groups = append(groups, []string{algorithm})
+	}
+	return groups
+}
+
+// hostKeyInfo describes a host key blob with OpenSSH-style fingerprints
+func hostKeyInfo(blob []byte) models.SSHHostKey {
+	sha := sha256.Sum256(blob)
+	sum := md5.Sum(blob)
+	md5Hex := make([]string, len(sum))
+	for i, b := range sum {
+		md5Hex[i] = fmt.Sprintf("%02x", b)
+	}
+
+	r := &reader{data: blob}
+	key := models.SSHHostKey{
+		Type:              r.string(),
+		FingerprintSHA256: "SHA256:" + base64.RawStdEncoding.EncodeToString(sha[:]),
+		FingerprintMD5:    "MD5:" + strings.Join(md5Hex, ":"),
+	}
+
+	switch key.Type {
+	case "ssh-rsa":
+		r.bytes(int(r.uint32())) // e
+		key.Bits = new(big.Int).SetBytes(r.bytes(int(r.uint32()))).BitLen()
+	case "ssh-dss":
+		key.Bits = new(big.Int).SetBytes(r.bytes(int(r.uint32()))).BitLen()
+	case "ecdsa-sha2-nistp256":
+		key.Bits = 256
+	case "ecdsa-sha2-nistp384":
+		key.Bits = 384
+	case "ecdsa-sha2-nistp521":
+		key.Bits = 521
+	case "ssh-ed25519":
+		key.Bits = 256
+	case "ssh-ed448":
+		key.Bits = 456
+	}
+	return key
+}
+
+// contains reports whether list holds s
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
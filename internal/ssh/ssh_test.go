@@ -0,0 +1,224 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/testutil"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// The testdata replies are canned OpenSSH 9.6 server messages. The host
+// keys in the kexreply-*.bin key exchange replies were made by ssh-keygen,
+// and the expected fingerprints are what "ssh-keygen -l" prints for them
+
+var wantHostKeys = map[string]models.SSHHostKey{
+	"rsa": {
+		Type: "ssh-rsa", Bits: 3072,
+		FingerprintSHA256: "SHA256:/I+0DTY5vihdwTZc+GP75kmCczpf6mX+btxn/HlVgwI",
+		FingerprintMD5:    "MD5:ca:24:90:ec:aa:82:f5:48:58:8c:c7:a8:ac:12:61:2f",
+	},
+	"ecdsa": {
+		Type: "ecdsa-sha2-nistp256", Bits: 256,
+		FingerprintSHA256: "SHA256:5W3+phYAV9I1ExQf4OZgZOitGVowqFwwRO0wJP5hzzM",
+		FingerprintMD5:    "MD5:d5:7d:61:cf:0e:9a:33:9a:72:c7:2a:db:f7:fd:35:30",
+	},
+	"ed25519": {
+		Type: "ssh-ed25519", Bits: 256,
+		FingerprintSHA256: "SHA256:h/EnO1dGGUnLh6css0NgJ2XSqgu55EQNzVReZB3p1Qg",
+		FingerprintMD5:    "MD5:54:18:66:a6:90:44:a6:82:32:05:7b:36:fc:32:6f:c8",
+	},
+}
+
+func TestScan(t *testing.T) {
+	for _, greeting := range []string{"openssh-kexinit.bin", "preamble-kexinit.bin"} {
+		t.Run(greeting, func(t *testing.T) {
+			info := Scan(fakeServer(t, testutil.Fixture(t, greeting)), time.Second)
+			if info.Error != "" {
+				t.Fatal(info.Error)
+			}
+
+			if info.VersionString != "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13.5" || info.ProtocolVersion != "2.0" ||
+				info.Software != "OpenSSH_9.6p1" || info.Comments != "Ubuntu-3ubuntu13.5" {
+				t.Errorf("version: %q, %q, %q, %q", info.VersionString, info.ProtocolVersion, info.Software, info.Comments)
+			}
+			if len(info.KexAlgorithms) != 11 || info.KexAlgorithms[1] != "curve25519-sha256" {
+				t.Errorf("kex algorithms: %v", info.KexAlgorithms)
+			}
+			if want := []string{"rsa-sha2-512", "rsa-sha2-256", "ecdsa-sha2-nistp256", "ssh-ed25519"}; !reflect.DeepEqual(info.HostKeyAlgorithms, want) {
+				t.Errorf("host key algorithms: %v", info.HostKeyAlgorithms)
+			}
+			if len(info.CiphersClientToServer) != 6 || len(info.MACsServerToClient) != 10 {
+				t.Errorf("ciphers %v, macs %v", info.CiphersClientToServer, info.MACsServerToClient)
+			}
+			if want := []string{"none", "zlib@openssh.com"}; !reflect.DeepEqual(info.CompressionServerToClient, want) {
+				t.Errorf("compression: %v", info.CompressionServerToClient)
+			}
+
+			want := []models.SSHHostKey{wantHostKeys["rsa"], wantHostKeys["ecdsa"], wantHostKeys["ed25519"]}
+			if !reflect.DeepEqual(info.HostKeys, want) {
+				t.Errorf("host keys:\n got %+v\nwant %+v", info.HostKeys, want)
+			}
+		})
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		greeting []byte
+		want     string
+	}{
+		{"disconnect", testutil.Fixture(t, "disconnect.bin"), "server disconnected: Protocol major versions differ."},
+		{"SSH-1", []byte("SSH-1.5-OldServer\r\n"), "unsupported protocol version"},
+		{"not SSH", []byte(strings.Repeat("HTTP/1.1 400 Bad Request\r\n", maxPreambleLines)), "no SSH version line"},
+		{"bad length", append([]byte("SSH-2.0-x\r\n"), 0xff, 0xff, 0xff, 0xff, 4), "bad packet length"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := Scan(fakeServer(t, tt.greeting), time.Second)
+			if !strings.Contains(info.Error, tt.want) {
+				t.Errorf("error %q, want one containing %q", info.Error, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version                      string
+		protocol, software, comments string
+	}{
+		{"SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13.5", "2.0", "OpenSSH_9.6p1", "Ubuntu-3ubuntu13.5"},
+		{"SSH-2.0-dropbear_2022.83", "2.0", "dropbear_2022.83", ""},
+		{"SSH-1.99-Cisco-1.25", "1.99", "Cisco-1.25", ""},
+		{"SSH-2.0-libssh_0.9.6 extra words", "2.0", "libssh_0.9.6", "extra words"},
+	}
+	for _, tt := range tests {
+		protocol, software, comments := ParseVersion(tt.version)
+		if protocol != tt.protocol || software != tt.software || comments != tt.comments {
+			t.Errorf("ParseVersion(%q) = %q, %q, %q", tt.version, protocol, software, comments)
+		}
+	}
+}
+
+func TestParseKexReply(t *testing.T) {
+	reply := testutil.Fixture(t, "kexreply-ed25519.bin")
+	payload, err := (&transport{r: bufio.NewReader(bytes.NewReader(reply))}).readPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, err := parseKexReply(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hostKeyInfo(blob); got != wantHostKeys["ed25519"] {
+		t.Errorf("got %+v, want %+v", got, wantHostKeys["ed25519"])
+	}
+
+	tests := []struct {
+		name    string
+		payload []byte
+		want    string
+	}{
+		{"wrong message", []byte{msgKexInit, 0, 0, 0, 0}, "expected key exchange reply, got message 20"},
+		{"truncated", payload[:20], "short key exchange reply"},
+	}
+	for _, tt := range tests {
+		if _, err := parseKexReply(tt.payload); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestKexInitRoundTrip(t *testing.T) {
+	in := &kexInit{
+		kex:            []string{"curve25519-sha256"},
+		hostKey:        []string{"rsa-sha2-512", "rsa-sha2-256"},
+		ciphersClient:  []string{"aes128-ctr"},
+		ciphersServer:  []string{"aes256-ctr"},
+		macsClient:     []string{"hmac-sha2-256"},
+		macsServer:     []string{"hmac-sha2-512"},
+		compressClient: []string{"none"},
+		compressServer: []string{"none"},
+	}
+
+	var buf bytes.Buffer
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		(&transport{conn: client}).writePacket(in.marshal())
+		client.Close()
+	}()
+	buf.ReadFrom(server)
+	if buf.Len()%8 != 0 {
+		t.Errorf("packet of %d bytes is not a multiple of 8", buf.Len())
+	}
+
+	payload, err := (&transport{r: bufio.NewReader(&buf)}).readPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := parseKexInit(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+
+	if _, err := parseKexInit(payload[:40]); err == nil {
+		t.Error("parsing a truncated KEXINIT succeeded")
+	}
+}
+
+func TestHostKeyTypes(t *testing.T) {
+	offered := []string{
+		"rsa-sha2-512", "rsa-sha2-256", "ecdsa-sha2-nistp256",
+		"ssh-ed25519-cert-v01@openssh.com", "ssh-ed25519", "ssh-rsa",
+	}
+	want := [][]string{
+		{"rsa-sha2-512", "rsa-sha2-256", "ssh-rsa"},
+		{"ecdsa-sha2-nistp256"},
+		{"ssh-ed25519"},
+	}
+	if got := hostKeyTypes(offered); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// fakeServer writes greeting on every connection. When the client gets as
+// far as a key exchange, it answers with the canned reply for the first
+// host key algorithm the client allows
+func fakeServer(t *testing.T, greeting []byte) string {
+	replies := map[string][]byte{
+		"rsa-sha2-512":        testutil.Fixture(t, "kexreply-rsa.bin"),
+		"ecdsa-sha2-nistp256": testutil.Fixture(t, "kexreply-ecdsa.bin"),
+		"ssh-ed25519":         testutil.Fixture(t, "kexreply-ed25519.bin"),
+	}
+	return testutil.Serve(t, func(conn net.Conn) {
+		conn.Write(greeting)
+
+		tr := &transport{conn: conn, r: bufio.NewReader(conn)}
+		if _, err := tr.r.ReadString('\n'); err != nil {
+			return
+		}
+		payload, err := tr.readPacket()
+		if err != nil {
+			return
+		}
+		client, err := parseKexInit(payload)
+		if err != nil || len(client.hostKey) == 0 {
+			return
+		}
+		if _, err := tr.readPacket(); err != nil { // KEX_ECDH_INIT
+			return
+		}
+		conn.Write(replies[client.hostKey[0]])
+	})
+}
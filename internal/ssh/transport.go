@@ -0,0 +1,196 @@
+package ssh
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// clientVersion is the identification string sent to servers
+const clientVersion = "SSH-2.0-go-scan"
+
+// Message numbers (RFC 4253, RFC 5656)
+const (
+	msgDisconnect = 1
+	msgIgnore     = 2
+	msgDebug      = 4
+	msgKexInit    = 20
+	msgKexInitDH  = 30 // KEXDH_INIT and KEX_ECDH_INIT
+	msgKexReplyDH = 31 // KEXDH_REPLY and KEX_ECDH_REPLY
+)
+
+// Limits that keep a misbehaving server from stalling the scan
+const (
+	maxPreambleLines = 20
+	maxPacket        = 256 << 10
+)
+
+// transport is the unencrypted start of an SSH connection, enough to
+// exchange versions and run a key exchange up to the server's reply
+type transport struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// exchangeVersions sends the client version and returns the server's,
+// skipping any lines a server sends before it
+func (t *transport) exchangeVersions() (string, error) {
+	if _, err := fmt.Fprintf(t.conn, "%s\r\n", clientVersion); err != nil {
+		return "", err
+	}
+	for i := 0; i < maxPreambleLines; i++ {
+		line, err := t.r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "SSH-") {
+			return line, nil
+		}
+	}
+	return "", fmt.Errorf("no SSH version line")
+}
+
+// readPacket returns the payload of the next packet that is not an ignore
+// or debug message. A disconnect becomes an error.
+func (t *transport) readPacket() ([]byte, error) {
+	for {
+		var header [5]byte
+		if _, err := io.ReadFull(t.r, header[:]); err != nil {
+			return nil, err
+		}
+		length := binary.BigEndian.Uint32(header[:4])
+		padding := uint32(header[4])
+		if length < padding+2 || length > maxPacket {
+			return nil, fmt.Errorf("bad packet length %d", length)
+		}
+
+		packet := make([]byte, length-1)
+		if _, err := io.ReadFull(t.r, packet); err != nil {
+			return nil, err
+		}
+		payload := packet[:len(packet)-int(padding)]
+
+		switch payload[0] {
+		case msgIgnore, msgDebug:
+			continue
+		case msgDisconnect:
+			r := &reader{data: payload[1:]}
+			r.uint32()
+			return nil, fmt.Errorf("server disconnected: %s", r.string())
+		}
+		return payload, nil
+	}
+}
+
+// writePacket sends a payload with random padding and no MAC
+func (t *transport) writePacket(payload []byte) error {
+	padding := 8 - (5+len(payload))%8
+	if padding < 4 {
+		padding += 8
+	}
+
+	packet := make([]byte, 5+len(payload)+padding)
+	binary.BigEndian.PutUint32(packet, uint32(1+len(payload)+padding))
+	packet[4] = byte(padding)
+	copy(packet[5:], payload)
+	rand.Read(packet[5+len(payload):])
+
+	_, err := t.conn.Write(packet)
+	return err
+}
+
+// kexInit is the algorithm negotiation message
+type kexInit struct {
+	kex, hostKey                   []string
+	ciphersClient, ciphersServer   []string
+	macsClient, macsServer         []string
+	compressClient, compressServer []string
+}
+
+// parseKexInit decodes a KEXINIT payload
+func parseKexInit(payload []byte) (*kexInit, error) {
+	if len(payload) < 17 || payload[0] != msgKexInit {
+		return nil, fmt.Errorf("expected KEXINIT, got message %d", payload[0])
+	}
+	r := &reader{data: payload[17:]} // skip the message number and cookie
+	k := &kexInit{
+		kex:            r.nameList(),
+		hostKey:        r.nameList(),
+		ciphersClient:  r.nameList(),
+		ciphersServer:  r.nameList(),
+		macsClient:     r.nameList(),
+		macsServer:     r.nameList(),
+		compressClient: r.nameList(),
+		compressServer: r.nameList(),
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("short KEXINIT")
+	}
+	return k, nil
+}
+
+// marshal encodes a KEXINIT with a random cookie and no languages
+func (k *kexInit) marshal() []byte {
+	b := []byte{msgKexInit}
+	cookie := make([]byte, 16)
+	rand.Read(cookie)
+	b = append(b, cookie...)
+	for _, list := range [][]string{
+		k.kex, k.hostKey,
+		k.ciphersClient, k.ciphersServer,
+		k.macsClient, k.macsServer,
+		k.compressClient, k.compressServer,
+		nil, nil,
+	} {
+		b = appendString(b, []byte(strings.Join(list, ",")))
+	}
+	b = append(b, 0)             // first_kex_packet_follows
+	return append(b, 0, 0, 0, 0) // reserved
+}
+
+// reader decodes SSH wire types; the first error sticks
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || n > len(r.data) {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) uint32() uint32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *reader) string() string {
+	return string(r.bytes(int(r.uint32())))
+}
+
+func (r *reader) nameList() []string {
+	s := r.string()
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// appendString appends an SSH string: a length and the bytes
+func appendString(b, s []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
@@ -1,41 +1,77 @@
 package ssl
 
 import (
-	"context"
 	"crypto/ecdsa"
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/hex"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/Sh4Ryuu/go-scan/pkg/models"
 )
 
-// GrabCertificate retrieves SSL/TLS certificate information
-func GrabCertificate(address string, timeout time.Duration) *models.SSLCertInfo {
-	dialer := &tls.Dialer{
-		Config: &tls.Config{
-			InsecureSkipVerify: true,
-		},
+// GrabCertificate retrieves SSL/TLS certificate information and validates
+// the presented chain against roots (nil means the system roots). The
+// serverName is sent as SNI and used for validation; when empty the host
+// part of address is used, and IP addresses send no SNI.
+func GrabCertificate(address, serverName string, timeout time.Duration, roots *x509.CertPool) *models.SSLCertInfo {
+	info, err := grabCertificate(address, serverName, timeout, roots)
+	if err != nil {
+		return nil
 	}
+	return info
+}
 
-	ctx, cancel := timeoutContext(timeout)
-	defer cancel()
+// GetCertificateInfo returns detailed certificate information, including
+// the negotiated TLS version, cipher suite and ALPN protocol
+func GetCertificateInfo(address string, timeout time.Duration) (*models.SSLCertInfo, error) {
+	return grabCertificate(address, "", timeout, nil)
+}
 
-	conn, err := dialer.DialContext(ctx, "tcp", address)
+// grabCertificate dials address and completes a handshake offering
+// ALPNProtocols, retrying without ALPN if the server rejects them all
+func grabCertificate(address, serverName string, timeout time.Duration, roots *x509.CertPool) (*models.SSLCertInfo, error) {
+	if serverName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+		serverName = host
+	}
+
+	state, err := dialHandshake(address, serverName, ALPNProtocols, timeout)
+	if ALPNRejected(err) {
+		state, err = dialHandshake(address, serverName, nil, timeout)
+	}
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	defer conn.Close()
 
-	tlsConn, ok := conn.(*tls.Conn)
-	if !ok {
-		return nil
+	info := CertInfoFromState(state, serverName, roots)
+	if info == nil {
+		return nil, fmt.Errorf("no certificate presented")
 	}
+	return info, nil
+}
 
-	certs := tlsConn.ConnectionState().PeerCertificates
+// dialHandshake dials address and runs a TLS handshake on the connection
+func dialHandshake(address, serverName string, alpn []string, timeout time.Duration) (tls.ConnectionState, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	defer conn.Close()
+	return Handshake(conn, serverName, alpn, timeout)
+}
+
+// CertInfoFromState builds certificate information from a completed
+// handshake, validating the chain for hostname
+func CertInfoFromState(state tls.ConnectionState, hostname string, roots *x509.CertPool) *models.SSLCertInfo {
+	certs := state.PeerCertificates
 	if len(certs) == 0 {
 		return nil
 	}
@@ -52,15 +88,35 @@ func GrabCertificate(address string, timeout time.Duration) *models.SSLCertInfo
 		IsExpired:          now.After(cert.NotAfter),
 		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
 		Fingerprint:        calculateFingerprint(cert.Raw),
+		PublicKeyHash:      calculateFingerprint(cert.RawSubjectPublicKeyInfo),
 		PublicKeyBits:      getKeySize(cert.PublicKey),
+		PublicKeyAlgorithm: cert.PublicKeyAlgorithm.String(),
+		SelfSigned:         isSelfSigned(cert),
+		TLSVersion:         VersionName(state.Version),
+		CipherSuite:        CipherSuiteName(state.CipherSuite),
+		ALPN:               state.NegotiatedProtocol,
+		Validation:         VerifyCertificateChain(certs, hostname, roots, now),
 	}
 
+	for _, c := range certs {
+		certInfo.Chain = append(certInfo.Chain, models.ChainCert{
+			Subject:     c.Subject.String(),
+			Issuer:      c.Issuer.String(),
+			Fingerprint: calculateFingerprint(c.Raw),
+			ValidTo:     c.NotAfter,
+			IsCA:        c.IsCA,
+			Raw:         c.Raw,
+		})
+	}
+
+	addRevocationInfo(certInfo, cert, state.OCSPResponse, state.SignedCertificateTimestamps)
+
 	return certInfo
 }
 
-// calculateFingerprint calculates SHA-256 fingerprint of certificate
-func calculateFingerprint(certDER []byte) string {
-	hash := sha256.Sum256(certDER)
+// calculateFingerprint calculates the SHA-256 fingerprint of DER data
+func calculateFingerprint(der []byte) string {
+	hash := sha256.Sum256(der)
 	return hex.EncodeToString(hash[:])
 }
 
@@ -75,70 +131,3 @@ func getKeySize(pubKey interface{}) int {
 		return 0
 	}
 }
-
-// VerifyCertificateChain verifies the certificate chain
-func VerifyCertificateChain(address string, timeout time.Duration) (bool, error) {
-	dialer := &tls.Dialer{
-		Config: &tls.Config{
-			InsecureSkipVerify: false,
-		},
-	}
-
-	ctx, cancel := timeoutContext(timeout)
-	defer cancel()
-
-	conn, err := dialer.DialContext(ctx, "tcp", address)
-	if err != nil {
-		return false, err
-	}
-	defer conn.Close()
-
-	return true, nil
-}
-
-// GetCertificateInfo returns detailed certificate information
-func GetCertificateInfo(address string, timeout time.Duration) (map[string]interface{}, error) {
-	dialer := &tls.Dialer{
-		Config: &tls.Config{
-			InsecureSkipVerify: true,
-		},
-	}
-
-	ctx, cancel := timeoutContext(timeout)
-	defer cancel()
-
-	conn, err := dialer.DialContext(ctx, "tcp", address)
-	if err != nil {
-		return nil, err
-	}
-	defer conn.Close()
-
-	tlsConn, ok := conn.(*tls.Conn)
-	if !ok {
-		return nil, fmt.Errorf("not a TLS connection")
-	}
-
-	state := tlsConn.ConnectionState()
-	certs := state.PeerCertificates
-
-	info := make(map[string]interface{})
-	info["protocol_version"] = state.Version
-	info["cipher_suite"] = state.CipherSuite
-	info["certificate_count"] = len(certs)
-
-	if len(certs) > 0 {
-		cert := certs[0]
-		info["subject"] = cert.Subject.String()
-		info["issuer"] = cert.Issuer.String()
-		info["valid_from"] = cert.NotBefore
-		info["valid_until"] = cert.NotAfter
-		info["dns_names"] = cert.DNSNames
-	}
-
-	return info, nil
-}
-
-// timeoutContext creates a context with timeout
-func timeoutContext(timeout time.Duration) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), timeout)
-}
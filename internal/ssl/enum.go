@@ -0,0 +1,300 @@
+package ssl
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// ProbeOptions controls the raw ClientHello probes used by Enumerate and JARM
+type ProbeOptions struct {
+	ServerName string        // SNI and STARTTLS host; IP addresses send no SNI
+	StartTLS   string        // STARTTLS protocol to negotiate before each hello
+	Timeout    time.Duration // per connection
+}
+
+// Enumerate tests which protocol versions, cipher suites and key exchange
+// groups a server accepts by sending raw ClientHellos, one per connection.
+// Cipher suites are listed in the server's preference order.
+func Enumerate(address string, opts ProbeOptions) (*models.TLSEnumeration, error) {
+	e := &enumerator{address: address, opts: opts}
+	enum := &models.TLSEnumeration{}
+
+	var accepted12 []uint16
+	for _, version := range []uint16{VersionSSL30, VersionTLS10, VersionTLS11, VersionTLS12} {
+		ciphers, preference := e.ciphers(version, legacySuites)
+		if len(ciphers) > 0 {
+			enum.Versions = append(enum.Versions, versionInfo(version, ciphers, preference))
+		}
+		if version == VersionTLS12 {
+			accepted12 = ciphers
+		}
+	}
+
+	tls13, preference := e.ciphers(VersionTLS13, tls13Suites)
+	if len(tls13) > 0 {
+		enum.Versions = append(enum.Versions, versionInfo(VersionTLS13, tls13, preference))
+	}
+
+	if len(enum.Versions) == 0 {
+		return nil, fmt.Errorf("%s: no TLS version accepted", address)
+	}
+
+	enum.Groups = e.groups(len(tls13) > 0, ecdheSuites(accepted12))
+	enum.Grade, enum.Warnings = Grade(enum)
+	return enum, nil
+}
+
+// enumerator sends ClientHellos to one address
+type enumerator struct {
+	address string
+	opts    ProbeOptions
+}
+
+// ciphers finds the suites accepted for a version by offering the
+// remaining suites until the server refuses, then checks whether the
+// server enforces its own order by offering the accepted suites reversed
+func (e *enumerator) ciphers(version uint16, offer []uint16) ([]uint16, bool) {
+	remaining := append([]uint16(nil), offer...)
+	var accepted []uint16
+
+	for len(remaining) > 0 {
+		sh, err := e.hello(&clientHello{
+			version:  version,
+			ciphers:  remaining,
+			groups:   defaultGroups,
+			keyShare: true,
+		})
+		if err != nil || sh.version != version || !containsID(remaining, sh.cipher) {
+			break
+		}
+		accepted = append(accepted, sh.cipher)
+		remaining = removeID(remaining, sh.cipher)
+	}
+
+	if len(accepted) < 2 {
+		return accepted, len(accepted) == 1
+	}
+
+	reversed := make([]uint16, len(accepted))
+	for i, id := range accepted {
+		reversed[len(accepted)-1-i] = id
+	}
+	sh, err := e.hello(&clientHello{version: version, ciphers: reversed, groups: defaultGroups, keyShare: true})
+	serverPreference := err == nil && sh.cipher == accepted[0]
+	return accepted, serverPreference
+}
+
+// groups finds the accepted key exchange groups. TLS 1.3 servers are asked
+// for each group with an empty key share, which they answer with a
+// HelloRetryRequest naming the group; TLS 1.2 servers are offered one curve
+// with ECDHE suites and the curve is read from the ServerKeyExchange.
+func (e *enumerator) groups(tls13 bool, ecdhe []uint16) []string {
+	var names []string
+	for _, g := range groupNames {
+		supported := false
+		if tls13 {
+			sh, err := e.hello(&clientHello{version: VersionTLS13, ciphers: tls13Suites, groups: []uint16{g.ID}})
+			supported = err == nil && sh.version == VersionTLS13 && sh.group == g.ID
+		}
+		if !supported && len(ecdhe) > 0 && isECGroup(g.ID) {
+			curve, err := e.keyExchange(&clientHello{version: VersionTLS12, ciphers: ecdhe, groups: []uint16{g.ID}})
+			supported = err == nil && curve == g.ID
+		}
+		if supported {
+			names = append(names, g.Name)
+		}
+	}
+	return names
+}
+
+// hello sends a ClientHello on a new connection and returns the ServerHello
+func (e *enumerator) hello(h *clientHello) (*serverHello, error) {
+	conn, hs, err := e.send(h)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	typ, body, err := hs.next()
+	if err != nil {
+		return nil, err
+	}
+	if typ != typeServerHello {
+		return nil, errNotTLS
+	}
+	return parseServerHello(body)
+}
+
+// keyExchange sends a TLS 1.2 ClientHello and returns the named curve from
+// the ServerKeyExchange, or 0 when the server sent none
+func (e *enumerator) keyExchange(h *clientHello) (uint16, error) {
+	conn, hs, err := e.send(h)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	for {
+		typ, body, err := hs.next()
+		if err != nil {
+			return 0, err
+		}
+		switch typ {
+		case typeServerKeyExchange:
+			// ECParameters: curve_type named_curve(3), then the curve ID
+			if len(body) < 3 || body[0] != 3 {
+				return 0, nil
+			}
+			return uint16(body[1])<<8 | uint16(body[2]), nil
+		case typeServerHelloDone:
+			return 0, nil
+		}
+	}
+}
+
+// send writes the hello on a new connection
+func (e *enumerator) send(h *clientHello) (net.Conn, *handshakeReader, error) {
+	h.serverName = e.opts.ServerName
+	return sendHello(e.address, e.opts, h.marshal())
+}
+
+// sendHello dials, negotiates STARTTLS if configured and writes a raw
+// ClientHello record
+func sendHello(address string, opts ProbeOptions, hello []byte) (net.Conn, *handshakeReader, error) {
+	conn, err := net.DialTimeout("tcp", address, opts.Timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn.SetDeadline(time.Now().Add(opts.Timeout))
+
+	r := bufio.NewReader(conn)
+	if opts.StartTLS != "" {
+		if err := StartTLS(conn, r, opts.StartTLS, opts.ServerName, ""); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+	}
+
+	if _, err := conn.Write(hello); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, &handshakeReader{r: r}, nil
+}
+
+// defaultGroups are offered when enumerating versions and ciphers
+var defaultGroups = []uint16{GroupX25519, GroupSecp256r1, GroupSecp384r1, GroupSecp521r1, GroupFFDHE2048}
+
+// Grade rates an enumeration from A to F and explains every deduction.
+// The worst finding sets the grade.
+func Grade(enum *models.TLSEnumeration) (string, []string) {
+	grade := "A"
+	var warnings []string
+	downgrade := func(g, warning string) {
+		if g > grade {
+			grade = g
+		}
+		warnings = append(warnings, warning)
+	}
+
+	versions := make(map[string]bool)
+	seen := make(map[string]bool)
+	var insecure, rc4, tripleDES []string
+	aead, fs := false, false
+
+	for _, v := range enum.Versions {
+		versions[v.Version] = true
+		for _, c := range v.Ciphers {
+			if isAEAD(c.Name) {
+				aead = true
+			}
+			if forwardSecret(c.Name) {
+				fs = true
+			}
+			if seen[c.Name] {
+				continue
+			}
+			seen[c.Name] = true
+			switch {
+			case c.Rating == RatingInsecure:
+				insecure = append(insecure, c.Name)
+			case strings.Contains(c.Name, "RC4"):
+				rc4 = append(rc4, c.Name)
+			case strings.Contains(c.Name, "3DES"):
+				tripleDES = append(tripleDES, c.Name)
+			}
+		}
+	}
+
+	if versions[VersionName(VersionSSL30)] {
+		downgrade("F", "SSLv3 is enabled (POODLE)")
+	}
+	if len(insecure) > 0 {
+		downgrade("F", "insecure cipher suites accepted: "+strings.Join(insecure, ", "))
+	}
+	if len(rc4) > 0 {
+		downgrade("D", "RC4 cipher suites accepted: "+strings.Join(rc4, ", "))
+	}
+	if len(tripleDES) > 0 {
+		downgrade("C", "3DES cipher suites accepted (Sweet32): "+strings.Join(tripleDES, ", "))
+	}
+	if !versions[VersionName(VersionTLS12)] && !versions[VersionName(VersionTLS13)] {
+		downgrade("C", "neither TLS 1.2 nor TLS 1.3 is supported")
+	}
+	if versions[VersionName(VersionTLS10)] || versions[VersionName(VersionTLS11)] {
+		downgrade("B", "legacy TLS 1.0/1.1 is enabled")
+	}
+	if !aead {
+		downgrade("B", "only CBC-mode cipher suites are accepted")
+	}
+	if !fs {
+		downgrade("B", "no cipher suite offers forward secrecy")
+	}
+
+	return grade, warnings
+}
+
+// versionInfo builds the model for one accepted version
+func versionInfo(version uint16, ciphers []uint16, serverPreference bool) models.TLSVersionInfo {
+	info := models.TLSVersionInfo{Version: VersionName(version), ServerPreference: serverPreference}
+	for _, id := range ciphers {
+		name := CipherSuiteName(id)
+		info.Ciphers = append(info.Ciphers, models.TLSCipher{ID: id, Name: name, Rating: CipherRating(name)})
+	}
+	return info
+}
+
+// ecdheSuites filters the ECDHE suites from a list
+func ecdheSuites(ids []uint16) []uint16 {
+	var ecdhe []uint16
+	for _, id := range ids {
+		if strings.Contains(CipherSuiteName(id), "_ECDHE_") {
+			ecdhe = append(ecdhe, id)
+		}
+	}
+	return ecdhe
+}
+
+func containsID(ids []uint16, id uint16) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func removeID(ids []uint16, id uint16) []uint16 {
+	out := ids[:0]
+	for _, v := range ids {
+		if v != id {
+			out = append(out, v)
+		}
+	}
+	return out
+}
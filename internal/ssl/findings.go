@@ -0,0 +1,147 @@
+package ssl
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// Expiry thresholds used by AnalyzeCertificate, in days
+const (
+	ExpiringSoonDays     = 30
+	ExpiringCriticalDays = 7
+	LongExpiredDays      = 30
+)
+
+// Minimum acceptable key sizes, in bits
+const (
+	minRSABits   = 2048
+	minECDSABits = 224
+)
+
+// AnalyzeCertificate evaluates a certificate for weaknesses: weak keys,
+// SHA-1 or MD5 signatures, self-signed, expired, expiring, not yet valid
+// and wildcard certificates, failed chain validation, and revoked, stale or
+// missing OCSP staples
+func AnalyzeCertificate(info *models.SSLCertInfo, now time.Time) []models.Finding {
+	if info == nil {
+		return nil
+	}
+	var findings []models.Finding
+	add := func(id, severity, title, detail string) {
+		findings = append(findings, models.Finding{ID: id, Title: title, Severity: severity, Detail: detail})
+	}
+
+	switch {
+	case info.PublicKeyAlgorithm == "RSA" && info.PublicKeyBits > 0 && info.PublicKeyBits < 1024:
+		add("tls-weak-key", models.SeverityCritical, "Weak RSA key",
+			fmt.Sprintf("%d-bit RSA key can be factored", info.PublicKeyBits))
+	case info.PublicKeyAlgorithm == "RSA" && info.PublicKeyBits > 0 && info.PublicKeyBits < minRSABits:
+		add("tls-weak-key", models.SeverityHigh, "Weak RSA key",
+			fmt.Sprintf("%d-bit RSA key is below the %d-bit minimum", info.PublicKeyBits, minRSABits))
+	case info.PublicKeyAlgorithm == "ECDSA" && info.PublicKeyBits > 0 && info.PublicKeyBits < minECDSABits:
+		add("tls-weak-key", models.SeverityHigh, "Weak ECDSA key",
+			fmt.Sprintf("%d-bit ECDSA key is below the %d-bit minimum", info.PublicKeyBits, minECDSABits))
+	}
+
+	// Self-signed certificates are trusted by key, so their signature
+	// algorithm does not matter
+	signature := strings.ToUpper(info.SignatureAlgorithm)
+	if !info.SelfSigned {
+		switch {
+		case strings.Contains(signature, "MD5"):
+			add("tls-weak-signature", models.SeverityHigh, "MD5 signature",
+				info.SignatureAlgorithm+" signatures can be forged")
+		case strings.Contains(signature, "SHA1"):
+			add("tls-weak-signature", models.SeverityMedium, "SHA-1 signature",
+				info.SignatureAlgorithm+" signatures are deprecated and collision-prone")
+		}
+	}
+
+	if info.SelfSigned {
+		add("tls-self-signed", models.SeverityMedium, "Self-signed certificate",
+			"the certificate is signed by its own key and cannot be validated by clients")
+	}
+
+	days := int(info.ValidTo.Sub(now).Hours() / 24)
+	switch {
+	case now.Before(info.ValidFrom):
+		add("tls-not-yet-valid", models.SeverityHigh, "Certificate not yet valid",
+			"valid from "+info.ValidFrom.UTC().Format(time.RFC3339))
+	case now.After(info.ValidTo) && -days >= LongExpiredDays:
+		add("tls-expired", models.SeverityHigh, "Certificate long expired",
+			fmt.Sprintf("expired %d days ago on %s", -days, info.ValidTo.Format("2006-01-02")))
+	case now.After(info.ValidTo):
+		add("tls-expired", models.SeverityHigh, "Certificate expired",
+			"expired on "+info.ValidTo.Format("2006-01-02"))
+	case days < ExpiringCriticalDays:
+		add("tls-expiring-soon", models.SeverityHigh, "Certificate expires within a week",
+			fmt.Sprintf("expires in %d days on %s", days, info.ValidTo.Format("2006-01-02")))
+	case days < ExpiringSoonDays:
+		add("tls-expiring-soon", models.SeverityMedium, "Certificate expires soon",
+			fmt.Sprintf("expires in %d days on %s", days, info.ValidTo.Format("2006-01-02")))
+	}
+
+	if wildcard := wildcardNames(info); len(wildcard) > 0 {
+		add("tls-wildcard", models.SeverityLow, "Wildcard certificate",
+			"covers "+strings.Join(wildcard, ", ")+"; a compromised key exposes every matching host")
+	}
+
+	// The leaf's own validity and self-signed roots are reported above
+	if v := info.Validation; v != nil && !v.Trusted {
+		switch {
+		case v.Verdict == models.CertVerdictHostnameMismatch:
+			add("tls-hostname-mismatch", models.SeverityMedium, "Certificate name mismatch", v.Reason)
+		case v.Verdict == models.CertVerdictExpired && info.IsExpired:
+		case v.Verdict == models.CertVerdictNotYetValid && now.Before(info.ValidFrom):
+		case v.Verdict == models.CertVerdictExpired || v.Verdict == models.CertVerdictNotYetValid:
+			add("tls-chain-expired", models.SeverityHigh, "Chain certificate outside its validity period", v.Reason)
+		case v.Verdict == models.CertVerdictUntrustedRoot && info.SelfSigned:
+		default:
+			add("tls-untrusted-chain", models.SeverityMedium, "Untrusted certificate chain", v.Verdict+": "+v.Reason)
+		}
+	}
+
+	if staple := info.OCSPStaple; staple != nil {
+		switch {
+		case staple.CertStatus == "revoked":
+			detail := "the stapled OCSP response reports the certificate revoked"
+			if staple.RevokedAt != nil {
+				detail += " on " + staple.RevokedAt.Format("2006-01-02")
+			}
+			if staple.RevocationReason != "" {
+				detail += " (" + staple.RevocationReason + ")"
+			}
+			add("tls-revoked", models.SeverityCritical, "Certificate revoked", detail)
+		case staple.NextUpdate != nil && now.After(*staple.NextUpdate):
+			add("tls-stale-ocsp", models.SeverityLow, "Stale OCSP staple",
+				"the stapled response expired on "+staple.NextUpdate.UTC().Format(time.RFC3339))
+		}
+	}
+	if info.MustStaple && !info.OCSPStapled {
+		add("tls-must-staple-missing", models.SeverityMedium, "OCSP Must-Staple not honoured",
+			"the certificate requires a stapled OCSP response but the server sent none")
+	}
+
+	return findings
+}
+
+// wildcardNames returns the wildcard names a certificate covers
+func wildcardNames(info *models.SSLCertInfo) []string {
+	var names []string
+	for _, name := range info.DNSNames {
+		if strings.HasPrefix(name, "*.") {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 && strings.Contains(info.Subject, "CN=*.") {
+		for _, part := range strings.Split(info.Subject, ",") {
+			if strings.HasPrefix(part, "CN=*.") {
+				names = append(names, strings.TrimPrefix(part, "CN="))
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,145 @@
+package ssl
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+func TestAnalyzeCertificate(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	revokedAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	staleAt := time.Date(2024, 5, 30, 0, 0, 0, 0, time.UTC)
+	good := func() *models.SSLCertInfo {
+		return &models.SSLCertInfo{
+			Subject:            "CN=www.example.com",
+			DNSNames:           []string{"www.example.com"},
+			PublicKeyAlgorithm: "RSA",
+			PublicKeyBits:      2048,
+			SignatureAlgorithm: "SHA256-RSA",
+			ValidFrom:          time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			ValidTo:            time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			Validation:         &models.CertValidation{Trusted: true, Verdict: models.CertVerdictOK},
+		}
+	}
+	expiresIn := func(days int) func(*models.SSLCertInfo) {
+		return func(info *models.SSLCertInfo) { info.ValidTo = now.AddDate(0, 0, days) }
+	}
+	untrusted := func(verdict, reason string) func(*models.SSLCertInfo) {
+		return func(info *models.SSLCertInfo) {
+			info.Validation = &models.CertValidation{Verdict: verdict, Reason: reason}
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*models.SSLCertInfo)
+		want   []string // id/severity: detail
+	}{
+		{"good", func(*models.SSLCertInfo) {}, nil},
+		{"512-bit RSA", func(info *models.SSLCertInfo) { info.PublicKeyBits = 512 },
+			[]string{"tls-weak-key/critical: 512-bit RSA key can be factored"}},
+		{"1024-bit RSA", func(info *models.SSLCertInfo) { info.PublicKeyBits = 1024 },
+			[]string{"tls-weak-key/high: 1024-bit RSA key is below the 2048-bit minimum"}},
+		{"P-192", func(info *models.SSLCertInfo) { info.PublicKeyAlgorithm, info.PublicKeyBits = "ECDSA", 192 },
+			[]string{"tls-weak-key/high: 192-bit ECDSA key is below the 224-bit minimum"}},
+		{"P-256", func(info *models.SSLCertInfo) { info.PublicKeyAlgorithm, info.PublicKeyBits = "ECDSA", 256 }, nil},
+		{"MD5", func(info *models.SSLCertInfo) { info.SignatureAlgorithm = "MD5-RSA" },
+			[]string{"tls-weak-signature/high: MD5-RSA signatures can be forged"}},
+		{"SHA-1", func(info *models.SSLCertInfo) { info.SignatureAlgorithm = "SHA1-RSA" },
+			[]string{"tls-weak-signature/medium: SHA1-RSA signatures are deprecated and collision-prone"}},
+		{
+			"self-signed SHA-1", func(info *models.SSLCertInfo) {
+				info.SelfSigned, info.SignatureAlgorithm = true, "SHA1-RSA"
+				untrusted(models.CertVerdictUntrustedRoot, "chain ends in self-signed certificate")(info)
+			},
+			[]string{"tls-self-signed/medium: the certificate is signed by its own key and cannot be validated by clients"},
+		},
+		{
+			"not yet valid", func(info *models.SSLCertInfo) {
+				info.ValidFrom = now.AddDate(0, 0, 2)
+				untrusted(models.CertVerdictNotYetValid, "certificate is not valid until")(info)
+			},
+			[]string{"tls-not-yet-valid/high: valid from 2024-06-03T00:00:00Z"},
+		},
+		{
+			"expired", func(info *models.SSLCertInfo) {
+				expiresIn(-3)(info)
+				info.IsExpired = true
+				untrusted(models.CertVerdictExpired, "certificate expired")(info)
+			},
+			[]string{"tls-expired/high: expired on 2024-05-29"},
+		},
+		{"long expired", expiresIn(-45), []string{"tls-expired/high: expired 45 days ago on 2024-04-17"}},
+		{"expires in 3 days", expiresIn(3), []string{"tls-expiring-soon/high: expires in 3 days on 2024-06-04"}},
+		{"expires in 20 days", expiresIn(20), []string{"tls-expiring-soon/medium: expires in 20 days on 2024-06-21"}},
+		{"expires in 30 days", expiresIn(30), nil},
+		{"wildcard", func(info *models.SSLCertInfo) { info.DNSNames = []string{"example.com", "*.example.com"} },
+			[]string{"tls-wildcard/low: covers *.example.com; a compromised key exposes every matching host"}},
+		{"wildcard subject only", func(info *models.SSLCertInfo) { info.Subject, info.DNSNames = "CN=*.example.org,O=Example", nil },
+			[]string{"tls-wildcard/low: covers *.example.org; a compromised key exposes every matching host"}},
+		{"hostname mismatch", untrusted(models.CertVerdictHostnameMismatch, "x509: certificate is valid for www.example.com, not example.com"),
+			[]string{"tls-hostname-mismatch/medium: x509: certificate is valid for www.example.com, not example.com"}},
+		{"expired intermediate", untrusted(models.CertVerdictExpired, `chain certificate 2 "CN=R3" expired on 2024-05-01T00:00:00Z`),
+			[]string{`tls-chain-expired/high: chain certificate 2 "CN=R3" expired on 2024-05-01T00:00:00Z`}},
+		{"missing intermediate", untrusted(models.CertVerdictMissingIntermediate, `no certificate for issuer "CN=R3" was presented`),
+			[]string{`tls-untrusted-chain/medium: missing_intermediate: no certificate for issuer "CN=R3" was presented`}},
+		{
+			"revoked", func(info *models.SSLCertInfo) {
+				info.OCSPStapled = true
+				info.OCSPStaple = &models.OCSPStaple{CertStatus: "revoked", RevokedAt: &revokedAt, RevocationReason: "keyCompromise"}
+			},
+			[]string{"tls-revoked/critical: the stapled OCSP response reports the certificate revoked on 2024-05-01 (keyCompromise)"},
+		},
+		{
+			"stale staple", func(info *models.SSLCertInfo) {
+				info.OCSPStapled = true
+				info.OCSPStaple = &models.OCSPStaple{CertStatus: "good", NextUpdate: &staleAt}
+			},
+			[]string{"tls-stale-ocsp/low: the stapled response expired on 2024-05-30T00:00:00Z"},
+		},
+		{"must-staple without a staple", func(info *models.SSLCertInfo) { info.MustStaple = true },
+			[]string{"tls-must-staple-missing/medium: the certificate requires a stapled OCSP response but the server sent none"}},
+		{
+			"several", func(info *models.SSLCertInfo) {
+				info.PublicKeyBits = 1024
+				info.SignatureAlgorithm = "SHA1-RSA"
+				info.DNSNames = []string{"*.example.com"}
+				expiresIn(20)(info)
+			},
+			[]string{
+				"tls-weak-key/high: 1024-bit RSA key is below the 2048-bit minimum",
+				"tls-weak-signature/medium: SHA1-RSA signatures are deprecated and collision-prone",
+				"tls-expiring-soon/medium: expires in 20 days on 2024-06-21",
+				"tls-wildcard/low: covers *.example.com; a compromised key exposes every matching host",
+			},
+		},
+	}
+	for _, tt := range tests {
+		info := good()
+		tt.modify(info)
+		var got []string
+		for _, f := range AnalyzeCertificate(info, now) {
+			got = append(got, f.ID+"/"+f.Severity+": "+f.Detail)
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+
+	// The findings raise the port's severity to the highest of them
+	info := good()
+	info.SignatureAlgorithm = "SHA1-RSA"
+	info.ValidTo = now.AddDate(0, 0, 3)
+	result := &models.ScanResult{Severity: models.SeverityLow}
+	result.AddFindings(AnalyzeCertificate(info, now)...)
+	if len(result.Findings) != 2 || result.Severity != models.SeverityHigh {
+		t.Errorf("got severity %q from %+v", result.Severity, result.Findings)
+	}
+
+	if findings := AnalyzeCertificate(nil, now); findings != nil {
+		t.Errorf("no certificate: got %+v", findings)
+	}
+}
@@ -0,0 +1,365 @@
+package ssl
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// Protocol versions
+const (
+	VersionSSL30 uint16 = 0x0300
+	VersionTLS10 uint16 = 0x0301
+	VersionTLS11 uint16 = 0x0302
+	VersionTLS12 uint16 = 0x0303
+	VersionTLS13 uint16 = 0x0304
+)
+
+// VersionName returns the display name of a protocol version
+func VersionName(version uint16) string {
+	switch version {
+	case VersionSSL30:
+		return "SSLv3"
+	case VersionTLS10:
+		return "TLS 1.0"
+	case VersionTLS11:
+		return "TLS 1.1"
+	case VersionTLS12:
+		return "TLS 1.2"
+	case VersionTLS13:
+		return "TLS 1.3"
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
+
+// Record and handshake message types
+const (
+	recordHandshake = 22
+	recordAlert     = 21
+
+	typeClientHello       = 1
+	typeServerHello       = 2
+	typeServerKeyExchange = 12
+	typeServerHelloDone   = 14
+)
+
+// Extension types
+const (
+	extServerName          = 0x0000
+	extSupportedGroups     = 0x000a
+	extECPointFormats      = 0x000b
+	extSignatureAlgorithms = 0x000d
+	extALPN                = 0x0010
+	extSupportedVersions   = 0x002b
+	extKeyShare            = 0x0033
+	extRenegotiationInfo   = 0xff01
+)
+
+// helloRetryRandom is the ServerHello random marking a HelloRetryRequest
+// (RFC 8446, section 4.1.3)
+var helloRetryRandom = []byte{
+	0xcf, 0x21, 0xad, 0x74, 0xe5, 0x9a, 0x61, 0x11, 0xbe, 0x1d, 0x8c, 0x02, 0x1e, 0x65, 0xb8, 0x91,
+	0xc2, 0xa2, 0x11, 0x16, 0x7a, 0xbb, 0x8c, 0x5e, 0x07, 0x9e, 0x09, 0xe2, 0xc8, 0xa8, 0x33, 0x9c,
+}
+
+// signatureAlgorithms offered in every TLS 1.2+ ClientHello
+var signatureAlgorithms = []uint16{
+	0x0403, 0x0503, 0x0603, 0x0804, 0x0805, 0x0806, 0x0401, 0x0501, 0x0601,
+	0x0807, 0x0808, 0x0203, 0x0201, 0x0202, 0x0402,
+}
+
+// errAlert is returned when the server answers with a TLS alert
+type errAlert struct {
+	description byte
+}
+
+func (e errAlert) Error() string {
+	return fmt.Sprintf("tls alert %d", e.description)
+}
+
+// errNotTLS is returned when the response is not a TLS record
+var errNotTLS = errors.New("response is not TLS")
+
+// clientHello describes a raw ClientHello
+type clientHello struct {
+	version    uint16 // highest version offered
+	serverName string
+	ciphers    []uint16
+	groups     []uint16
+	keyShare   bool     // TLS 1.3: send an x25519 share instead of an empty list
+	alpn       []string // offered application protocols
+}
+
+// marshal encodes the ClientHello as a TLS record
+func (h *clientHello) marshal() []byte {
+	legacyVersion := h.version
+	if legacyVersion > VersionTLS12 {
+		legacyVersion = VersionTLS12
+	}
+
+	var body bytes.Buffer
+	putUint16(&body, legacyVersion)
+	body.Write(randomBytes(32))
+
+	if h.version >= VersionTLS13 {
+		// A legacy session ID keeps middlebox-compatible servers happy
+		body.WriteByte(32)
+		body.Write(randomBytes(32))
+	} else {
+		body.WriteByte(0)
+	}
+
+	putUint16(&body, uint16(2*len(h.ciphers)))
+	for _, id := range h.ciphers {
+		putUint16(&body, id)
+	}
+	body.Write([]byte{1, 0}) // null compression
+
+	if h.version > VersionSSL30 {
+		extensions := h.extensions()
+		putUint16(&body, uint16(len(extensions)))
+		body.Write(extensions)
+	}
+
+	var handshake bytes.Buffer
+	handshake.WriteByte(typeClientHello)
+	putUint24(&handshake, len(body.Bytes()))
+	handshake.Write(body.Bytes())
+
+	recordVersion := VersionTLS10
+	if h.version == VersionSSL30 {
+		recordVersion = VersionSSL30
+	}
+
+	var record bytes.Buffer
+	record.WriteByte(recordHandshake)
+	putUint16(&record, recordVersion)
+	putUint16(&record, uint16(handshake.Len()))
+	record.Write(handshake.Bytes())
+	return record.Bytes()
+}
+
+// extensions encodes the ClientHello extensions
+func (h *clientHello) extensions() []byte {
+	var b bytes.Buffer
+	ext := func(typ uint16, data []byte) {
+		putUint16(&b, typ)
+		putUint16(&b, uint16(len(data)))
+		b.Write(data)
+	}
+
+	if h.serverName != "" && net.ParseIP(h.serverName) == nil {
+		var sni bytes.Buffer
+		putUint16(&sni, uint16(len(h.serverName)+3))
+		sni.WriteByte(0) // host_name
+		putUint16(&sni, uint16(len(h.serverName)))
+		sni.WriteString(h.serverName)
+		ext(extServerName, sni.Bytes())
+	}
+
+	if len(h.groups) > 0 {
+		var groups bytes.Buffer
+		putUint16(&groups, uint16(2*len(h.groups)))
+		for _, g := range h.groups {
+			putUint16(&groups, g)
+		}
+		ext(extSupportedGroups, groups.Bytes())
+		ext(extECPointFormats, []byte{1, 0})
+	}
+
+	if h.version >= VersionTLS12 {
+		var sigs bytes.Buffer
+		putUint16(&sigs, uint16(2*len(signatureAlgorithms)))
+		for _, s := range signatureAlgorithms {
+			putUint16(&sigs, s)
+		}
+		ext(extSignatureAlgorithms, sigs.Bytes())
+	}
+
+	if len(h.alpn) > 0 {
+		var list bytes.Buffer
+		for _, proto := range h.alpn {
+			list.WriteByte(byte(len(proto)))
+			list.WriteString(proto)
+		}
+		var alpn bytes.Buffer
+		putUint16(&alpn, uint16(list.Len()))
+		alpn.Write(list.Bytes())
+		ext(extALPN, alpn.Bytes())
+	}
+
+	if h.version >= VersionTLS13 {
+		ext(extSupportedVersions, []byte{2, byte(VersionTLS13 >> 8), byte(VersionTLS13 & 0xff)})
+
+		var shares bytes.Buffer
+		if h.keyShare {
+			putUint16(&shares, GroupX25519)
+			putUint16(&shares, 32)
+			shares.Write(randomBytes(32))
+		}
+		var keyShare bytes.Buffer
+		putUint16(&keyShare, uint16(shares.Len()))
+		keyShare.Write(shares.Bytes())
+		ext(extKeyShare, keyShare.Bytes())
+	} else {
+		ext(extRenegotiationInfo, []byte{0})
+	}
+
+	return b.Bytes()
+}
+
+// serverHello holds the negotiated parameters of a ServerHello
+type serverHello struct {
+	legacyVersion uint16
+	version       uint16 // negotiated version, from supported_versions when present
+	cipher        uint16
+	retry         bool   // HelloRetryRequest
+	group         uint16 // TLS 1.3 key_share group
+	alpn          string
+	extensions    []uint16 // extension types in the order sent
+}
+
+// parseServerHello decodes a ServerHello handshake message body
+func parseServerHello(body []byte) (*serverHello, error) {
+	r := &reader{data: body}
+	sh := &serverHello{legacyVersion: r.uint16()}
+	sh.version = sh.legacyVersion
+	random := r.bytes(32)
+	r.bytes(int(r.uint8())) // session ID
+	sh.cipher = r.uint16()
+	r.uint8() // compression
+	if r.err != nil {
+		return nil, fmt.Errorf("short server hello")
+	}
+	sh.retry = bytes.Equal(random, helloRetryRandom)
+
+	if r.empty() {
+		return sh, nil
+	}
+	extensions := &reader{data: r.bytes(int(r.uint16()))}
+	for !extensions.empty() && extensions.err == nil {
+		typ := extensions.uint16()
+		data := &reader{data: extensions.bytes(int(extensions.uint16()))}
+		sh.extensions = append(sh.extensions, typ)
+		switch typ {
+		case extSupportedVersions:
+			sh.version = data.uint16()
+		case extKeyShare:
+			sh.group = data.uint16()
+		case extALPN:
+			list := &reader{data: data.bytes(int(data.uint16()))}
+			sh.alpn = string(list.bytes(int(list.uint8())))
+		}
+	}
+	if r.err != nil || extensions.err != nil {
+		return nil, fmt.Errorf("malformed server hello extensions")
+	}
+	return sh, nil
+}
+
+// handshakeReader reassembles handshake messages from TLS records
+type handshakeReader struct {
+	r   io.Reader
+	buf []byte
+}
+
+// next returns the next handshake message type and body
+func (h *handshakeReader) next() (byte, []byte, error) {
+	for {
+		if len(h.buf) >= 4 {
+			n := int(h.buf[1])<<16 | int(h.buf[2])<<8 | int(h.buf[3])
+			if len(h.buf) >= 4+n {
+				typ, body := h.buf[0], h.buf[4:4+n]
+				h.buf = h.buf[4+n:]
+				return typ, body, nil
+			}
+		}
+		if len(h.buf) > 1<<18 {
+			return 0, nil, fmt.Errorf("handshake message too large")
+		}
+
+		header := make([]byte, 5)
+		if _, err := io.ReadFull(h.r, header); err != nil {
+			return 0, nil, err
+		}
+		if header[1] != 3 {
+			return 0, nil, errNotTLS
+		}
+		length := int(binary.BigEndian.Uint16(header[3:5]))
+		if length > 1<<14+2048 {
+			return 0, nil, errNotTLS
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(h.r, payload); err != nil {
+			return 0, nil, err
+		}
+
+		switch header[0] {
+		case recordHandshake:
+			h.buf = append(h.buf, payload...)
+		case recordAlert:
+			if len(payload) < 2 {
+				return 0, nil, errNotTLS
+			}
+			return 0, nil, errAlert{description: payload[1]}
+		default:
+			return 0, nil, errNotTLS
+		}
+	}
+}
+
+// reader is a bounds-checked big-endian byte reader; the first short read
+// sets err and every later read returns zero values
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil || n > len(r.data) {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) uint8() uint8 {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) uint16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *reader) empty() bool {
+	return len(r.data) == 0
+}
+
+func putUint16(b *bytes.Buffer, v uint16) {
+	b.WriteByte(byte(v >> 8))
+	b.WriteByte(byte(v))
+}
+
+func putUint24(b *bytes.Buffer, v int) {
+	b.WriteByte(byte(v >> 16))
+	b.WriteByte(byte(v >> 8))
+	b.WriteByte(byte(v))
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
@@ -0,0 +1,209 @@
+package ssl
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/testutil"
+)
+
+// The testdata/*.bin replies were recorded from a crypto/tls server
+// answering ClientHellos built by clientHello.marshal
+
+func TestParseServerHello(t *testing.T) {
+	tests := []struct {
+		file string
+		want serverHello
+	}{
+		{
+			file: "serverhello-tls13.bin",
+			want: serverHello{
+				legacyVersion: VersionTLS12, version: VersionTLS13, cipher: 0x1301, group: GroupX25519,
+				extensions: []uint16{extSupportedVersions, extKeyShare},
+			},
+		},
+		{
+			file: "serverhello-tls12.bin",
+			want: serverHello{
+				legacyVersion: VersionTLS12, version: VersionTLS12, cipher: 0xc02b, alpn: "http/1.1",
+				extensions: []uint16{extRenegotiationInfo, extALPN, extECPointFormats, extServerName},
+			},
+		},
+		{
+			file: "hello-retry.bin",
+			want: serverHello{
+				legacyVersion: VersionTLS12, version: VersionTLS13, cipher: 0x1301, retry: true, group: GroupX25519,
+				extensions: []uint16{extSupportedVersions, extKeyShare},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			hs := &handshakeReader{r: bytes.NewReader(testutil.Fixture(t, tt.file))}
+			typ, body, err := hs.next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if typ != typeServerHello {
+				t.Fatalf("message type %d, want ServerHello", typ)
+			}
+			got, err := parseServerHello(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandshakeReaderMessages(t *testing.T) {
+	// A TLS 1.2 server sends its whole first flight at once
+	hs := &handshakeReader{r: bytes.NewReader(testutil.Fixture(t, "serverhello-tls12.bin"))}
+	var types []byte
+	for {
+		typ, _, err := hs.next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, typ)
+		if typ == typeServerHelloDone {
+			break
+		}
+	}
+	want := []byte{typeServerHello, 11, typeServerKeyExchange, typeServerHelloDone}
+	if !bytes.Equal(types, want) {
+		t.Errorf("message types %v, want %v", types, want)
+	}
+}
+
+func TestHandshakeReaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"alert", testutil.Fixture(t, "alert-handshake-failure.bin"), errAlert{description: 40}},
+		{"plaintext", testutil.Fixture(t, "http-response.bin"), errNotTLS},
+		{"oversized record", []byte{recordHandshake, 3, 3, 0xff, 0xff}, errNotTLS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hs := &handshakeReader{r: bytes.NewReader(tt.data)}
+			if _, _, err := hs.next(); !errors.Is(err, tt.want) {
+				t.Errorf("got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseServerHelloTruncated(t *testing.T) {
+	hs := &handshakeReader{r: bytes.NewReader(testutil.Fixture(t, "serverhello-tls13.bin"))}
+	_, body, err := hs.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{0, 10, 34, 38, len(body) - 1} {
+		if _, err := parseServerHello(body[:n]); err == nil {
+			t.Errorf("parsing %d of %d bytes succeeded", n, len(body))
+		}
+	}
+}
+
+func TestClientHelloMarshal(t *testing.T) {
+	tests := []struct {
+		name  string
+		hello clientHello
+		want  tls.ClientHelloInfo
+	}{
+		{
+			name: "TLS 1.3",
+			hello: clientHello{
+				version: VersionTLS13, serverName: "example.com", ciphers: []uint16{0x1301, 0x1302},
+				groups: []uint16{GroupX25519, GroupSecp256r1}, keyShare: true, alpn: []string{"h2", "http/1.1"},
+			},
+			want: tls.ClientHelloInfo{
+				ServerName:        "example.com",
+				CipherSuites:      []uint16{0x1301, 0x1302},
+				SupportedCurves:   []tls.CurveID{tls.X25519, tls.CurveP256},
+				SupportedPoints:   []uint8{0},
+				SupportedProtos:   []string{"h2", "http/1.1"},
+				SupportedVersions: []uint16{VersionTLS13},
+			},
+		},
+		{
+			name: "TLS 1.2 to an IP address",
+			hello: clientHello{
+				version: VersionTLS12, serverName: "192.0.2.1", ciphers: []uint16{0xc02f, 0x009c},
+				groups: []uint16{GroupSecp256r1},
+			},
+			want: tls.ClientHelloInfo{
+				CipherSuites:      []uint16{0xc02f, 0x009c},
+				SupportedCurves:   []tls.CurveID{tls.CurveP256},
+				SupportedPoints:   []uint8{0},
+				SupportedVersions: []uint16{VersionTLS12, VersionTLS11, VersionTLS10},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := serverView(t, tt.hello.marshal())
+			got.SignatureSchemes = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("server saw %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// serverView hands a ClientHello record to a crypto/tls server and returns
+// what the server parsed from it
+func serverView(t *testing.T, record []byte) tls.ClientHelloInfo {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	seen := make(chan tls.ClientHelloInfo, 1)
+	config := &tls.Config{
+		GetConfigForClient: func(info *tls.ClientHelloInfo) (*tls.Config, error) {
+			seen <- tls.ClientHelloInfo{
+				CipherSuites:      info.CipherSuites,
+				ServerName:        info.ServerName,
+				SupportedCurves:   info.SupportedCurves,
+				SupportedPoints:   info.SupportedPoints,
+				SignatureSchemes:  info.SignatureSchemes,
+				SupportedProtos:   info.SupportedProtos,
+				SupportedVersions: info.SupportedVersions,
+			}
+			return nil, errors.New("done")
+		},
+	}
+	go func() {
+		defer server.Close()
+		tls.Server(server, config).Handshake()
+	}()
+	go func() {
+		client.Write(record)
+		// Drain the alert the server sends after rejecting the hello
+		client.SetReadDeadline(time.Now().Add(time.Second))
+		buf := make([]byte, 64)
+		for {
+			if _, err := client.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case info := <-seen:
+		return info
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not parse the ClientHello")
+	}
+	return tls.ClientHelloInfo{}
+}
@@ -0,0 +1,360 @@
+package ssl
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// jarmProbe is one of the ten JARM ClientHellos
+type jarmProbe struct {
+	version        uint16 // TLS version in the hello: TLS 1.1, 1.2 or 1.3
+	noTLS13        bool   // omit the TLS 1.3 cipher suites
+	cipherOrder    string
+	grease         bool
+	rareALPN       bool   // omit http/1.1 and h2
+	support        string // supported_versions contents: "1.2", "1.3" or "" for none
+	extensionOrder string
+}
+
+// jarmProbes is the standard JARM probe sequence
+var jarmProbes = []jarmProbe{
+	{version: VersionTLS12, cipherOrder: "FORWARD", support: "1.2", extensionOrder: "REVERSE"},
+	{version: VersionTLS12, cipherOrder: "REVERSE", support: "1.2", extensionOrder: "FORWARD"},
+	{version: VersionTLS12, cipherOrder: "TOP_HALF", extensionOrder: "FORWARD"},
+	{version: VersionTLS12, cipherOrder: "BOTTOM_HALF", rareALPN: true, extensionOrder: "FORWARD"},
+	{version: VersionTLS12, cipherOrder: "MIDDLE_OUT", grease: true, rareALPN: true, extensionOrder: "REVERSE"},
+	{version: VersionTLS11, cipherOrder: "FORWARD", extensionOrder: "FORWARD"},
+	{version: VersionTLS13, cipherOrder: "FORWARD", support: "1.3", extensionOrder: "REVERSE"},
+	{version: VersionTLS13, cipherOrder: "REVERSE", support: "1.3", extensionOrder: "FORWARD"},
+	{version: VersionTLS13, noTLS13: true, cipherOrder: "FORWARD", support: "1.3", extensionOrder: "FORWARD"},
+	{version: VersionTLS13, cipherOrder: "MIDDLE_OUT", grease: true, support: "1.3", extensionOrder: "REVERSE"},
+}
+
+// jarmCiphers is the cipher list offered by every JARM probe
+var jarmCiphers = []uint16{
+	0x0016, 0x0033, 0x0067, 0xc09e, 0xc0a2, 0x009e, 0x0039, 0x006b, 0xc09f, 0xc0a3, 0x009f, 0x0045,
+	0x00be, 0x0088, 0x00c4, 0x009a, 0xc008, 0xc009, 0xc023, 0xc0ac, 0xc0ae, 0xc02b, 0xc00a, 0xc024,
+	0xc0ad, 0xc0af, 0xc02c, 0xc072, 0xc073, 0xcca9, 0x1302, 0x1301, 0xcc14, 0xc007, 0xc012, 0xc013,
+	0xc027, 0xc02f, 0xc014, 0xc028, 0xc030, 0xc060, 0xc061, 0xc076, 0xc077, 0xcca8, 0x1305, 0x1304,
+	0x1303, 0xcc13, 0xc011, 0x000a, 0x002f, 0x003c, 0xc09c, 0xc0a0, 0x009c, 0x0035, 0x003d, 0xc09d,
+	0xc0a1, 0x009d, 0x0041, 0x00ba, 0x0084, 0x00c0, 0x0007, 0x0004, 0x0005,
+}
+
+// jarmCipherIndex is the order ciphers are numbered in the fuzzy hash
+var jarmCipherIndex = []uint16{
+	0x0004, 0x0005, 0x0007, 0x000a, 0x0016, 0x002f, 0x0033, 0x0035, 0x0039, 0x003c, 0x003d, 0x0041,
+	0x0045, 0x0067, 0x006b, 0x0084, 0x0088, 0x009a, 0x009c, 0x009d, 0x009e, 0x009f, 0x00ba, 0x00be,
+	0x00c0, 0x00c4, 0xc007, 0xc008, 0xc009, 0xc00a, 0xc011, 0xc012, 0xc013, 0xc014, 0xc023, 0xc024,
+	0xc027, 0xc028, 0xc02b, 0xc02c, 0xc02f, 0xc030, 0xc060, 0xc061, 0xc072, 0xc073, 0xc076, 0xc077,
+	0xc09c, 0xc09d, 0xc09e, 0xc09f, 0xc0a0, 0xc0a1, 0xc0a2, 0xc0a3, 0xc0ac, 0xc0ad, 0xc0ae, 0xc0af,
+	0xcc13, 0xcc14, 0xcca8, 0xcca9, 0x1301, 0x1302, 0x1303, 0x1304, 0x1305,
+}
+
+// jarmALPN lists the offered protocols from weakest to strongest
+var jarmALPN = []string{"http/0.9", "http/1.0", "http/1.1", "spdy/1", "spdy/2", "spdy/3", "h2", "h2c", "hq"}
+
+// jarmEmpty is the fingerprint of a server that answered no probe
+var jarmEmpty = strings.Repeat("0", 62)
+
+// JARM computes the JARM fingerprint of a TLS server: ten varied
+// ClientHellos whose negotiated cipher, version, ALPN and extensions are
+// hashed into 62 hex characters. It returns the all-zero fingerprint when
+// no probe gets a ServerHello.
+func JARM(address string, opts ProbeOptions) string {
+	raw := make([]string, len(jarmProbes))
+	for i, probe := range jarmProbes {
+		raw[i] = jarmResult(address, opts, probe.marshal(opts.ServerName))
+	}
+	return jarmHash(raw)
+}
+
+// jarmResult sends one probe and formats the response as
+// "cipher|version|alpn|extensions", or "|||" when there is none
+func jarmResult(address string, opts ProbeOptions, hello []byte) string {
+	conn, hs, err := sendHello(address, opts, hello)
+	if err != nil {
+		return "|||"
+	}
+	defer conn.Close()
+
+	typ, body, err := hs.next()
+	if err != nil || typ != typeServerHello {
+		return "|||"
+	}
+	sh, err := parseServerHello(body)
+	if err != nil {
+		return "|||"
+	}
+
+	types := make([]string, len(sh.extensions))
+	for i, ext := range sh.extensions {
+		types[i] = fmt.Sprintf("%04x", ext)
+	}
+	return fmt.Sprintf("%04x|%04x|%s|%s", sh.cipher, sh.legacyVersion, sh.alpn, strings.Join(types, "-"))
+}
+
+// jarmHash builds the fingerprint: per probe one cipher byte and one
+// version character, then the truncated SHA-256 of the ALPNs and extensions
+func jarmHash(raw []string) string {
+	var fuzzy strings.Builder
+	var rest strings.Builder
+	empty := true
+
+	for _, result := range raw {
+		parts := strings.SplitN(result, "|", 4)
+		if len(parts) != 4 {
+			parts = []string{"", "", "", ""}
+		}
+		if parts[0] != "" {
+			empty = false
+		}
+		fuzzy.WriteString(jarmCipherByte(parts[0]))
+		fuzzy.WriteString(jarmVersionByte(parts[1]))
+		rest.WriteString(parts[2])
+		rest.WriteString(parts[3])
+	}
+	if empty {
+		return jarmEmpty
+	}
+
+	sum := sha256.Sum256([]byte(rest.String()))
+	return fuzzy.String() + hex.EncodeToString(sum[:])[:32]
+}
+
+// jarmCipherByte numbers the selected cipher from 1; unknown ciphers get
+// one past the end of the list and no answer gets 00
+func jarmCipherByte(cipher string) string {
+	if cipher == "" {
+		return "00"
+	}
+	n := len(jarmCipherIndex) + 1
+	for i, id := range jarmCipherIndex {
+		if fmt.Sprintf("%04x", id) == cipher {
+			n = i + 1
+			break
+		}
+	}
+	return fmt.Sprintf("%02x", n)
+}
+
+// jarmVersionByte maps 0300..0305 to a..f
+func jarmVersionByte(version string) string {
+	if len(version) != 4 {
+		return "0"
+	}
+	minor := version[3] - '0'
+	if minor > 5 {
+		return "0"
+	}
+	return string("abcdef"[minor])
+}
+
+// marshal builds the probe's ClientHello record
+func (p jarmProbe) marshal(serverName string) []byte {
+	var hello bytes.Buffer
+	if p.version == VersionTLS13 {
+		putUint16(&hello, VersionTLS12)
+	} else {
+		putUint16(&hello, p.version)
+	}
+	hello.Write(randomBytes(32))
+	hello.WriteByte(32)
+	hello.Write(randomBytes(32))
+
+	var ciphers []uint16
+	for _, id := range jarmCiphers {
+		if p.noTLS13 && isTLS13Suite(id) {
+			continue
+		}
+		ciphers = append(ciphers, id)
+	}
+	if p.cipherOrder != "FORWARD" {
+		ciphers = mung(ciphers, p.cipherOrder)
+	}
+	if p.grease {
+		ciphers = append([]uint16{randomGrease()}, ciphers...)
+	}
+	putUint16(&hello, uint16(2*len(ciphers)))
+	for _, id := range ciphers {
+		putUint16(&hello, id)
+	}
+	hello.Write([]byte{1, 0})
+
+	extensions := p.extensions(serverName)
+	putUint16(&hello, uint16(len(extensions)))
+	hello.Write(extensions)
+
+	var handshake bytes.Buffer
+	handshake.WriteByte(typeClientHello)
+	putUint24(&handshake, hello.Len())
+	handshake.Write(hello.Bytes())
+
+	recordVersion := p.version
+	if p.version == VersionTLS13 {
+		recordVersion = VersionTLS10
+	}
+	var record bytes.Buffer
+	record.WriteByte(recordHandshake)
+	putUint16(&record, recordVersion)
+	putUint16(&record, uint16(handshake.Len()))
+	record.Write(handshake.Bytes())
+	return record.Bytes()
+}
+
+// extensions builds the probe's extension block in the reference order
+func (p jarmProbe) extensions(serverName string) []byte {
+	var b bytes.Buffer
+	ext := func(typ uint16, data []byte) {
+		putUint16(&b, typ)
+		putUint16(&b, uint16(len(data)))
+		b.Write(data)
+	}
+
+	grease := randomGrease()
+	if p.grease {
+		ext(grease, nil)
+	}
+
+	var sni bytes.Buffer
+	putUint16(&sni, uint16(len(serverName)+3))
+	sni.WriteByte(0)
+	putUint16(&sni, uint16(len(serverName)))
+	sni.WriteString(serverName)
+	ext(extServerName, sni.Bytes())
+
+	ext(0x0017, nil)                     // extended_master_secret
+	ext(0x0001, []byte{1})               // max_fragment_length
+	ext(extRenegotiationInfo, []byte{0}) // renegotiation_info
+	ext(extSupportedGroups, []byte{0, 8, 0, 0x1d, 0, 0x17, 0, 0x18, 0, 0x19})
+	ext(extECPointFormats, []byte{1, 0})
+	ext(0x0023, nil) // session_ticket
+
+	protocols := jarmALPN
+	if p.rareALPN {
+		protocols = []string{"http/0.9", "http/1.0", "spdy/1", "spdy/2", "spdy/3", "h2c", "hq"}
+	}
+	if p.extensionOrder != "FORWARD" {
+		protocols = mungStrings(protocols, p.extensionOrder)
+	}
+	var alpn bytes.Buffer
+	for _, proto := range protocols {
+		alpn.WriteByte(byte(len(proto)))
+		alpn.WriteString(proto)
+	}
+	alpnList := alpn.Bytes()
+	ext(extALPN, append([]byte{byte(len(alpnList) >> 8), byte(len(alpnList))}, alpnList...))
+
+	ext(extSignatureAlgorithms, []byte{
+		0x00, 0x12, 0x04, 0x03, 0x08, 0x04, 0x04, 0x01, 0x05, 0x03,
+		0x08, 0x05, 0x05, 0x01, 0x08, 0x06, 0x06, 0x01, 0x02, 0x01,
+	})
+
+	var shares bytes.Buffer
+	if p.grease {
+		putUint16(&shares, grease)
+		shares.Write([]byte{0, 1, 0})
+	}
+	putUint16(&shares, GroupX25519)
+	putUint16(&shares, 32)
+	shares.Write(randomBytes(32))
+	ext(extKeyShare, append([]byte{byte(shares.Len() >> 8), byte(shares.Len())}, shares.Bytes()...))
+
+	ext(0x002d, []byte{1, 1}) // psk_key_exchange_modes
+
+	if p.support != "" {
+		versions := []uint16{VersionTLS10, VersionTLS11, VersionTLS12}
+		if p.support == "1.3" {
+			versions = append(versions, VersionTLS13)
+		}
+		if p.extensionOrder != "FORWARD" {
+			versions = mung(versions, p.extensionOrder)
+		}
+		var list bytes.Buffer
+		if p.grease {
+			putUint16(&list, grease)
+		}
+		for _, v := range versions {
+			putUint16(&list, v)
+		}
+		ext(extSupportedVersions, append([]byte{byte(list.Len())}, list.Bytes()...))
+	}
+
+	return b.Bytes()
+}
+
+// mung reorders a list the way the JARM reference implementation does
+func mung(items []uint16, order string) []uint16 {
+	idx := mungOrder(len(items), order)
+	out := make([]uint16, len(idx))
+	for i, j := range idx {
+		out[i] = items[j]
+	}
+	return out
+}
+
+func mungStrings(items []string, order string) []string {
+	idx := mungOrder(len(items), order)
+	out := make([]string, len(idx))
+	for i, j := range idx {
+		out[i] = items[j]
+	}
+	return out
+}
+
+// mungOrder returns the indexes of an n-item list in the given order:
+// REVERSE, BOTTOM_HALF, TOP_HALF (reversed first half, middle item first)
+// or MIDDLE_OUT (alternating outward from the middle, upper half first)
+func mungOrder(n int, order string) []int {
+	var idx []int
+	switch order {
+	case "REVERSE":
+		for i := n - 1; i >= 0; i-- {
+			idx = append(idx, i)
+		}
+	case "BOTTOM_HALF":
+		start := n / 2
+		if n%2 == 1 {
+			start++
+		}
+		for i := start; i < n; i++ {
+			idx = append(idx, i)
+		}
+	case "TOP_HALF":
+		if n%2 == 1 {
+			idx = append(idx, n/2)
+		}
+		reversed := mungOrder(n, "REVERSE")
+		for _, i := range mungOrder(n, "BOTTOM_HALF") {
+			idx = append(idx, reversed[i])
+		}
+	case "MIDDLE_OUT":
+		middle := n / 2
+		if n%2 == 1 {
+			idx = append(idx, middle)
+			for i := 1; i <= middle; i++ {
+				idx = append(idx, middle+i, middle-i)
+			}
+		} else {
+			for i := 1; i <= middle; i++ {
+				idx = append(idx, middle-1+i, middle-i)
+			}
+		}
+	default:
+		for i := 0; i < n; i++ {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// randomGrease picks a GREASE value (RFC 8701)
+func randomGrease() uint16 {
+	n, _ := rand.Int(rand.Reader, big.NewInt(16))
+	v := uint16(n.Int64())<<12 | 0x0a00 | uint16(n.Int64())<<4 | 0x0a
+	return v
+}
@@ -0,0 +1,65 @@
+package ssl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// JARMDatabase maps JARM fingerprints to labels
+type JARMDatabase map[string]string
+
+// defaultJARMDatabase holds published fingerprints of offensive tooling
+// defaults. Several are shared with ordinary software built on the same
+// TLS stack, so a match is a lead rather than proof. Web servers are left
+// out: their fingerprint follows the TLS library and configuration, so
+// there is no single nginx or Apache default to publish.
+var defaultJARMDatabase = JARMDatabase{
+	"07d14d16d21d21d07c42d41d00041d24a458a375eef0c576d23a7bab9a9fb1": "Cobalt Strike default (also Java TLS servers)",
+	"07d14d16d21d21d00042d43d000000aa99ce74e2c6d013c745aa52b5cc042d": "Metasploit default",
+	"22b22b09b22b22b22b22b22b22b22b352842cd5d6b0278445702035e06875c": "Trickbot",
+	"1dd28d28d00028d00042d41d00041df1e57cd0b3bf64d18696fb4fce056610": "AsyncRAT",
+	"29d21b20d29d29d21c41d21b21b41d494e0df9532e75299f15ba73156cee38": "Merlin C2",
+}
+
+// DefaultJARMDatabase returns a copy of the built-in fingerprints
+func DefaultJARMDatabase() JARMDatabase {
+	db := make(JARMDatabase, len(defaultJARMDatabase))
+	for fingerprint, label := range defaultJARMDatabase {
+		db[fingerprint] = label
+	}
+	return db
+}
+
+// LoadJARMDatabase reads a JSON object of fingerprint to label and merges
+// it over the built-in fingerprints
+func LoadJARMDatabase(path string) (JARMDatabase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries map[string]string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse %s: %v", path, err)
+	}
+
+	db := DefaultJARMDatabase()
+	for fingerprint, label := range entries {
+		fingerprint = strings.ToLower(strings.TrimSpace(fingerprint))
+		if len(fingerprint) != len(jarmEmpty) {
+			return nil, fmt.Errorf("%s: %q is not a JARM fingerprint", path, fingerprint)
+		}
+		db[fingerprint] = label
+	}
+	return db, nil
+}
+
+// Label returns the label for a fingerprint, if known
+func (db JARMDatabase) Label(fingerprint string) string {
+	if fingerprint == jarmEmpty {
+		return ""
+	}
+	return db[fingerprint]
+}
@@ -0,0 +1,131 @@
+package ssl
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/testutil"
+)
+
+// testdata/jarm-vectors.json holds raw probe results hashed by the JARM
+// reference implementation (salesforce/jarm, jarm.py)
+
+func TestJARMHash(t *testing.T) {
+	var vectors []struct {
+		Raw  []string `json:"raw"`
+		JARM string   `json:"jarm"`
+	}
+	if err := json.Unmarshal(testutil.Fixture(t, "jarm-vectors.json"), &vectors); err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range vectors {
+		if got := jarmHash(v.Raw); got != v.JARM {
+			t.Errorf("vector %d: got %s, want %s", i, got, v.JARM)
+		}
+	}
+}
+
+func TestJARMCipherByte(t *testing.T) {
+	for i, id := range jarmCipherIndex {
+		if got, want := jarmCipherByte(fmt.Sprintf("%04x", id)), fmt.Sprintf("%02x", i+1); got != want {
+			t.Errorf("cipher %04x: got %s, want %s", id, got, want)
+		}
+	}
+	if got := jarmCipherByte("abcd"); got != "46" {
+		t.Errorf("unknown cipher: got %s, want 46", got)
+	}
+	if got := jarmCipherByte(""); got != "00" {
+		t.Errorf("no cipher: got %s, want 00", got)
+	}
+}
+
+func TestMungOrder(t *testing.T) {
+	// Expected orders are the output of the reference cipher_mung
+	tests := []struct {
+		n     int
+		order string
+		want  []int
+	}{
+		{7, "REVERSE", []int{6, 5, 4, 3, 2, 1, 0}},
+		{7, "BOTTOM_HALF", []int{4, 5, 6}},
+		{7, "TOP_HALF", []int{3, 2, 1, 0}},
+		{7, "MIDDLE_OUT", []int{3, 4, 2, 5, 1, 6, 0}},
+		{8, "REVERSE", []int{7, 6, 5, 4, 3, 2, 1, 0}},
+		{8, "BOTTOM_HALF", []int{4, 5, 6, 7}},
+		{8, "TOP_HALF", []int{3, 2, 1, 0}},
+		{8, "MIDDLE_OUT", []int{4, 3, 5, 2, 6, 1, 7, 0}},
+		{3, "FORWARD", []int{0, 1, 2}},
+	}
+	for _, tt := range tests {
+		if got := mungOrder(tt.n, tt.order); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("mungOrder(%d, %s) = %v, want %v", tt.n, tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestJARMResult(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"serverhello-tls12.bin", "c02b|0303|http/1.1|ff01-0010-000b-0000"},
+		{"serverhello-tls13.bin", "1301|0303||002b-0033"},
+		{"alert-handshake-failure.bin", "|||"},
+		{"http-response.bin", "|||"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			address := replayServer(t, testutil.Fixture(t, tt.file))
+			hello := jarmProbes[0].marshal("example.com")
+			if got := jarmResult(address, ProbeOptions{Timeout: time.Second}, hello); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJARMProbes(t *testing.T) {
+	tls13Suites := 0
+	for _, id := range jarmCiphers {
+		if isTLS13Suite(id) {
+			tls13Suites++
+		}
+	}
+	for i, probe := range jarmProbes {
+		info := serverView(t, probe.marshal("example.com"))
+		if info.ServerName != "example.com" {
+			t.Errorf("probe %d: server name %q", i+1, info.ServerName)
+		}
+		ciphers := len(jarmCiphers)
+		if probe.noTLS13 {
+			ciphers -= tls13Suites
+		}
+		if probe.cipherOrder == "TOP_HALF" || probe.cipherOrder == "BOTTOM_HALF" {
+			ciphers = len(mungOrder(ciphers, probe.cipherOrder))
+		}
+		if probe.grease {
+			ciphers++
+		}
+		if len(info.CipherSuites) != ciphers {
+			t.Errorf("probe %d: %d cipher suites, want %d", i+1, len(info.CipherSuites), ciphers)
+		}
+		if want := probe.support == "1.3"; containsID(info.SupportedVersions, VersionTLS13) != want {
+			t.Errorf("probe %d: supported versions %04x", i+1, info.SupportedVersions)
+		}
+		if len(info.SupportedProtos) == 0 {
+			t.Errorf("probe %d: no ALPN protocols", i+1)
+		}
+	}
+}
+
+// replayServer answers a ClientHello with the recorded reply
+func replayServer(t *testing.T, reply []byte) string {
+	return testutil.Serve(t, func(conn net.Conn) {
+		conn.Read(make([]byte, 4096))
+		conn.Write(reply)
+	})
+}
@@ -0,0 +1,260 @@
+package ssl
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// SCT sources
+const (
+	SCTSourceTLS         = "tls_extension"
+	SCTSourceCertificate = "certificate"
+	SCTSourceOCSP        = "ocsp"
+)
+
+var (
+	oidOCSPBasic      = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+	oidTLSFeature     = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 24}
+	oidCertificateSCT = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+	oidOCSPSCT        = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 5}
+)
+
+// ocspResponseStatus names the OCSPResponseStatus values (RFC 6960)
+var ocspResponseStatus = map[asn1.Enumerated]string{
+	0: "successful",
+	1: "malformedRequest",
+	2: "internalError",
+	3: "tryLater",
+	5: "sigRequired",
+	6: "unauthorized",
+}
+
+// crlReasons names the CRLReason values (RFC 5280)
+var crlReasons = map[asn1.Enumerated]string{
+	0:  "unspecified",
+	1:  "keyCompromise",
+	2:  "cACompromise",
+	3:  "affiliationChanged",
+	4:  "superseded",
+	5:  "cessationOfOperation",
+	6:  "certificateHold",
+	8:  "removeFromCRL",
+	9:  "privilegeWithdrawn",
+	10: "aACompromise",
+}
+
+// ASN.1 structures of an OCSP response (RFC 6960, section 4.2.1)
+type ocspResponse struct {
+	Status   asn1.Enumerated
+	Response ocspResponseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type ocspBasicResponse struct {
+	TBSResponseData    ocspResponseData
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseData struct {
+	Raw                asn1.RawContent
+	Version            int `asn1:"optional,default:0,explicit,tag:0"`
+	RawResponderID     asn1.RawValue
+	ProducedAt         time.Time `asn1:"generalized"`
+	Responses          []ocspSingleResponse
+	ResponseExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type ocspSingleResponse struct {
+	CertID           ocspCertID
+	Good             asn1.Flag        `asn1:"tag:0,optional"`
+	Revoked          ocspRevokedInfo  `asn1:"tag:1,optional"`
+	Unknown          asn1.Flag        `asn1:"tag:2,optional"`
+	ThisUpdate       time.Time        `asn1:"generalized"`
+	NextUpdate       time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	SingleExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type ocspCertID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+type ocspRevokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+// addRevocationInfo records the OCSP and CRL locations, Must-Staple, the
+// stapled OCSP response and every SCT of a handshake
+func addRevocationInfo(info *models.SSLCertInfo, leaf *x509.Certificate, staple []byte, tlsSCTs [][]byte) {
+	info.OCSPServers = leaf.OCSPServer
+	info.CRLDistributionPoints = leaf.CRLDistributionPoints
+	info.IssuingCertificateURL = leaf.IssuingCertificateURL
+	info.MustStaple = mustStaple(leaf)
+
+	for _, sct := range tlsSCTs {
+		if parsed, err := parseSCT(sct, SCTSourceTLS); err == nil {
+			info.SCTs = append(info.SCTs, parsed)
+		}
+	}
+	for _, ext := range leaf.Extensions {
+		if ext.Id.Equal(oidCertificateSCT) {
+			info.SCTs = append(info.SCTs, parseSCTExtension(ext.Value, SCTSourceCertificate)...)
+		}
+	}
+
+	if len(staple) > 0 {
+		info.OCSPStapled = true
+		var scts []models.SCT
+		info.OCSPStaple, scts = parseOCSPStaple(staple, leaf)
+		info.SCTs = append(info.SCTs, scts...)
+	}
+}
+
+// parseOCSPStaple decodes a stapled OCSP response without checking its
+// signature, returning the status of the leaf and any SCTs it carries
+func parseOCSPStaple(der []byte, leaf *x509.Certificate) (*models.OCSPStaple, []models.SCT) {
+	var resp ocspResponse
+	if _, err := asn1.Unmarshal(der, &resp); err != nil {
+		return &models.OCSPStaple{Error: fmt.Sprintf("parse OCSP response: %v", err)}, nil
+	}
+
+	staple := &models.OCSPStaple{ResponseStatus: ocspResponseStatus[resp.Status]}
+	if staple.ResponseStatus == "" {
+		staple.ResponseStatus = fmt.Sprintf("status %d", resp.Status)
+	}
+	if resp.Status != 0 {
+		return staple, nil
+	}
+	if !resp.Response.ResponseType.Equal(oidOCSPBasic) {
+		staple.Error = "unsupported OCSP response type " + resp.Response.ResponseType.String()
+		return staple, nil
+	}
+
+	var basic ocspBasicResponse
+	if _, err := asn1.Unmarshal(resp.Response.Response, &basic); err != nil {
+		staple.Error = fmt.Sprintf("parse basic OCSP response: %v", err)
+		return staple, nil
+	}
+	data := basic.TBSResponseData
+	staple.ProducedAt = data.ProducedAt
+	if len(data.Responses) == 0 {
+		staple.Error = "OCSP response has no certificate status"
+		return staple, nil
+	}
+
+	// Use the response for the leaf's serial number, or the first one
+	single := data.Responses[0]
+	for _, r := range data.Responses {
+		if r.CertID.SerialNumber != nil && r.CertID.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+			single = r
+			break
+		}
+	}
+
+	staple.ThisUpdate = single.ThisUpdate
+	if !single.NextUpdate.IsZero() {
+		next := single.NextUpdate
+		staple.NextUpdate = &next
+	}
+	switch {
+	case bool(single.Good):
+		staple.CertStatus = "good"
+	case bool(single.Unknown):
+		staple.CertStatus = "unknown"
+	default:
+		staple.CertStatus = "revoked"
+		revokedAt := single.Revoked.RevocationTime
+		staple.RevokedAt = &revokedAt
+		staple.RevocationReason = crlReasons[single.Revoked.Reason]
+	}
+
+	var scts []models.SCT
+	for _, ext := range single.SingleExtensions {
+		if ext.Id.Equal(oidOCSPSCT) {
+			scts = append(scts, parseSCTExtension(ext.Value, SCTSourceOCSP)...)
+		}
+	}
+	return staple, scts
+}
+
+// parseSCTExtension decodes an X.509 or OCSP SCT list extension: an
+// OCTET STRING holding a TLS-encoded SignedCertificateTimestampList
+func parseSCTExtension(value []byte, source string) []models.SCT {
+	var list []byte
+	if _, err := asn1.Unmarshal(value, &list); err != nil {
+		return nil
+	}
+	if len(list) < 2 || int(binary.BigEndian.Uint16(list)) != len(list)-2 {
+		return nil
+	}
+
+	var scts []models.SCT
+	r := &reader{data: list[2:]}
+	for !r.empty() && r.err == nil {
+		raw := r.bytes(int(r.uint16()))
+		if r.err != nil {
+			break
+		}
+		if sct, err := parseSCT(raw, source); err == nil {
+			scts = append(scts, sct)
+		}
+	}
+	return scts
+}
+
+// parseSCT decodes a serialized SignedCertificateTimestamp (RFC 6962,
+// section 3.2); the signature is not checked
+func parseSCT(raw []byte, source string) (models.SCT, error) {
+	r := &reader{data: raw}
+	version := r.uint8()
+	logID := r.bytes(32)
+	timestamp := r.bytes(8)
+	if r.err != nil {
+		return models.SCT{}, fmt.Errorf("short SCT")
+	}
+
+	ms := int64(binary.BigEndian.Uint64(timestamp))
+	return models.SCT{
+		Source:    source,
+		Version:   int(version) + 1,
+		LogID:     base64.StdEncoding.EncodeToString(logID),
+		Timestamp: time.UnixMilli(ms).UTC(),
+	}, nil
+}
+
+// mustStaple reports whether the certificate carries the TLS feature
+// extension requesting status_request (RFC 7633)
+func mustStaple(cert *x509.Certificate) bool {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidTLSFeature) {
+			continue
+		}
+		var features []int
+		if _, err := asn1.Unmarshal(ext.Value, &features); err != nil {
+			return false
+		}
+		for _, feature := range features {
+			if feature == 5 {
+				return true
+			}
+		}
+	}
+	return false
+}
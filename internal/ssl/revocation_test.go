@@ -0,0 +1,143 @@
+package ssl
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/testutil"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// testdata/revocation-leaf.pem and the ocsp-*.der responses for it were
+// made with the openssl CLI: the leaf carries Must-Staple and an SCT list
+// of two SCTs, and the responses come from "openssl ocsp" with an index
+// marking the leaf good, revoked or absent
+
+func TestAddRevocationInfo(t *testing.T) {
+	leaf := readLeaf(t)
+	info := &models.SSLCertInfo{}
+	addRevocationInfo(info, leaf, nil, nil)
+
+	want := &models.SSLCertInfo{
+		OCSPServers:           []string{"http://ocsp.fixture.test"},
+		CRLDistributionPoints: []string{"http://crl.fixture.test/ca.crl"},
+		IssuingCertificateURL: []string{"http://ca.fixture.test/ca.der"},
+		MustStaple:            true,
+		SCTs: []models.SCT{
+			{
+				Source:    SCTSourceCertificate,
+				Version:   1,
+				LogID:     "pKSkpKSkpKSkpKSkpKSkpKSkpKSkpKSkpKSkpKSkpKQ=",
+				Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			{
+				Source:    SCTSourceCertificate,
+				Version:   1,
+				LogID:     "Ozs7Ozs7Ozs7Ozs7Ozs7Ozs7Ozs7Ozs7Ozs7Ozs7Ozs=",
+				Timestamp: time.Date(2024, 1, 1, 0, 0, 1, 500e6, time.UTC),
+			},
+		},
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("got %+v\nwant %+v", info, want)
+	}
+}
+
+func TestParseOCSPStaple(t *testing.T) {
+	leaf := readLeaf(t)
+	revokedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		file string
+		want models.OCSPStaple
+	}{
+		{"ocsp-good.der", models.OCSPStaple{ResponseStatus: "successful", CertStatus: "good"}},
+		{"ocsp-unknown.der", models.OCSPStaple{ResponseStatus: "successful", CertStatus: "unknown"}},
+		{"ocsp-revoked.der", models.OCSPStaple{
+			ResponseStatus: "successful", CertStatus: "revoked",
+			RevokedAt: &revokedAt, RevocationReason: "keyCompromise",
+		}},
+		{"ocsp-trylater.der", models.OCSPStaple{ResponseStatus: "tryLater"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got, scts := parseOCSPStaple(testutil.Fixture(t, tt.file), leaf)
+			if len(scts) != 0 {
+				t.Errorf("got %d SCTs, want none", len(scts))
+			}
+			if tt.want.ResponseStatus == "successful" {
+				// The responses were produced with a 7 day validity
+				if got.ProducedAt.IsZero() || got.ThisUpdate.IsZero() || got.NextUpdate == nil {
+					t.Fatalf("missing response times: %+v", got)
+				}
+				if validity := got.NextUpdate.Sub(got.ThisUpdate); validity != 7*24*time.Hour {
+					t.Errorf("next update %v after this update, want 7 days", validity)
+				}
+				got.ProducedAt, got.ThisUpdate, got.NextUpdate = time.Time{}, time.Time{}, nil
+			}
+			if got.RevokedAt != nil {
+				revoked := got.RevokedAt.UTC()
+				got.RevokedAt = &revoked
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOCSPStapleMalformed(t *testing.T) {
+	leaf := readLeaf(t)
+	good := testutil.Fixture(t, "ocsp-good.der")
+	for _, der := range [][]byte{nil, {0x30, 0x03, 0x0a}, good[:len(good)/2]} {
+		got, _ := parseOCSPStaple(der, leaf)
+		if got.Error == "" {
+			t.Errorf("parsing % x... gave no error: %+v", der[:min(len(der), 8)], got)
+		}
+	}
+}
+
+func TestParseSCT(t *testing.T) {
+	raw := append([]byte{0}, make([]byte, 32)...)
+	raw = append(raw, 0, 0, 0x01, 0x8c, 0xc2, 0x51, 0xf4, 0x00) // 1704067200000 ms
+	sct, err := parseSCT(raw, SCTSourceTLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.SCT{
+		Source:    SCTSourceTLS,
+		Version:   1,
+		LogID:     "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+		Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if sct != want {
+		t.Errorf("got %+v, want %+v", sct, want)
+	}
+
+	if _, err := parseSCT(raw[:40], SCTSourceTLS); err == nil {
+		t.Error("parsing a truncated SCT succeeded")
+	}
+}
+
+func TestParseSCTExtensionBadLength(t *testing.T) {
+	// An OCTET STRING whose list length disagrees with its contents
+	if scts := parseSCTExtension([]byte{0x04, 0x04, 0x00, 0x05, 0x00, 0x00}, SCTSourceCertificate); scts != nil {
+		t.Errorf("got %+v, want none", scts)
+	}
+}
+
+func readLeaf(t *testing.T) *x509.Certificate {
+	t.Helper()
+	block, _ := pem.Decode(testutil.Fixture(t, "revocation-leaf.pem"))
+	if block == nil {
+		t.Fatal("no PEM block in revocation-leaf.pem")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
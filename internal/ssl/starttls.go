@@ -0,0 +1,339 @@
+package ssl
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// STARTTLS protocols understood by StartTLS
+const (
+	ProtoSMTP     = "smtp"
+	ProtoIMAP     = "imap"
+	ProtoPOP3     = "pop3"
+	ProtoFTP      = "ftp"
+	ProtoXMPP     = "xmpp"
+	ProtoLDAP     = "ldap"
+	ProtoPostgres = "postgres"
+)
+
+// startTLSPorts maps well-known ports to their STARTTLS protocol
+var startTLSPorts = map[int]string{
+	21:   ProtoFTP,
+	25:   ProtoSMTP,
+	110:  ProtoPOP3,
+	143:  ProtoIMAP,
+	389:  ProtoLDAP,
+	587:  ProtoSMTP,
+	2525: ProtoSMTP,
+	5222: ProtoXMPP,
+	5269: ProtoXMPP,
+	5432: ProtoPostgres,
+}
+
+// DetectStartTLS picks the STARTTLS protocol for a well-known port, and
+// sniffs the greeting banner on other ports, since greetings overlap (FTP
+// and SMTP both answer 220). It returns "" when the port should be tried
+// with a direct TLS handshake instead.
+func DetectStartTLS(port int, banner string) string {
+	if protocol, ok := startTLSPorts[port]; ok {
+		return protocol
+	}
+	switch {
+	case strings.HasPrefix(banner, "220") && strings.Contains(strings.ToUpper(banner), "FTP"):
+		return ProtoFTP
+	case strings.HasPrefix(banner, "220"):
+		return ProtoSMTP
+	case strings.HasPrefix(banner, "* OK"):
+		return ProtoIMAP
+	case strings.HasPrefix(banner, "+OK"):
+		return ProtoPOP3
+	}
+	return ""
+}
+
+// ServerSpeaksFirst reports whether a STARTTLS protocol starts with a
+// server greeting
+func ServerSpeaksFirst(protocol string) bool {
+	switch protocol {
+	case ProtoSMTP, ProtoIMAP, ProtoPOP3, ProtoFTP:
+		return true
+	}
+	return false
+}
+
+// StartTLS negotiates a TLS upgrade over a plaintext connection. r must
+// wrap conn; banner is the first greeting line when it was already read.
+// On success the caller continues with a TLS handshake on conn.
+func StartTLS(conn net.Conn, r *bufio.Reader, protocol, host, banner string) error {
+	switch protocol {
+	case ProtoSMTP:
+		if _, err := readReply(r, banner); err != nil {
+			return err
+		}
+		if err := command(conn, r, "EHLO go-scan", "250"); err != nil {
+			return err
+		}
+		return command(conn, r, "STARTTLS", "220")
+	case ProtoFTP:
+		if _, err := readReply(r, banner); err != nil {
+			return err
+		}
+		return command(conn, r, "AUTH TLS", "234")
+	case ProtoIMAP:
+		if banner == "" {
+			if _, err := r.ReadString('\n'); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(conn, "a001 STARTTLS\r\n"); err != nil {
+			return err
+		}
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return err
+			}
+			if strings.HasPrefix(line, "a001 ") {
+				if !strings.HasPrefix(line, "a001 OK") {
+					return fmt.Errorf("imap starttls refused: %s", strings.TrimSpace(line))
+				}
+				return nil
+			}
+		}
+	case ProtoPOP3:
+		if banner == "" {
+			if _, err := r.ReadString('\n'); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(conn, "STLS\r\n"); err != nil {
+			return err
+		}
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(line, "+OK") {
+			return fmt.Errorf("pop3 stls refused: %s", strings.TrimSpace(line))
+		}
+		return nil
+	case ProtoXMPP:
+		return startTLSXMPP(conn, r, host)
+	case ProtoLDAP:
+		return startTLSLDAP(conn, r)
+	case ProtoPostgres:
+		return startTLSPostgres(conn, r)
+	}
+	return fmt.Errorf("unknown starttls protocol %q", protocol)
+}
+
+// ALPNProtocols are offered during handshakes, most preferred first.
+// acme-tls/1 is last because some servers switch to a challenge
+// certificate when they select it.
+var ALPNProtocols = []string{
+	"h2", "http/1.1", "http/1.0", "spdy/3.1",
+	"imap", "pop3", "ftp", "xmpp-client", "xmpp-server", "postgresql", "mqtt", "dot",
+	"acme-tls/1",
+}
+
+// Handshake runs a TLS client handshake over conn without verifying the
+// certificate, offering the alpn protocols; verification is reported
+// separately by CertInfoFromState
+func Handshake(conn net.Conn, serverName string, alpn []string, timeout time.Duration) (tls.ConnectionState, error) {
+	tlsConn := Client(conn, serverName, alpn)
+	tlsConn.SetDeadline(time.Now().Add(timeout))
+	defer tlsConn.SetDeadline(time.Time{})
+
+	if err := tlsConn.Handshake(); err != nil {
+		return tls.ConnectionState{}, err
+	}
+	return tlsConn.ConnectionState(), nil
+}
+
+// Client wraps conn in a TLS client that skips certificate verification,
+// sending serverName as SNI unless it is an IP address
+func Client(conn net.Conn, serverName string, alpn []string) *tls.Conn {
+	config := &tls.Config{InsecureSkipVerify: true, NextProtos: alpn}
+	if net.ParseIP(serverName) == nil {
+		config.ServerName = serverName
+	}
+	return tls.Client(conn, config)
+}
+
+// ALPNRejected reports whether a handshake failed because the server
+// supports none of the offered ALPN protocols. Retrying without ALPN
+// usually succeeds.
+func ALPNRejected(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "no application protocol")
+}
+
+// readReply reads a (possibly multi-line) SMTP or FTP reply. first is the
+// first line when it was already read.
+func readReply(r *bufio.Reader, first string) (string, error) {
+	line := strings.TrimRight(first, "\r\n")
+	for {
+		if line == "" {
+			next, err := r.ReadString('\n')
+			if err != nil {
+				return "", err
+			}
+			line = strings.TrimRight(next, "\r\n")
+		}
+		if len(line) < 4 || line[3] != '-' {
+			return line, nil
+		}
+		line = ""
+	}
+}
+
+// command sends a line and checks that the reply has the expected code
+func command(conn net.Conn, r *bufio.Reader, cmd, code string) error {
+	if _, err := fmt.Fprintf(conn, "%s\r\n", cmd); err != nil {
+		return err
+	}
+	reply, err := readReply(r, "")
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(reply, code) {
+		return fmt.Errorf("%s refused: %s", strings.Fields(cmd)[0], reply)
+	}
+	return nil
+}
+
+// startTLSXMPP opens a stream and requests TLS (RFC 6120)
+func startTLSXMPP(conn net.Conn, r *bufio.Reader, host string) error {
+	namespace := "jabber:client"
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok && addr.Port == 5269 {
+		namespace = "jabber:server"
+	}
+	if _, err := fmt.Fprintf(conn, "<?xml version='1.0'?><stream:stream to='%s' xmlns='%s' "+
+		"xmlns:stream='http://etherx.jabber.org/streams' version='1.0'>", host, namespace); err != nil {
+		return err
+	}
+	if _, err := readUntil(r, "</stream:features>"); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(conn, "<starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>"); err != nil {
+		return err
+	}
+	data, err := readUntil(r, ">")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(data, "<proceed") {
+		return fmt.Errorf("xmpp starttls refused")
+	}
+	return nil
+}
+
+// readUntil reads until marker has been seen, up to 64 KiB
+func readUntil(r *bufio.Reader, marker string) (string, error) {
+	var b strings.Builder
+	for b.Len() < 64<<10 {
+		c, err := r.ReadByte()
+		if err != nil {
+			return b.String(), err
+		}
+		b.WriteByte(c)
+		if strings.HasSuffix(b.String(), marker) {
+			return b.String(), nil
+		}
+	}
+	return b.String(), fmt.Errorf("no %q in server response", marker)
+}
+
+// ldapStartTLS is an ExtendedRequest for the StartTLS OID 1.3.6.1.4.1.1466.20037
+var ldapStartTLS = []byte{
+	0x30, 0x1d, 0x02, 0x01, 0x01, 0x77, 0x18, 0x80, 0x16,
+	'1', '.', '3', '.', '6', '.', '1', '.', '4', '.', '1', '.',
+	'1', '4', '6', '6', '.', '2', '0', '0', '3', '7',
+}
+
+// startTLSLDAP sends the StartTLS extended operation (RFC 4511)
+func startTLSLDAP(conn net.Conn, r *bufio.Reader) error {
+	if _, err := conn.Write(ldapStartTLS); err != nil {
+		return err
+	}
+
+	message, err := readBER(r)
+	if err != nil {
+		return err
+	}
+	var response struct {
+		ID int
+		Op asn1.RawValue
+	}
+	if _, err := asn1.Unmarshal(message, &response); err != nil {
+		return fmt.Errorf("ldap response: %v", err)
+	}
+	if response.Op.Class != asn1.ClassApplication || response.Op.Tag != 24 {
+		return fmt.Errorf("ldap response is not an extended response")
+	}
+	var code asn1.Enumerated
+	if _, err := asn1.Unmarshal(response.Op.Bytes, &code); err != nil {
+		return fmt.Errorf("ldap result code: %v", err)
+	}
+	if code != 0 {
+		return fmt.Errorf("ldap starttls refused with result code %d", code)
+	}
+	return nil
+}
+
+// readBER reads one BER-encoded element
+func readBER(r *bufio.Reader) ([]byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	length := int(header[1])
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 3 {
+			return nil, fmt.Errorf("unsupported BER length")
+		}
+		extra := make([]byte, n)
+		if _, err := io.ReadFull(r, extra); err != nil {
+			return nil, err
+		}
+		header = append(header, extra...)
+		length = 0
+		for _, b := range extra {
+			length = length<<8 | int(b)
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return append(header, body...), nil
+}
+
+// startTLSPostgres sends an SSLRequest and expects 'S'
+func startTLSPostgres(conn net.Conn, r *bufio.Reader) error {
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request[0:4], 8)
+	binary.BigEndian.PutUint32(request[4:8], 80877103)
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	reply, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if reply != 'S' {
+		return fmt.Errorf("postgres refused ssl")
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package ssl
+
+import (
+	"bufio"
+	"crypto/tls"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/testutil"
+)
+
+// step is one exchange of a scripted server: once expect has been read
+// it sends reply. An empty expect sends reply straight away.
+type step struct {
+	expect, reply string
+}
+
+// scriptServer plays steps to each client, then runs a TLS handshake
+// with a certificate for mail.example.com when upgrade is set
+func scriptServer(t *testing.T, steps []step, upgrade bool) string {
+	cert := issue(t, serverCert("mail.example.com"), nil)
+	return testutil.Serve(t, func(conn net.Conn) {
+		buf := make([]byte, 512)
+		for _, s := range steps {
+			var read strings.Builder
+			for !strings.Contains(read.String(), s.expect) {
+				n, err := conn.Read(buf)
+				if err != nil {
+					t.Errorf("waiting for %q: read %q: %v", s.expect, read.String(), err)
+					return
+				}
+				read.Write(buf[:n])
+			}
+			conn.Write([]byte(s.reply))
+		}
+		if upgrade {
+			server := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{{
+				Certificate: [][]byte{cert.cert.Raw},
+				PrivateKey:  cert.key,
+			}}})
+			server.Handshake()
+		}
+	})
+}
+
+var (
+	// ldapStartTLSOK is an ExtendedResponse with resultCode success
+	ldapStartTLSOK = "\x30\x0c\x02\x01\x01\x78\x07\x0a\x01\x00\x04\x00\x04\x00"
+	// ldapStartTLSError is one with resultCode protocolError
+	ldapStartTLSError = "\x30\x0c\x02\x01\x01\x78\x07\x0a\x01\x02\x04\x00\x04\x00"
+	// ldapBindResponse is a BindResponse, not an ExtendedResponse
+	ldapBindResponse = "\x30\x0c\x02\x01\x01\x61\x07\x0a\x01\x00\x04\x00\x04\x00"
+
+	postgresSSLRequest = "\x00\x00\x00\x08\x04\xd2\x16\x2f"
+)
+
+func TestStartTLS(t *testing.T) {
+	xmppFeatures := "<?xml version='1.0'?><stream:stream xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' " +
+		"id='x1' from='mail.example.com' version='1.0'><stream:features><starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'>" +
+		"<required/></starttls></stream:features>"
+	xmppStartTLS := "<starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>"
+
+	tests := []struct {
+		name       string
+		protocol   string
+		readBanner bool // the caller read the greeting's first line
+		steps      []step
+		want       string // error, or "" when the upgrade succeeds
+	}{
+		{"SMTP", ProtoSMTP, false, []step{
+			{"", "220-mail.example.com ESMTP\r\n220 ready\r\n"},
+			{"EHLO go-scan\r\n", "250-mail.example.com\r\n250-PIPELINING\r\n250 STARTTLS\r\n"},
+			{"STARTTLS\r\n", "220 2.0.0 Ready to start TLS\r\n"},
+		}, ""},
+		{"SMTP after the banner", ProtoSMTP, true, []step{
+			{"", "220-mail.example.com ESMTP\r\n220 ready\r\n"},
+			{"EHLO go-scan\r\n", "250 mail.example.com\r\n"},
+			{"STARTTLS\r\n", "220 2.0.0 Ready to start TLS\r\n"},
+		}, ""},
+		{"SMTP refused", ProtoSMTP, false, []step{
+			{"", "220 mail.example.com ESMTP\r\n"},
+			{"EHLO go-scan\r\n", "250 mail.example.com\r\n"},
+			{"STARTTLS\r\n", "454 4.7.0 TLS not available\r\n"},
+		}, "STARTTLS refused: 454 4.7.0 TLS not available"},
+		{"FTP", ProtoFTP, true, []step{
+			{"", "220 FTP server ready\r\n"},
+			{"AUTH TLS\r\n", "234 AUTH TLS OK.\r\n"},
+		}, ""},
+		{"FTP refused", ProtoFTP, false, []step{
+			{"", "220 FTP server ready\r\n"},
+			{"AUTH TLS\r\n", "502 Command not implemented.\r\n"},
+		}, "AUTH refused: 502 Command not implemented."},
+		{"IMAP", ProtoIMAP, false, []step{
+			{"", "* OK [CAPABILITY IMAP4rev1 STARTTLS] ready\r\n"},
+			{"a001 STARTTLS\r\n", "* BYE not really\r\na001 OK Begin TLS negotiation now\r\n"},
+		}, ""},
+		{"IMAP refused", ProtoIMAP, true, []step{
+			{"", "* OK ready\r\n"},
+			{"a001 STARTTLS\r\n", "a001 BAD STARTTLS not supported\r\n"},
+		}, "imap starttls refused: a001 BAD STARTTLS not supported"},
+		{"POP3", ProtoPOP3, true, []step{
+			{"", "+OK POP3 ready\r\n"},
+			{"STLS\r\n", "+OK Begin TLS negotiation\r\n"},
+		}, ""},
+		{"POP3 refused", ProtoPOP3, false, []step{
+			{"", "+OK POP3 ready\r\n"},
+			{"STLS\r\n", "-ERR Command not permitted\r\n"},
+		}, "pop3 stls refused: -ERR Command not permitted"},
+		{"XMPP", ProtoXMPP, false, []step{
+			{"to='mail.example.com' xmlns='jabber:client'", xmppFeatures},
+			{xmppStartTLS, "<proceed xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>"},
+		}, ""},
+		{"XMPP refused", ProtoXMPP, false, []step{
+			{"version='1.0'>", xmppFeatures},
+			{xmppStartTLS, "<failure xmlns='urn:ietf:params:xml:ns:xmpp-tls'/></stream:stream>"},
+		}, "xmpp starttls refused"},
+		{"LDAP", ProtoLDAP, false, []step{{string(ldapStartTLS), ldapStartTLSOK}}, ""},
+		{"LDAP refused", ProtoLDAP, false, []step{{string(ldapStartTLS), ldapStartTLSError}},
+			"ldap starttls refused with result code 2"},
+		{"LDAP wrong response", ProtoLDAP, false, []step{{string(ldapStartTLS), ldapBindResponse}},
+			"ldap response is not an extended response"},
+		{"PostgreSQL", ProtoPostgres, false, []step{{postgresSSLRequest, "S"}}, ""},
+		{"PostgreSQL refused", ProtoPostgres, false, []step{{postgresSSLRequest, "N"}}, "postgres refused ssl"},
+		{"unknown protocol", "gopher", false, nil, `unknown starttls protocol "gopher"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := scriptServer(t, tt.steps, tt.want == "")
+			conn, err := net.DialTimeout("tcp", address, time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+			r := bufio.NewReader(conn)
+			banner := ""
+			if tt.readBanner {
+				if banner, err = r.ReadString('\n'); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err = StartTLS(conn, r, tt.protocol, "mail.example.com", banner)
+			if tt.want != "" {
+				if err == nil || err.Error() != tt.want {
+					t.Errorf("got error %v, want %q", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The connection is ready for the handshake
+			state, err := Handshake(conn, "mail.example.com", nil, 2*time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name := state.PeerCertificates[0].Subject.CommonName; name != "mail.example.com" {
+				t.Errorf("handshake with %q", name)
+			}
+		})
+	}
+}
+
+func TestDetectStartTLS(t *testing.T) {
+	tests := []struct {
+		port   int
+		banner string
+		want   string
+	}{
+		{25, "", ProtoSMTP},
+		{21, "220 mail.example.com ESMTP", ProtoFTP}, // well-known ports win
+		{5432, "", ProtoPostgres},
+		{2121, "220 ProFTPD Server ready", ProtoFTP},
+		{10025, "220 mail.example.com ESMTP Postfix", ProtoSMTP},
+		{10143, "* OK [CAPABILITY IMAP4rev1] Dovecot ready.", ProtoIMAP},
+		{10110, "+OK Dovecot ready.", ProtoPOP3},
+		{8443, "", ""},
+		{2222, "SSH-2.0-OpenSSH_9.6", ""},
+	}
+	for _, tt := range tests {
+		if got := DetectStartTLS(tt.port, tt.banner); got != tt.want {
+			t.Errorf("DetectStartTLS(%d, %q) = %q, want %q", tt.port, tt.banner, got, tt.want)
+		}
+	}
+}
+
+func TestReadBER(t *testing.T) {
+	long := "\x04\x82\x01\x00" + strings.Repeat("x", 256)
+	tests := []struct {
+		data string
+		want string // error, or "" to get data back
+	}{
+		{ldapStartTLSOK, ""},
+		{long, ""},
+		{"\x04\x80", "unsupported BER length"},
+		{"\x04\x84\x00\x00\x01\x00", "unsupported BER length"},
+		{"\x04\x05abc", "unexpected EOF"},
+	}
+	for _, tt := range tests {
+		got, err := readBER(bufio.NewReader(strings.NewReader(tt.data)))
+		switch {
+		case tt.want == "" && (err != nil || string(got) != tt.data):
+			t.Errorf("readBER(%q) = %q, %v", tt.data, got, err)
+		case tt.want != "" && (err == nil || err.Error() != tt.want):
+			t.Errorf("readBER(%q): got error %v, want %q", tt.data, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package ssl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Cipher suite ratings, best first
+const (
+	RatingStrong   = "strong"   // AEAD with forward secrecy
+	RatingLegacy   = "legacy"   // CBC mode or no forward secrecy
+	RatingWeak     = "weak"     // RC4, 3DES or MD5
+	RatingInsecure = "insecure" // NULL, anonymous, export or single DES
+)
+
+// tls13Suites are the TLS 1.3 cipher suites
+var tls13Suites = []uint16{0x1301, 0x1302, 0x1303, 0x1304, 0x1305}
+
+// cipherSuites maps IANA cipher suite IDs to their names. Only suites
+// offered by the enumerator are listed.
+var cipherSuites = map[uint16]string{
+	0x0001: "TLS_RSA_WITH_NULL_MD5",
+	0x0002: "TLS_RSA_WITH_NULL_SHA",
+	0x0003: "TLS_RSA_EXPORT_WITH_RC4_40_MD5",
+	0x0004: "TLS_RSA_WITH_RC4_128_MD5",
+	0x0005: "TLS_RSA_WITH_RC4_128_SHA",
+	0x0006: "TLS_RSA_EXPORT_WITH_RC2_CBC_40_MD5",
+	0x0007: "TLS_RSA_WITH_IDEA_CBC_SHA",
+	0x0008: "TLS_RSA_EXPORT_WITH_DES40_CBC_SHA",
+	0x0009: "TLS_RSA_WITH_DES_CBC_SHA",
+	0x000a: "TLS_RSA_WITH_3DES_EDE_CBC_SHA",
+	0x0011: "TLS_DHE_DSS_EXPORT_WITH_DES40_CBC_SHA",
+	0x0012: "TLS_DHE_DSS_WITH_DES_CBC_SHA",
+	0x0013: "TLS_DHE_DSS_WITH_3DES_EDE_CBC_SHA",
+	0x0014: "TLS_DHE_RSA_EXPORT_WITH_DES40_CBC_SHA",
+	0x0015: "TLS_DHE_RSA_WITH_DES_CBC_SHA",
+	0x0016: "TLS_DHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	0x0017: "TLS_DH_anon_EXPORT_WITH_RC4_40_MD5",
+	0x0018: "TLS_DH_anon_WITH_RC4_128_MD5",
+	0x001b: "TLS_DH_anon_WITH_3DES_EDE_CBC_SHA",
+	0x002f: "TLS_RSA_WITH_AES_128_CBC_SHA",
+	0x0032: "TLS_DHE_DSS_WITH_AES_128_CBC_SHA",
+	0x0033: "TLS_DHE_RSA_WITH_AES_128_CBC_SHA",
+	0x0034: "TLS_DH_anon_WITH_AES_128_CBC_SHA",
+	0x0035: "TLS_RSA_WITH_AES_256_CBC_SHA",
+	0x0038: "TLS_DHE_DSS_WITH_AES_256_CBC_SHA",
+	0x0039: "TLS_DHE_RSA_WITH_AES_256_CBC_SHA",
+	0x003a: "TLS_DH_anon_WITH_AES_256_CBC_SHA",
+	0x003b: "TLS_RSA_WITH_NULL_SHA256",
+	0x003c: "TLS_RSA_WITH_AES_128_CBC_SHA256",
+	0x003d: "TLS_RSA_WITH_AES_256_CBC_SHA256",
+	0x0040: "TLS_DHE_DSS_WITH_AES_128_CBC_SHA256",
+	0x0041: "TLS_RSA_WITH_CAMELLIA_128_CBC_SHA",
+	0x0045: "TLS_DHE_RSA_WITH_CAMELLIA_128_CBC_SHA",
+	0x0067: "TLS_DHE_RSA_WITH_AES_128_CBC_SHA256",
+	0x006a: "TLS_DHE_DSS_WITH_AES_256_CBC_SHA256",
+	0x006b: "TLS_DHE_RSA_WITH_AES_256_CBC_SHA256",
+	0x0084: "TLS_RSA_WITH_CAMELLIA_256_CBC_SHA",
+	0x0088: "TLS_DHE_RSA_WITH_CAMELLIA_256_CBC_SHA",
+	0x0096: "TLS_RSA_WITH_SEED_CBC_SHA",
+	0x009c: "TLS_RSA_WITH_AES_128_GCM_SHA256",
+	0x009d: "TLS_RSA_WITH_AES_256_GCM_SHA384",
+	0x009e: "TLS_DHE_RSA_WITH_AES_128_GCM_SHA256",
+	0x009f: "TLS_DHE_RSA_WITH_AES_256_GCM_SHA384",
+	0x00a2: "TLS_DHE_DSS_WITH_AES_128_GCM_SHA256",
+	0x00a3: "TLS_DHE_DSS_WITH_AES_256_GCM_SHA384",
+	0x00a6: "TLS_DH_anon_WITH_AES_128_GCM_SHA256",
+	0x00a7: "TLS_DH_anon_WITH_AES_256_GCM_SHA384",
+	0x1301: "TLS_AES_128_GCM_SHA256",
+	0x1302: "TLS_AES_256_GCM_SHA384",
+	0x1303: "TLS_CHACHA20_POLY1305_SHA256",
+	0x1304: "TLS_AES_128_CCM_SHA256",
+	0x1305: "TLS_AES_128_CCM_8_SHA256",
+	0xc002: "TLS_ECDH_ECDSA_WITH_RC4_128_SHA",
+	0xc003: "TLS_ECDH_ECDSA_WITH_3DES_EDE_CBC_SHA",
+	0xc004: "TLS_ECDH_ECDSA_WITH_AES_128_CBC_SHA",
+	0xc005: "TLS_ECDH_ECDSA_WITH_AES_256_CBC_SHA",
+	0xc006: "TLS_ECDHE_ECDSA_WITH_NULL_SHA",
+	0xc007: "TLS_ECDHE_ECDSA_WITH_RC4_128_SHA",
+	0xc008: "TLS_ECDHE_ECDSA_WITH_3DES_EDE_CBC_SHA",
+	0xc009: "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA",
+	0xc00a: "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA",
+	0xc00c: "TLS_ECDH_RSA_WITH_RC4_128_SHA",
+	0xc00d: "TLS_ECDH_RSA_WITH_3DES_EDE_CBC_SHA",
+	0xc00e: "TLS_ECDH_RSA_WITH_AES_128_CBC_SHA",
+	0xc00f: "TLS_ECDH_RSA_WITH_AES_256_CBC_SHA",
+	0xc010: "TLS_ECDHE_RSA_WITH_NULL_SHA",
+	0xc011: "TLS_ECDHE_RSA_WITH_RC4_128_SHA",
+	0xc012: "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	0xc013: "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+	0xc014: "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+	0xc016: "TLS_ECDH_anon_WITH_RC4_128_SHA",
+	0xc017: "TLS_ECDH_anon_WITH_3DES_EDE_CBC_SHA",
+	0xc018: "TLS_ECDH_anon_WITH_AES_128_CBC_SHA",
+	0xc019: "TLS_ECDH_anon_WITH_AES_256_CBC_SHA",
+	0xc023: "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256",
+	0xc024: "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384",
+	0xc025: "TLS_ECDH_ECDSA_WITH_AES_128_CBC_SHA256",
+	0xc026: "TLS_ECDH_ECDSA_WITH_AES_256_CBC_SHA384",
+	0xc027: "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256",
+	0xc028: "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384",
+	0xc029: "TLS_ECDH_RSA_WITH_AES_128_CBC_SHA256",
+	0xc02a: "TLS_ECDH_RSA_WITH_AES_256_CBC_SHA384",
+	0xc02b: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	0xc02c: "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	0xc02d: "TLS_ECDH_ECDSA_WITH_AES_128_GCM_SHA256",
+	0xc02e: "TLS_ECDH_ECDSA_WITH_AES_256_GCM_SHA384",
+	0xc02f: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	0xc030: "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	0xc031: "TLS_ECDH_RSA_WITH_AES_128_GCM_SHA256",
+	0xc032: "TLS_ECDH_RSA_WITH_AES_256_GCM_SHA384",
+	0xc09c: "TLS_RSA_WITH_AES_128_CCM",
+	0xc09d: "TLS_RSA_WITH_AES_256_CCM",
+	0xc09e: "TLS_DHE_RSA_WITH_AES_128_CCM",
+	0xc09f: "TLS_DHE_RSA_WITH_AES_256_CCM",
+	0xc0ac: "TLS_ECDHE_ECDSA_WITH_AES_128_CCM",
+	0xc0ad: "TLS_ECDHE_ECDSA_WITH_AES_256_CCM",
+	0xcca8: "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+	0xcca9: "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
+	0xccaa: "TLS_DHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+}
+
+// legacySuites are the suites offered to SSLv3 through TLS 1.2, in
+// ascending ID order
+var legacySuites = func() []uint16 {
+	var ids []uint16
+	for id := 0; id <= 0xffff; id++ {
+		if _, ok := cipherSuites[uint16(id)]; ok && !isTLS13Suite(uint16(id)) {
+			ids = append(ids, uint16(id))
+		}
+	}
+	return ids
+}()
+
+// Named groups (RFC 8446 and RFC 7919)
+const (
+	GroupSecp256r1      uint16 = 0x0017
+	GroupSecp384r1      uint16 = 0x0018
+	GroupSecp521r1      uint16 = 0x0019
+	GroupX25519         uint16 = 0x001d
+	GroupX448           uint16 = 0x001e
+	GroupFFDHE2048      uint16 = 0x0100
+	GroupFFDHE3072      uint16 = 0x0101
+	GroupFFDHE4096      uint16 = 0x0102
+	GroupFFDHE6144      uint16 = 0x0103
+	GroupFFDHE8192      uint16 = 0x0104
+	GroupX25519MLKEM768 uint16 = 0x11ec
+)
+
+// groupNames lists the groups tested by the enumerator, in test order
+var groupNames = []struct {
+	ID   uint16
+	Name string
+}{
+	{GroupX25519MLKEM768, "X25519MLKEM768"},
+	{GroupX25519, "x25519"},
+	{GroupSecp256r1, "secp256r1"},
+	{GroupSecp384r1, "secp384r1"},
+	{GroupSecp521r1, "secp521r1"},
+	{GroupX448, "x448"},
+	{GroupFFDHE2048, "ffdhe2048"},
+	{GroupFFDHE3072, "ffdhe3072"},
+	{GroupFFDHE4096, "ffdhe4096"},
+	{GroupFFDHE6144, "ffdhe6144"},
+	{GroupFFDHE8192, "ffdhe8192"},
+}
+
+// isECGroup reports whether a group can be used by TLS 1.2 ECDHE suites
+func isECGroup(group uint16) bool {
+	switch group {
+	case GroupSecp256r1, GroupSecp384r1, GroupSecp521r1, GroupX25519, GroupX448:
+		return true
+	}
+	return false
+}
+
+// CipherSuiteName returns the IANA name of a cipher suite
+func CipherSuiteName(id uint16) string {
+	if name, ok := cipherSuites[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("UNKNOWN_0x%04X", id)
+}
+
+// CipherRating rates a cipher suite by name
+func CipherRating(name string) string {
+	switch {
+	case strings.Contains(name, "NULL"), strings.Contains(name, "EXPORT"), strings.Contains(name, "_anon_"),
+		strings.Contains(name, "_DES_"), strings.Contains(name, "RC2"):
+		return RatingInsecure
+	case strings.Contains(name, "RC4"), strings.Contains(name, "3DES"), strings.HasSuffix(name, "_MD5"):
+		return RatingWeak
+	case !strings.Contains(name, "_WITH_"):
+		// TLS 1.3 suites are all AEAD with ephemeral key exchange
+		return RatingStrong
+	case strings.Contains(name, "CBC"), !forwardSecret(name):
+		return RatingLegacy
+	default:
+		return RatingStrong
+	}
+}
+
+// forwardSecret reports whether a suite uses an ephemeral key exchange
+func forwardSecret(name string) bool {
+	return !strings.Contains(name, "_WITH_") || strings.Contains(name, "DHE_")
+}
+
+// isAEAD reports whether a suite uses an AEAD cipher
+func isAEAD(name string) bool {
+	return strings.Contains(name, "GCM") || strings.Contains(name, "CHACHA20") || strings.Contains(name, "CCM")
+}
+
+// isTLS13Suite reports whether id is a TLS 1.3 cipher suite
+func isTLS13Suite(id uint16) bool {
+	return id>>8 == 0x13
+}
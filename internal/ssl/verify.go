@@ -0,0 +1,118 @@
+package ssl
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// VerifyCertificateChain validates a presented chain (leaf first) against
+// roots, or the system roots when roots is nil, and the expected hostname.
+// Chain trust is checked first, so a presented certificate that has
+// expired only matters when no valid path avoids it; then the hostname.
+func VerifyCertificateChain(certs []*x509.Certificate, hostname string, roots *x509.CertPool, now time.Time) *models.CertValidation {
+	if len(certs) == 0 {
+		return &models.CertValidation{Verdict: models.CertVerdictInvalid, Reason: "no certificates presented"}
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	leaf := certs[0]
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	if err != nil {
+		return chainFailure(certs, err, now)
+	}
+
+	if hostname != "" {
+		if err := leaf.VerifyHostname(hostname); err != nil {
+			return &models.CertValidation{Verdict: models.CertVerdictHostnameMismatch, Reason: err.Error()}
+		}
+	}
+
+	return &models.CertValidation{Trusted: true, Verdict: models.CertVerdictOK}
+}
+
+// chainFailure classifies a chain verification error. A certificate
+// outside its validity period is expired or not yet valid. An unknown
+// authority is an untrusted root when the chain ends in a self-signed or
+// CA certificate, and a missing intermediate when the server sent no CA
+// certificate that links the leaf to a root.
+func chainFailure(certs []*x509.Certificate, err error, now time.Time) *models.CertValidation {
+	var invalid x509.CertificateInvalidError
+	if errors.As(err, &invalid) && invalid.Reason == x509.Expired {
+		return validityFailure(certs, invalid.Cert, now)
+	}
+
+	var unknown x509.UnknownAuthorityError
+	if !errors.As(err, &unknown) {
+		return &models.CertValidation{Verdict: models.CertVerdictInvalid, Reason: err.Error()}
+	}
+
+	top := certs[len(certs)-1]
+	switch {
+	case isSelfSigned(top):
+		return &models.CertValidation{
+			Verdict: models.CertVerdictUntrustedRoot,
+			Reason:  fmt.Sprintf("chain ends in self-signed certificate %q that is not a trusted root", top.Subject.String()),
+		}
+	case len(certs) > 1 && top.IsCA:
+		return &models.CertValidation{
+			Verdict: models.CertVerdictUntrustedRoot,
+			Reason:  fmt.Sprintf("issuer %q of %q is not a trusted root", top.Issuer.String(), top.Subject.String()),
+		}
+	default:
+		return &models.CertValidation{
+			Verdict: models.CertVerdictMissingIntermediate,
+			Reason:  fmt.Sprintf("no certificate for issuer %q was presented and it is not a trusted root", top.Issuer.String()),
+		}
+	}
+}
+
+// validityFailure reports the certificate that is outside its validity
+// period on every path the verifier tried
+func validityFailure(certs []*x509.Certificate, cert *x509.Certificate, now time.Time) *models.CertValidation {
+	role := "chain certificate"
+	for i, presented := range certs {
+		if presented.Equal(cert) {
+			role = chainRole(i)
+			break
+		}
+	}
+	if now.Before(cert.NotBefore) {
+		return &models.CertValidation{
+			Verdict: models.CertVerdictNotYetValid,
+			Reason:  fmt.Sprintf("%s %q is not valid until %s", role, cert.Subject.String(), cert.NotBefore.UTC().Format(time.RFC3339)),
+		}
+	}
+	return &models.CertValidation{
+		Verdict: models.CertVerdictExpired,
+		Reason:  fmt.Sprintf("%s %q expired on %s", role, cert.Subject.String(), cert.NotAfter.UTC().Format(time.RFC3339)),
+	}
+}
+
+// isSelfSigned reports whether a certificate is signed by its own key
+func isSelfSigned(cert *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		return false
+	}
+	return cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// chainRole names a certificate by its position in the chain
+func chainRole(i int) string {
+	if i == 0 {
+		return "certificate"
+	}
+	return fmt.Sprintf("chain certificate %d", i+1)
+}
@@ -0,0 +1,176 @@
+package ssl
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// testCA is a certificate and the key it signs with
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// issue creates a certificate for template signed by parent, or
+// self-signed when parent is nil. Unset validity defaults to 2024.
+func issue(t testing.TB, template *x509.Certificate, parent *testCA) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if template.SerialNumber == nil {
+		template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	}
+	if template.NotBefore.IsZero() {
+		template.NotBefore = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if template.NotAfter.IsZero() {
+		template.NotAfter = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if template.IsCA {
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+// serverCert is a template for a TLS server certificate for names
+func serverCert(names ...string) *x509.Certificate {
+	return &x509.Certificate{
+		Subject:     pkix.Name{CommonName: names[0]},
+		DNSNames:    names,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+}
+
+func TestVerifyCertificateChain(t *testing.T) {
+	root := issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "Test Root"}, IsCA: true}, nil)
+	intermediate := issue(t, &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "Test Intermediate"},
+		IsCA:     true,
+		NotAfter: time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC),
+	}, root)
+	leaf := issue(t, serverCert("www.example.com", "example.com"), intermediate).cert
+	selfSigned := issue(t, serverCert("router.local"), nil).cert
+	clientOnly := issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "www.example.com"},
+		DNSNames:    []string{"www.example.com"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, intermediate).cert
+
+	trusted := x509.NewCertPool()
+	trusted.AddCert(root.cert)
+	untrusted := x509.NewCertPool()
+	june := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		certs    []*x509.Certificate
+		hostname string
+		roots    *x509.CertPool
+		now      time.Time
+		want     models.CertValidation
+	}{
+		{
+			"valid", []*x509.Certificate{leaf, intermediate.cert}, "example.com", trusted, june,
+			models.CertValidation{Trusted: true, Verdict: models.CertVerdictOK},
+		},
+		{
+			"no hostname to check", []*x509.Certificate{leaf, intermediate.cert}, "", trusted, june,
+			models.CertValidation{Trusted: true, Verdict: models.CertVerdictOK},
+		},
+		{
+			"hostname mismatch", []*x509.Certificate{leaf, intermediate.cert}, "mail.example.com", trusted, june,
+			models.CertValidation{
+				Verdict: models.CertVerdictHostnameMismatch,
+				Reason:  "x509: certificate is valid for www.example.com, example.com, not mail.example.com",
+			},
+		},
+		{
+			"missing intermediate", []*x509.Certificate{leaf}, "example.com", trusted, june,
+			models.CertValidation{
+				Verdict: models.CertVerdictMissingIntermediate,
+				Reason:  `no certificate for issuer "CN=Test Intermediate" was presented and it is not a trusted root`,
+			},
+		},
+		{
+			"root presented but not trusted", []*x509.Certificate{leaf, intermediate.cert, root.cert}, "example.com", untrusted, june,
+			models.CertValidation{
+				Verdict: models.CertVerdictUntrustedRoot,
+				Reason:  `chain ends in self-signed certificate "CN=Test Root" that is not a trusted root`,
+			},
+		},
+		{
+			"issuer of the last CA not trusted", []*x509.Certificate{leaf, intermediate.cert}, "example.com", untrusted, june,
+			models.CertValidation{
+				Verdict: models.CertVerdictUntrustedRoot,
+				Reason:  `issuer "CN=Test Root" of "CN=Test Intermediate" is not a trusted root`,
+			},
+		},
+		{
+			"self-signed leaf", []*x509.Certificate{selfSigned}, "router.local", trusted, june,
+			models.CertValidation{
+				Verdict: models.CertVerdictUntrustedRoot,
+				Reason:  `chain ends in self-signed certificate "CN=router.local" that is not a trusted root`,
+			},
+		},
+		{
+			"expired leaf", []*x509.Certificate{leaf, intermediate.cert}, "example.com", trusted, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+			models.CertValidation{
+				Verdict: models.CertVerdictExpired,
+				Reason:  `certificate "CN=www.example.com" expired on 2025-01-01T00:00:00Z`,
+			},
+		},
+		{
+			"expired intermediate", []*x509.Certificate{leaf, intermediate.cert}, "example.com", trusted, time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+			models.CertValidation{
+				Verdict: models.CertVerdictExpired,
+				Reason:  `chain certificate 2 "CN=Test Intermediate" expired on 2024-09-01T00:00:00Z`,
+			},
+		},
+		{
+			"not yet valid", []*x509.Certificate{leaf, intermediate.cert}, "example.com", trusted, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC),
+			models.CertValidation{
+				Verdict: models.CertVerdictNotYetValid,
+				Reason:  `certificate "CN=www.example.com" is not valid until 2024-01-01T00:00:00Z`,
+			},
+		},
+		{
+			"wrong key usage", []*x509.Certificate{clientOnly, intermediate.cert}, "www.example.com", trusted, june,
+			models.CertValidation{
+				Verdict: models.CertVerdictInvalid,
+				Reason:  "x509: certificate specifies an incompatible key usage",
+			},
+		},
+		{
+			"nothing presented", nil, "example.com", trusted, june,
+			models.CertValidation{Verdict: models.CertVerdictInvalid, Reason: "no certificates presented"},
+		},
+	}
+	for _, tt := range tests {
+		if got := VerifyCertificateChain(tt.certs, tt.hostname, tt.roots, tt.now); *got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package ssl
+
+import (
+	"bufio"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// VHostCertificates repeats the TLS handshake once per name, sending it as
+// SNI, and records which certificate each name returns. defaultFingerprint
+// is the certificate returned for the scan's own server name; names that
+// return a different one point at a separately configured virtual host.
+func VHostCertificates(address string, names []string, defaultFingerprint string, opts ProbeOptions) []models.VHostCert {
+	var results []models.VHostCert
+	for _, name := range names {
+		result := models.VHostCert{Name: name}
+
+		opts.ServerName = name
+		leaf, err := vhostCertificate(address, opts)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		result.Subject = leaf.Subject.String()
+		result.Fingerprint = calculateFingerprint(leaf.Raw)
+		result.Different = result.Fingerprint != defaultFingerprint
+		result.NameMatches = leaf.VerifyHostname(name) == nil
+		results = append(results, result)
+	}
+	return results
+}
+
+// vhostCertificate runs one handshake with opts.ServerName as SNI and
+// returns the leaf certificate
+func vhostCertificate(address string, opts ProbeOptions) (*x509.Certificate, error) {
+	conn, err := net.DialTimeout("tcp", address, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if opts.StartTLS != "" {
+		conn.SetDeadline(time.Now().Add(opts.Timeout))
+		r := bufio.NewReader(conn)
+		if err := StartTLS(conn, r, opts.StartTLS, opts.ServerName, ""); err != nil {
+			return nil, err
+		}
+		conn.SetDeadline(time.Time{})
+	}
+
+	state, err := Handshake(conn, opts.ServerName, nil, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	if len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("no certificate presented")
+	}
+	return state.PeerCertificates[0], nil
+}
+
+// VHostNames merges explicit names with the DNS names of a certificate,
+// skipping wildcards, duplicates and the name already used for the scan
+func VHostNames(explicit []string, info *models.SSLCertInfo, fromCert bool, exclude string) []string {
+	seen := map[string]bool{strings.ToLower(exclude): true}
+	var names []string
+	add := func(name string) {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || strings.HasPrefix(name, "*.") || seen[name] {
+			return
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	for _, name := range explicit {
+		add(name)
+	}
+	if fromCert && info != nil {
+		for _, name := range info.DNSNames {
+			add(name)
+		}
+	}
+	return names
+}
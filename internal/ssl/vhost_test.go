@@ -0,0 +1,92 @@
+package ssl
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/internal/testutil"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// vhostServer serves the api certificate for api.example.com, refuses
+// blocked.example.com and serves the default certificate to any other
+// name. A postgres server first answers an SSLRequest.
+func vhostServer(t *testing.T, postgres bool, defaultCert, api *testCA) string {
+	tlsCert := func(c *testCA) *tls.Certificate {
+		return &tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+	}
+	config := &tls.Config{GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		switch hello.ServerName {
+		case "api.example.com":
+			return tlsCert(api), nil
+		case "blocked.example.com":
+			return nil, fmt.Errorf("no certificate for %s", hello.ServerName)
+		}
+		return tlsCert(defaultCert), nil
+	}}
+	return testutil.Serve(t, func(conn net.Conn) {
+		if postgres {
+			if _, err := io.ReadFull(conn, make([]byte, 8)); err != nil {
+				return
+			}
+			conn.Write([]byte("S"))
+		}
+		tls.Server(conn, config).Handshake()
+	})
+}
+
+func TestVHostCertificates(t *testing.T) {
+	defaultCert := issue(t, serverCert("example.com", "www.example.com"), nil)
+	api := issue(t, serverCert("api.example.com"), nil)
+	defaultFingerprint := calculateFingerprint(defaultCert.cert.Raw)
+	names := []string{"www.example.com", "api.example.com", "legacy.example.net", "blocked.example.com"}
+
+	want := []models.VHostCert{
+		{Name: "www.example.com", Subject: "CN=example.com", Fingerprint: defaultFingerprint, NameMatches: true},
+		{
+			Name: "api.example.com", Subject: "CN=api.example.com", Fingerprint: calculateFingerprint(api.cert.Raw),
+			Different: true, NameMatches: true,
+		},
+		{Name: "legacy.example.net", Subject: "CN=example.com", Fingerprint: defaultFingerprint},
+		{Name: "blocked.example.com", Error: "remote error: tls: internal error"},
+	}
+	for _, postgres := range []bool{false, true} {
+		opts := ProbeOptions{Timeout: 2 * time.Second}
+		if postgres {
+			opts.StartTLS = ProtoPostgres
+		}
+		address := vhostServer(t, postgres, defaultCert, api)
+		if got := VHostCertificates(address, names, defaultFingerprint, opts); !reflect.DeepEqual(got, want) {
+			t.Errorf("STARTTLS %q: got %+v\nwant %+v", opts.StartTLS, got, want)
+		}
+	}
+}
+
+func TestVHostNames(t *testing.T) {
+	info := &models.SSLCertInfo{DNSNames: []string{"example.com", "*.example.com", "WWW.example.com", "mail.example.com"}}
+	tests := []struct {
+		name     string
+		explicit []string
+		info     *models.SSLCertInfo
+		fromCert bool
+		exclude  string
+		want     []string
+	}{
+		{"explicit only", []string{" api.example.com ", "", "API.example.com"}, info, false, "example.com", []string{"api.example.com"}},
+		{
+			"with certificate names", []string{"www.example.com"}, info, true, "Example.com",
+			[]string{"www.example.com", "mail.example.com"},
+		},
+		{"no certificate", []string{"www.example.com"}, nil, true, "", []string{"www.example.com"}},
+	}
+	for _, tt := range tests {
+		if got := VHostNames(tt.explicit, tt.info, tt.fromCert, tt.exclude); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Package testutil holds the helpers the package tests share: testdata
+// fixtures and local stand-in servers
+package testutil
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Fixture returns the contents of testdata/name in the package under test
+func Fixture(t testing.TB, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// Serve listens on a loopback port and runs handle for every connection,
+// closing it afterwards. Connections get a two second deadline so a stuck
+// exchange fails the test instead of hanging it. The listener is closed
+// when the test ends.
+func Serve(t testing.TB, handle func(conn net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(2 * time.Second))
+				handle(conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
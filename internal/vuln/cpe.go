@@ -0,0 +1,139 @@
+package vuln
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CPE holds the fields of a CPE 2.3 formatted string that matching uses,
+// unescaped and lowercased. Unset fields are "*".
+type CPE struct {
+	Part    string
+	Vendor  string
+	Product string
+	Version string
+	Update  string
+}
+
+// ParseCPE parses a "cpe:2.3:part:vendor:product:version:update:..."
+// string. Trailing fields may be omitted.
+func ParseCPE(s string) (CPE, error) {
+	rest, ok := strings.CutPrefix(s, "cpe:2.3:")
+	if !ok {
+		return CPE{}, fmt.Errorf("not a CPE 2.3 string: %q", s)
+	}
+
+	// Split on colons that are not escaped
+	var fields []string
+	var b strings.Builder
+	for i := 0; i < len(rest); i++ {
+		switch c := rest[i]; {
+		case c == '\\' && i+1 < len(rest):
+			i++
+			b.WriteByte(rest[i])
+		case c == ':':
+			fields = append(fields, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	fields = append(fields, b.String())
+	if len(fields) < 3 || fields[1] == "" || fields[2] == "" {
+		return CPE{}, fmt.Errorf("CPE without vendor and product: %q", s)
+	}
+
+	for len(fields) < 5 {
+		fields = append(fields, "*")
+	}
+	for i := range fields {
+		fields[i] = strings.ToLower(fields[i])
+		if fields[i] == "" {
+			fields[i] = "*"
+		}
+	}
+	return CPE{Part: fields[0], Vendor: fields[1], Product: fields[2], Version: fields[3], Update: fields[4]}, nil
+}
+
+// CompareVersions compares two version strings, returning -1, 0 or 1.
+// Versions are split into runs of digits and of letters, ignoring
+// separators; digit runs compare as numbers and letter runs as text, so
+// 1.10 > 1.9, 1.1.1k > 1.1.1 > 1.1 and 1.1.0 == 1.1. A pre-release tag
+// after the common base sorts before it: 2.0rc1 < 2.0 and 2.0.0beta < 2.0.
+func CompareVersions(a, b string) int {
+	as, bs := versionParts(a), versionParts(b)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := comparePart(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	n := min(len(as), len(bs))
+	return remainder(as[n:]) - remainder(bs[n:])
+}
+
+// preRelease lists the letter runs that mark a version before its base
+// release. Other letters, such as OpenSSL's 1.1.1k or OpenSSH's 8.9p1,
+// mark later releases.
+var preRelease = map[string]bool{
+	"alpha": true, "beta": true, "rc": true, "pre": true,
+	"preview": true, "dev": true, "snapshot": true,
+}
+
+// remainder ranks what one version has beyond the common base: 1 for a
+// later release, -1 for a pre-release and 0 for trailing zeros only
+func remainder(parts []string) int {
+	for _, part := range parts {
+		switch {
+		case preRelease[part]:
+			return -1
+		case strings.Trim(part, "0") != "":
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionParts splits a version into digit and letter runs
+func versionParts(version string) []string {
+	version = strings.ToLower(version)
+	var parts []string
+	start := -1
+	digits := false
+	for i, r := range version {
+		isDigit := r >= '0' && r <= '9'
+		isLetter := r >= 'a' && r <= 'z'
+		if start >= 0 && (!(isDigit || isLetter) || isDigit != digits) {
+			parts = append(parts, version[start:i])
+			start = -1
+		}
+		if start < 0 && (isDigit || isLetter) {
+			start, digits = i, isDigit
+		}
+	}
+	if start >= 0 {
+		parts = append(parts, version[start:])
+	}
+	return parts
+}
+
+// comparePart compares two runs; a number sorts after text
+func comparePart(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		if an != bn {
+			if an < bn {
+				return -1
+			}
+			return 1
+		}
+		return 0
+	case aErr == nil:
+		return 1
+	case bErr == nil:
+		return -1
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,83 @@
+package vuln
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.1", "1.1.0", 0},
+		{"9.3.0", "9.3", 0},
+		{"1.10", "1.9", 1},
+		{"1.9", "1.10", -1},
+		{"2.4.49", "2.4.50", -1},
+		{"1.1.1k", "1.1.1", 1},
+		{"1.1.1k", "1.1.1j", 1},
+		{"1.1.1", "1.1", 1},
+		{"8.9p1", "8.9", 1},
+		{"2.0rc1", "2.0", -1},
+		{"2.0", "2.0rc1", 1},
+		{"2.0-rc1", "2.0.0", -1},
+		{"2.0.0beta", "2.0", -1},
+		{"2.0alpha1", "2.0beta1", -1},
+		{"2.0beta2", "2.0rc1", -1},
+		{"2.0rc1", "2.0rc2", -1},
+		{"2.0rc1", "1.9", 1},
+		{"2.0rc1", "2.0.1", -1},
+		{"1.0.0-dev", "1.0.0", -1},
+		{"V1.2", "v1.2", 0},
+		{"", "", 0},
+		{"1", "", 1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseCPE(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    CPE
+		wantErr bool
+	}{
+		{
+			in:   "cpe:2.3:a:apache:http_server:2.4.49:*:*:*:*:*:*:*",
+			want: CPE{Part: "a", Vendor: "apache", Product: "http_server", Version: "2.4.49", Update: "*"},
+		},
+		{
+			in:   "cpe:2.3:a:OpenBSD:OpenSSH:8.9:p1",
+			want: CPE{Part: "a", Vendor: "openbsd", Product: "openssh", Version: "8.9", Update: "p1"},
+		},
+		{
+			in:   "cpe:2.3:a:nginx:nginx",
+			want: CPE{Part: "a", Vendor: "nginx", Product: "nginx", Version: "*", Update: "*"},
+		},
+		{
+			in:   `cpe:2.3:a:vendor:product\:name:1.0::`,
+			want: CPE{Part: "a", Vendor: "vendor", Product: "product:name", Version: "1.0", Update: "*"},
+		},
+		{in: "cpe:/a:apache:http_server:2.4.49", wantErr: true},
+		{in: "cpe:2.3:a:apache", wantErr: true},
+		{in: "cpe:2.3:a::http_server", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCPE(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseCPE(%q) = %+v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseCPE(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseCPE(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
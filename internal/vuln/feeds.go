@@ -0,0 +1,285 @@
+package vuln
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// feedFormat names the format of a feed file from its extension, or ""
+func feedFormat(name string) string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".gz")
+	switch {
+	case strings.HasSuffix(name, ".json"):
+		return "nvd"
+	case strings.HasSuffix(name, ".csv"):
+		return "csv"
+	}
+	return ""
+}
+
+// loadFile parses one feed file
+func loadFile(path string) ([]*Entry, error) {
+	format := feedFormat(path)
+	if format == "" {
+		return nil, fmt.Errorf("%s: unknown feed format, expected .json or .csv", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(strings.ToLower(path), ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var entries []*Entry
+	if format == "nvd" {
+		entries, err = parseNVD(r)
+	} else {
+		entries, err = parseCSV(r)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return entries, nil
+}
+
+// nvdFeed is the part of an NVD CVE API 2.0 response, or data feed, that
+// matching needs
+type nvdFeed struct {
+	Vulnerabilities []struct {
+		CVE struct {
+			ID           string `json:"id"`
+			Descriptions []struct {
+				Lang  string `json:"lang"`
+				Value string `json:"value"`
+			} `json:"descriptions"`
+			Metrics struct {
+				V40 []nvdMetric `json:"cvssMetricV40"`
+				V31 []nvdMetric `json:"cvssMetricV31"`
+				V30 []nvdMetric `json:"cvssMetricV30"`
+				V2  []nvdMetric `json:"cvssMetricV2"`
+			} `json:"metrics"`
+			Configurations []struct {
+				Nodes []struct {
+					CPEMatch []nvdCPEMatch `json:"cpeMatch"`
+				} `json:"nodes"`
+			} `json:"configurations"`
+		} `json:"cve"`
+	} `json:"vulnerabilities"`
+}
+
+// nvdMetric is one CVSS score; v2 keeps its severity outside cvssData
+type nvdMetric struct {
+	Type     string `json:"type"` // Primary or Secondary
+	CVSSData struct {
+		Version      string  `json:"version"`
+		BaseScore    float64 `json:"baseScore"`
+		BaseSeverity string  `json:"baseSeverity"`
+	} `json:"cvssData"`
+	BaseSeverity string `json:"baseSeverity"`
+}
+
+// nvdCPEMatch is one affected CPE with its version range
+type nvdCPEMatch struct {
+	Vulnerable            bool   `json:"vulnerable"`
+	Criteria              string `json:"criteria"`
+	VersionStartIncluding string `json:"versionStartIncluding"`
+	VersionStartExcluding string `json:"versionStartExcluding"`
+	VersionEndIncluding   string `json:"versionEndIncluding"`
+	VersionEndExcluding   string `json:"versionEndExcluding"`
+}
+
+// parseNVD reads an NVD 2.0 JSON document. Only CPEs marked vulnerable are
+// kept; platform conditions ("running on") are ignored.
+func parseNVD(r io.Reader) ([]*Entry, error) {
+	var feed nvdFeed
+	if err := json.NewDecoder(r).Decode(&feed); err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, item := range feed.Vulnerabilities {
+		cve := item.CVE
+		var summary string
+		for _, desc := range cve.Descriptions {
+			if desc.Lang == "en" {
+				summary = desc.Value
+				break
+			}
+		}
+
+		score, version, severity := 0.0, "", ""
+		for _, metrics := range [][]nvdMetric{cve.Metrics.V31, cve.Metrics.V40, cve.Metrics.V30, cve.Metrics.V2} {
+			if metric := primaryMetric(metrics); metric != nil {
+				score = metric.CVSSData.BaseScore
+				version = metric.CVSSData.Version
+				severity = metric.CVSSData.BaseSeverity
+				if severity == "" {
+					severity = metric.BaseSeverity
+				}
+				break
+			}
+		}
+		severity = normalizeSeverity(severity, score)
+
+		for _, config := range cve.Configurations {
+			for _, node := range config.Nodes {
+				for _, match := range node.CPEMatch {
+					if !match.Vulnerable {
+						continue
+					}
+					cpe, err := ParseCPE(match.Criteria)
+					if err != nil {
+						return nil, fmt.Errorf("%s: %v", cve.ID, err)
+					}
+					entries = append(entries, &Entry{
+						CVE:            cve.ID,
+						Summary:        summary,
+						CVSS:           score,
+						CVSSVersion:    version,
+						Severity:       severity,
+						Part:           cpe.Part,
+						Vendor:         cpe.Vendor,
+						Product:        cpe.Product,
+						Version:        cpe.Version,
+						Update:         cpe.Update,
+						StartIncluding: match.VersionStartIncluding,
+						StartExcluding: match.VersionStartExcluding,
+						EndIncluding:   match.VersionEndIncluding,
+						EndExcluding:   match.VersionEndExcluding,
+					})
+				}
+			}
+		}
+	}
+	return entries, nil
+}
+
+// primaryMetric picks the score from the NVD itself over scores from
+// other sources
+func primaryMetric(metrics []nvdMetric) *nvdMetric {
+	for i := range metrics {
+		if metrics[i].Type == "Primary" {
+			return &metrics[i]
+		}
+	}
+	if len(metrics) > 0 {
+		return &metrics[0]
+	}
+	return nil
+}
+
+// CSV columns. Rows name a CPE, an optional version range and the CVE.
+const (
+	colCPE            = "cpe"
+	colStartIncluding = "version_start_including"
+	colStartExcluding = "version_start_excluding"
+	colEndIncluding   = "version_end_including"
+	colEndExcluding   = "version_end_excluding"
+	colCVE            = "cve"
+	colCVSS           = "cvss"
+	colSeverity       = "severity"
+	colSummary        = "summary"
+)
+
+// parseCSV reads a CSV feed. The header row names the columns; cpe and cve
+// are required and the rest are optional.
+func parseCSV(r io.Reader) ([]*Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("header: %v", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{colCPE, colCVE} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("header: missing %q column", required)
+		}
+	}
+
+	var entries []*Entry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		cpe, err := ParseCPE(field(colCPE))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		id := field(colCVE)
+		if id == "" {
+			return nil, fmt.Errorf("line %d: empty cve", line)
+		}
+		var score float64
+		if value := field(colCVSS); value != "" {
+			if score, err = strconv.ParseFloat(value, 64); err != nil || score < 0 || score > 10 {
+				return nil, fmt.Errorf("line %d: invalid cvss %q", line, value)
+			}
+		}
+
+		entries = append(entries, &Entry{
+			CVE:            id,
+			Summary:        field(colSummary),
+			CVSS:           score,
+			Severity:       normalizeSeverity(field(colSeverity), score),
+			Part:           cpe.Part,
+			Vendor:         cpe.Vendor,
+			Product:        cpe.Product,
+			Version:        cpe.Version,
+			Update:         cpe.Update,
+			StartIncluding: field(colStartIncluding),
+			StartExcluding: field(colStartExcluding),
+			EndIncluding:   field(colEndIncluding),
+			EndExcluding:   field(colEndExcluding),
+		})
+	}
+	return entries, nil
+}
+
+// normalizeSeverity lowercases a feed severity, mapping NVD's NONE to
+// info, and falls back to the score when the feed gives none
+func normalizeSeverity(severity string, score float64) string {
+	switch severity = strings.ToLower(severity); severity {
+	case "none":
+		return models.SeverityInfo
+	case models.SeverityInfo, models.SeverityLow, models.SeverityMedium, models.SeverityHigh, models.SeverityCritical:
+		return severity
+	}
+	return ScoreSeverity(score)
+}
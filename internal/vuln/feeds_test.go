@@ -0,0 +1,140 @@
+package vuln
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNVD(t *testing.T) {
+	entries, err := loadFile(filepath.Join("testdata", "nvd.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Entry{
+		{
+			CVE: "CVE-2024-6387", Summary: "A signal handler race condition was found in sshd.",
+			CVSS: 8.1, CVSSVersion: "3.1", Severity: "high",
+			Part: "a", Vendor: "openbsd", Product: "openssh", Version: "*", Update: "*",
+			StartIncluding: "8.5", EndExcluding: "9.8",
+		},
+		{
+			CVE: "CVE-2024-6387", Summary: "A signal handler race condition was found in sshd.",
+			CVSS: 8.1, CVSSVersion: "3.1", Severity: "high",
+			Part: "a", Vendor: "openbsd", Product: "openssh", Version: "4.3", Update: "p1",
+		},
+		{
+			CVE: "CVE-2023-38408", Summary: "PKCS#11 feature in ssh-agent.",
+			CVSS: 9.8, CVSSVersion: "3.1", Severity: "critical",
+			Part: "a", Vendor: "openbsd", Product: "openssh", Version: "*", Update: "*",
+			EndExcluding: "9.3",
+		},
+		{
+			CVE: "CVE-2010-0001", Summary: "An old issue scored only under CVSS v2.",
+			CVSS: 0, CVSSVersion: "2.0", Severity: "info",
+			Part: "a", Vendor: "gnu", Product: "gzip", Version: "1.3.12", Update: "*",
+		},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if !reflect.DeepEqual(*entry, want[i]) {
+			t.Errorf("entry %d:\n got %+v\nwant %+v", i, *entry, want[i])
+		}
+	}
+}
+
+func TestParseNVDInvalidCPE(t *testing.T) {
+	feed := `{"vulnerabilities": [{"cve": {"id": "CVE-1", "configurations": [{"nodes": [{"cpeMatch": [{"vulnerable": true, "criteria": "cpe:/a:x:y"}]}]}]}}]}`
+	if _, err := parseNVD(strings.NewReader(feed)); err == nil || !strings.Contains(err.Error(), "CVE-1") {
+		t.Errorf("got error %v, want one naming CVE-1", err)
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []Entry
+		wantErr string
+	}{
+		{
+			name: "range and exact version",
+			in: `# local feed
+cpe,version_start_including,version_end_excluding,cve,cvss,summary
+cpe:2.3:a:redis:redis,7.0.0,7.2.5,CVE-2024-31449,8.8,"Lua bit library stack overflow, remote code execution"
+cpe:2.3:a:nginx:nginx:1.25.3,,,CVE-TEST-1,,exact version
+`,
+			want: []Entry{
+				{
+					CVE: "CVE-2024-31449", Summary: "Lua bit library stack overflow, remote code execution",
+					CVSS: 8.8, Severity: "high",
+					Part: "a", Vendor: "redis", Product: "redis", Version: "*", Update: "*",
+					StartIncluding: "7.0.0", EndExcluding: "7.2.5",
+				},
+				{
+					CVE: "CVE-TEST-1", Summary: "exact version", Severity: "medium",
+					Part: "a", Vendor: "nginx", Product: "nginx", Version: "1.25.3", Update: "*",
+				},
+			},
+		},
+		{
+			name: "columns in any order, severity given",
+			in: `CVE, Severity, CPE, Version_End_Including
+CVE-TEST-2, CRITICAL, cpe:2.3:a:acme:widget, 2.0rc1
+`,
+			want: []Entry{
+				{
+					CVE: "CVE-TEST-2", Severity: "critical",
+					Part: "a", Vendor: "acme", Product: "widget", Version: "*", Update: "*",
+					EndIncluding: "2.0rc1",
+				},
+			},
+		},
+		{
+			name:    "missing cve column",
+			in:      "cpe,cvss\ncpe:2.3:a:acme:widget,5.0\n",
+			wantErr: `missing "cve" column`,
+		},
+		{
+			name:    "invalid cpe",
+			in:      "cpe,cve\ncpe:2.3:a:acme,CVE-TEST-3\n",
+			wantErr: "line 2",
+		},
+		{
+			name:    "empty cve",
+			in:      "cpe,cve\ncpe:2.3:a:acme:widget,\n",
+			wantErr: "empty cve",
+		},
+		{
+			name:    "cvss out of range",
+			in:      "cpe,cve,cvss\ncpe:2.3:a:acme:widget,CVE-TEST-4,11\n",
+			wantErr: "invalid cvss",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := parseCSV(strings.NewReader(tt.in))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(tt.want) {
+				t.Fatalf("got %d entries, want %d", len(entries), len(tt.want))
+			}
+			for i, entry := range entries {
+				if !reflect.DeepEqual(*entry, tt.want[i]) {
+					t.Errorf("entry %d:\n got %+v\nwant %+v", i, *entry, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+// Package vuln matches identified products against a local vulnerability
+// feed, so likely CVEs are reported without any network calls
+package vuln
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// Entry says that one CVE affects a product, optionally within a range of
+// versions. An empty bound is open.
+type Entry struct {
+	CVE         string
+	Summary     string
+	CVSS        float64
+	CVSSVersion string
+	Severity    string
+
+	Part    string
+	Vendor  string
+	Product string
+	Version string // exact version, or "*" for any version within the range
+	Update  string // exact update, or "*"
+
+	StartIncluding string
+	StartExcluding string
+	EndIncluding   string
+	EndExcluding   string
+}
+
+// Database holds feed entries indexed by vendor and product
+type Database struct {
+	entries map[string][]*Entry
+	count   int
+}
+
+// Load reads a feed file, or every feed file in a directory. NVD 2.0 JSON
+// files end in .json and CSV files in .csv; either may be gzipped (.gz).
+func Load(path string) (*Database, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		dirEntries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, entry := range dirEntries {
+			if !entry.IsDir() && feedFormat(entry.Name()) != "" {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("%s: no .json or .csv feed files", path)
+		}
+	}
+
+	db := &Database{entries: make(map[string][]*Entry)}
+	for _, file := range files {
+		entries, err := loadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			db.add(entry)
+		}
+	}
+	return db, nil
+}
+
+// Len returns the number of entries in the database
+func (db *Database) Len() int {
+	return db.count
+}
+
+// add indexes an entry
+func (db *Database) add(entry *Entry) {
+	key := entry.Vendor + ":" + entry.Product
+	db.entries[key] = append(db.entries[key], entry)
+	db.count++
+}
+
+// aliases lists other vendor:product names the NVD has filed a product
+// under, such as nginx since F5 took it over
+var aliases = map[string][]string{
+	"nginx:nginx": {"f5:nginx"},
+}
+
+// Match lists the CVEs affecting the products, highest score first. Each
+// CVE is listed once, for the first product it affects. Products without
+// a version are skipped, since every versioned entry would match them.
+func (db *Database) Match(products []models.Product) []models.Vulnerability {
+	var vulns []models.Vulnerability
+	seen := make(map[string]bool)
+	for _, p := range products {
+		cpe, err := ParseCPE(p.CPE)
+		if err != nil || cpe.Version == "*" || cpe.Version == "-" {
+			continue
+		}
+		key := cpe.Vendor + ":" + cpe.Product
+		var entries []*Entry
+		for _, name := range append([]string{key}, aliases[key]...) {
+			entries = append(entries, db.entries[name]...)
+		}
+		for _, entry := range entries {
+			if seen[entry.CVE] || !entry.matches(cpe) {
+				continue
+			}
+			seen[entry.CVE] = true
+			name := p.Name
+			if p.Version != "" {
+				name += " " + p.Version
+			}
+			vulns = append(vulns, models.Vulnerability{
+				ID:          entry.CVE,
+				Product:     name,
+				CPE:         p.CPE,
+				CVSS:        entry.CVSS,
+				CVSSVersion: entry.CVSSVersion,
+				Severity:    entry.Severity,
+				Summary:     entry.Summary,
+			})
+		}
+	}
+
+	sort.SliceStable(vulns, func(i, j int) bool {
+		if vulns[i].CVSS != vulns[j].CVSS {
+			return vulns[i].CVSS > vulns[j].CVSS
+		}
+		return vulns[i].ID < vulns[j].ID
+	})
+	return vulns
+}
+
+// matches reports whether the entry covers a product CPE
+func (entry *Entry) matches(cpe CPE) bool {
+	if entry.Part != "*" && entry.Part != cpe.Part {
+		return false
+	}
+	// A product without a known update matches entries for the release
+	// itself ("-")
+	if entry.Update != "*" && entry.Update != cpe.Update && !(entry.Update == "-" && cpe.Update == "*") {
+		return false
+	}
+	if entry.Version != "*" {
+		return entry.Version == cpe.Version
+	}
+
+	// Ranges compare the version and update together, so OpenSSH 9.3 p1
+	// is checked as 9.3p1 against bounds like "9.3p2"
+	version := cpe.Version
+	if cpe.Update != "*" && cpe.Update != "-" {
+		version += cpe.Update
+	}
+	if entry.StartIncluding != "" && CompareVersions(version, entry.StartIncluding) < 0 {
+		return false
+	}
+	if entry.StartExcluding != "" && CompareVersions(version, entry.StartExcluding) <= 0 {
+		return false
+	}
+	if entry.EndIncluding != "" && CompareVersions(version, entry.EndIncluding) > 0 {
+		return false
+	}
+	if entry.EndExcluding != "" && CompareVersions(version, entry.EndExcluding) >= 0 {
+		return false
+	}
+	return true
+}
+
+// Maximum summary length kept in a finding's detail
+const maxFindingSummary = 200
+
+// Findings turns matched CVEs into findings, one per CVE, at the CVE's
+// severity
+func Findings(vulns []models.Vulnerability) []models.Finding {
+	var findings []models.Finding
+	for _, v := range vulns {
+		detail := v.Summary
+		if len(detail) > maxFindingSummary {
+			detail = strings.TrimSpace(strings.ToValidUTF8(detail[:maxFindingSummary], "")) + "..."
+		}
+		if v.CVSS > 0 {
+			detail = fmt.Sprintf("CVSS %.1f: %s", v.CVSS, detail)
+		}
+		findings = append(findings, models.Finding{
+			ID:       v.ID,
+			Title:    v.Product + " is affected by " + v.ID,
+			Severity: v.Severity,
+			Detail:   detail,
+		})
+	}
+	return findings
+}
+
+// ScoreSeverity maps a CVSS base score to a severity level using the CVSS
+// v3 rating scale. Unscored CVEs are medium.
+func ScoreSeverity(score float64) string {
+	switch {
+	case score >= 9:
+		return models.SeverityCritical
+	case score >= 7:
+		return models.SeverityHigh
+	case score >= 4:
+		return models.SeverityMedium
+	case score > 0:
+		return models.SeverityLow
+	default:
+		return models.SeverityMedium
+	}
+}
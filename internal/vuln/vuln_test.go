@@ -0,0 +1,42 @@
+package vuln
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+func TestMatch(t *testing.T) {
+	db := &Database{entries: make(map[string][]*Entry)}
+	for _, entry := range []*Entry{
+		{CVE: "CVE-2024-7347", CVSS: 4.7, Part: "a", Vendor: "f5", Product: "nginx", Version: "*", Update: "*", StartIncluding: "1.5.13", EndExcluding: "1.27.1"},
+		{CVE: "CVE-2021-23017", CVSS: 7.7, Part: "a", Vendor: "nginx", Product: "nginx", Version: "*", Update: "*", StartIncluding: "0.6.18", EndExcluding: "1.21.0"},
+		{CVE: "CVE-2023-38408", CVSS: 9.8, Part: "a", Vendor: "openbsd", Product: "openssh", Version: "*", Update: "*", EndExcluding: "9.3p2"},
+		{CVE: "CVE-TEST-RC", CVSS: 5.0, Part: "a", Vendor: "acme", Product: "widget", Version: "*", Update: "*", EndExcluding: "2.0"},
+	} {
+		db.add(entry)
+	}
+
+	tests := []struct {
+		cpe  string
+		want []string
+	}{
+		{"cpe:2.3:a:nginx:nginx:1.20.1", []string{"CVE-2021-23017", "CVE-2024-7347"}},
+		{"cpe:2.3:a:nginx:nginx:1.27.1", nil},
+		{"cpe:2.3:a:openbsd:openssh:9.3:p1", []string{"CVE-2023-38408"}},
+		{"cpe:2.3:a:openbsd:openssh:9.3:p2", nil},
+		{"cpe:2.3:a:acme:widget:2.0rc1", []string{"CVE-TEST-RC"}},
+		{"cpe:2.3:a:acme:widget:2.0", nil},
+		{"cpe:2.3:a:nginx:nginx", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, v := range db.Match([]models.Product{{Name: "product", CPE: tt.cpe}}) {
+			got = append(got, v.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Match(%s) = %v, want %v", tt.cpe, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// Limits on what Enumerate fetches
+const (
+	maxRedirects = 10
+	maxBody      = 1 << 20
+	maxTitle     = 200
+)
+
+var (
+	titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	linkPattern  = regexp.MustCompile(`(?is)<link\s[^>]*>`)
+	attrPattern  = regexp.MustCompile(`(?is)\b(rel|href)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+)
+
+// Options control HTTP enumeration
+type Options struct {
+	Host    string // Host header and TLS server name
+	Timeout time.Duration
+}
+
+// Enumerate fetches the root page of the HTTP or HTTPS service at address,
+// following redirects that stay on the same origin, then fetches the
+// favicon and asks for the allowed methods with OPTIONS. Every connection
+// goes to address whatever the Host.
+func Enumerate(address, scheme string, opts Options) *models.HTTPInfo {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return &models.HTTPInfo{Error: err.Error()}
+	}
+	host := opts.Host
+	if host == "" {
+		host, _, _ = net.SplitHostPort(address)
+	}
+
+	client := newClient(address, host, opts.Timeout)
+	defer client.CloseIdleConnections()
+
+	target := &url.URL{Scheme: scheme, Host: net.JoinHostPort(host, port), Path: "/"}
+	info := &models.HTTPInfo{URL: target.String()}
+
+	resp, body, err := fetchPage(client, target, info)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+
+	info.StatusCode = resp.StatusCode
+	info.Server = resp.Header.Get("Server")
+	info.PoweredBy = resp.Header.Get("X-Powered-By")
+	info.ContentType = resp.Header.Get("Content-Type")
+	info.ContentLength = resp.ContentLength
+	if info.ContentLength < 0 {
+		info.ContentLength = int64(len(body))
+	}
+	info.Title = pageTitle(body)
+
+	final := resp.Request.URL
+	if icon := faviconURL(final, body); icon != nil {
+		if data, err := get(client, icon); err == nil && len(data) > 0 {
+			hash := FaviconHash(data)
+			info.FaviconURL = icon.String()
+			info.FaviconHash = &hash
+		}
+	}
+	info.Methods = allowedMethods(client, final)
+
+	return info
+}
+
+// newClient builds a client that dials address for every request, skips
+// certificate verification and never follows redirects itself
+func newClient(address, host string, timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	if net.ParseIP(host) == nil {
+		tlsConfig.ServerName = host
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, address)
+			},
+			TLSClientConfig: tlsConfig,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// fetchPage GETs target, following same-origin redirects and recording
+// every hop in info.Redirects, and returns the last response with its body
+func fetchPage(client *http.Client, target *url.URL, info *models.HTTPInfo) (*http.Response, []byte, error) {
+	for {
+		resp, err := do(client, http.MethodGet, target)
+		if err != nil {
+			return nil, nil, err
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+		resp.Body.Close()
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode > 399 || location == "" {
+			return resp, body, nil
+		}
+
+		next, err := target.Parse(location)
+		if err != nil {
+			return resp, body, nil
+		}
+		info.Redirects = append(info.Redirects, models.HTTPRedirect{
+			URL:        target.String(),
+			StatusCode: resp.StatusCode,
+			Location:   next.String(),
+		})
+
+		// Other origins are other services, scanned on their own
+		if !sameOrigin(target, next) || len(info.Redirects) >= maxRedirects {
+			return resp, body, nil
+		}
+		target = next
+	}
+}
+
+// allowedMethods asks for the methods a URL allows with OPTIONS
+func allowedMethods(client *http.Client, target *url.URL) []string {
+	resp, err := do(client, http.MethodOptions, target)
+	if err != nil {
+		return nil
+	}
+	resp.Body.Close()
+
+	var methods []string
+	for _, method := range strings.Split(resp.Header.Get("Allow"), ",") {
+		if method = strings.ToUpper(strings.TrimSpace(method)); method != "" {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// get fetches a URL's body, failing on any status but 200
+func get(client *http.Client, target *url.URL) ([]byte, error) {
+	resp, err := do(client, http.MethodGet, target)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: status %d", target, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxBody))
+}
+
+// do sends a request with the scanner's User-Agent
+func do(client *http.Client, method string, target *url.URL) (*http.Response, error) {
+	req, err := http.NewRequest(method, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "go-scan")
+	return client.Do(req)
+}
+
+// pageTitle extracts the HTML title, unescaped and on one line, cut to
+// maxTitle bytes without splitting a character
+func pageTitle(body []byte) string {
+	match := titlePattern.FindSubmatch(body)
+	if match == nil {
+		return ""
+	}
+	title := strings.Join(strings.Fields(html.UnescapeString(string(match[1]))), " ")
+	if len(title) > maxTitle {
+		cut := maxTitle
+		for cut > 0 && !utf8.RuneStart(title[cut]) {
+			cut--
+		}
+		title = title[:cut]
+	}
+	return title
+}
+
+// faviconURL finds the icon a page links to, or /favicon.ico. Icons on
+// other origins are not fetched.
+func faviconURL(page *url.URL, body []byte) *url.URL {
+	for _, link := range linkPattern.FindAll(body, -1) {
+		var rel, href string
+		for _, attr := range attrPattern.FindAllSubmatch(link, -1) {
+			value := string(attr[2]) + string(attr[3]) + string(attr[4])
+			switch strings.ToLower(string(attr[1])) {
+			case "rel":
+				rel = strings.ToLower(value)
+			case "href":
+				href = html.UnescapeString(value)
+			}
+		}
+		if !strings.Contains(rel, "icon") || href == "" {
+			continue
+		}
+		if icon, err := page.Parse(href); err == nil && sameOrigin(page, icon) {
+			return icon
+		}
+	}
+	icon, _ := page.Parse("/favicon.ico")
+	return icon
+}
+
+// sameOrigin reports whether two URLs share scheme, host and port
+func sameOrigin(a, b *url.URL) bool {
+	return a.Scheme == b.Scheme && strings.EqualFold(a.Hostname(), b.Hostname()) && urlPort(a) == urlPort(b)
+}
+
+// urlPort is the port of a URL, defaulting by scheme
+func urlPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	if u.Scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
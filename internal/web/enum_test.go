@@ -0,0 +1,134 @@
+package web
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Sh4Ryuu/go-scan/internal/testutil"
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+func TestEnumerate(t *testing.T) {
+	icon := testutil.Fixture(t, "favicon.ico")
+	// The icon on another origin is passed over
+	page := `<html><head><TITLE lang="en">Acme &amp; Co
+		  Portal</TITLE>
+		<link rel="icon" href="https://cdn.example.com/icon.ico">
+		<link href='/static/icon.ico?v=2&amp;s=16' rel='Shortcut Icon'>
+		</head></html>`
+	// origin is the URL base the scan uses with the Host option, loud the
+	// same origin with the host in capitals
+	var origin, loud string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.EqualFold(r.Host, strings.TrimPrefix(origin, "http://")) || r.UserAgent() != "go-scan" {
+			t.Errorf("request for %s with User-Agent %q", r.Host, r.UserAgent())
+		}
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "/login", http.StatusFound)
+		case "/login":
+			// An absolute redirect, to the same origin spelt in capitals
+			http.Redirect(w, r, loud+"/home", http.StatusMovedPermanently)
+		case "/home":
+			if r.Method == http.MethodOptions {
+				w.Header().Set("Allow", "GET, head,OPTIONS, ,post")
+				return
+			}
+			w.Header().Set("Server", "nginx/1.25.3")
+			w.Header().Set("X-Powered-By", "PHP/8.2.12")
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, page)
+		case "/static/icon.ico":
+			if r.URL.RawQuery != "v=2&s=16" {
+				t.Errorf("icon query %q", r.URL.RawQuery)
+			}
+			w.Write(icon)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	address := server.Listener.Addr().String()
+	_, port, _ := net.SplitHostPort(address)
+	origin = "http://intranet.example:" + port
+	loud = "http://INTRANET.EXAMPLE:" + port
+
+	info := Enumerate(address, "http", Options{Host: "intranet.example", Timeout: 2 * time.Second})
+	hash := FaviconHash(icon)
+	want := &models.HTTPInfo{
+		URL:           origin + "/",
+		StatusCode:    http.StatusOK,
+		Title:         "Acme & Co Portal",
+		Server:        "nginx/1.25.3",
+		PoweredBy:     "PHP/8.2.12",
+		ContentType:   "text/html; charset=utf-8",
+		ContentLength: int64(len(page)),
+		Redirects: []models.HTTPRedirect{
+			{URL: origin + "/", StatusCode: http.StatusFound, Location: origin + "/login"},
+			{URL: origin + "/login", StatusCode: http.StatusMovedPermanently, Location: loud + "/home"},
+		},
+		FaviconURL:  loud + "/static/icon.ico?v=2&s=16",
+		FaviconHash: &hash,
+		Methods:     []string{"GET", "HEAD", "OPTIONS", "POST"},
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("got %+v\nwant %+v", info, want)
+	}
+}
+
+func TestEnumerateOtherOrigin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "https://www.example.com/", http.StatusMovedPermanently)
+		case "/favicon.ico":
+			http.NotFound(w, r)
+		default:
+			t.Errorf("followed the redirect to %s", r.URL)
+		}
+	}))
+	defer server.Close()
+	address := server.Listener.Addr().String()
+
+	// The redirect is recorded but not followed, and with no icon on the
+	// page and no /favicon.ico there is no favicon
+	info := Enumerate(address, "http", Options{Timeout: 2 * time.Second})
+	want := []models.HTTPRedirect{{URL: "http://" + address + "/", StatusCode: http.StatusMovedPermanently, Location: "https://www.example.com/"}}
+	if info.Error != "" || info.StatusCode != http.StatusMovedPermanently || !reflect.DeepEqual(info.Redirects, want) ||
+		info.FaviconHash != nil || info.Methods != nil {
+		t.Errorf("got %+v", info)
+	}
+
+	server.Close()
+	if info := Enumerate(address, "http", Options{Timeout: time.Second}); info.Error == "" {
+		t.Errorf("closed server: got %+v", info)
+	}
+}
+
+func TestPageTitle(t *testing.T) {
+	long := "a" + strings.Repeat("é", 150) // byte 200 falls inside a character
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"<title>Dashboard</title>", "Dashboard"},
+		{"<html><title>\n  Sign in &#8211; Grafana\n</title>", "Sign in – Grafana"},
+		{"<title></title>", ""},
+		{"<h1>No title</h1>", ""},
+		{"<title>" + strings.Repeat("x", 250) + "</title>", strings.Repeat("x", 200)},
+		{"<title>" + long + "</title>", "a" + strings.Repeat("é", 99)},
+	}
+	for _, tt := range tests {
+		got := pageTitle([]byte(tt.body))
+		if got != tt.want || !utf8.ValidString(got) {
+			t.Errorf("pageTitle(%.40q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
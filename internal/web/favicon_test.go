@@ -0,0 +1,68 @@
+package web
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Sh4Ryuu/go-scan/internal/testutil"
+)
+
+func TestMurmur3(t *testing.T) {
+	// Published MurmurHash3 x86 32-bit vectors, seed 0
+	tests := []struct {
+		data string
+		want uint32
+	}{
+		{"", 0},
+		{"foo", 0xf6a5c420},   // mmh3.hash("foo") = -156908512
+		{"hello", 0x248bfa47}, // 613153351
+		{"The quick brown fox jumps over the lazy dog", 0x2e4ff723},
+	}
+	for _, tt := range tests {
+		if got := murmur3([]byte(tt.data)); got != tt.want {
+			t.Errorf("murmur3(%q) = %#x, want %#x", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestFaviconHash(t *testing.T) {
+	// The hash Shodan's http.favicon.hash gives, as computed by
+	// mmh3.hash(codecs.encode(icon, "base64")) for this 1150-byte icon
+	icon := testutil.Fixture(t, "favicon.ico")
+	if got := FaviconHash(icon); got != -596419152 {
+		t.Errorf("FaviconHash = %d, want -596419152", got)
+	}
+	if got := FaviconHash(nil); got != 0 {
+		t.Errorf("FaviconHash(nil) = %d, want 0", got)
+	}
+
+	// Lines are 76 characters, the last one too when it is full
+	tests := []struct {
+		size  int
+		lines []int
+	}{
+		{1, []int{4}},
+		{57, []int{76}},
+		{58, []int{76, 4}},
+		{114, []int{76, 76}},
+	}
+	for _, tt := range tests {
+		encoded := encodeLines(make([]byte, tt.size))
+		var lengths []int
+		for _, line := range strings.SplitAfter(encoded, "\n") {
+			if line != "" {
+				lengths = append(lengths, len(strings.TrimSuffix(line, "\n")))
+			}
+		}
+		if !strings.HasSuffix(encoded, "\n") || len(lengths) != len(tt.lines) {
+			t.Errorf("%d bytes: got lines of %v, want %v", tt.size, lengths, tt.lines)
+			continue
+		}
+		for i := range lengths {
+			if lengths[i] != tt.lines[i] {
+				t.Errorf("%d bytes: got lines of %v, want %v", tt.size, lengths, tt.lines)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package check is the plugin API for protocol checks. A check declares
+// which open ports it applies to and how to test them; the scanner hands
+// every open port to the matching registered checks and records what they
+// find in ScanResult.Findings.
+//
+// Checks shipped in other packages register themselves from init:
+//
+//	func init() {
+//		check.Register(check.Func(check.Info{
+//			Name:  "acme-admin-exposed",
+//			Ports: []int{8000},
+//		}, runAdminCheck))
+//	}
+//
+// and are built in by importing the package for its side effects.
+package check
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// Protocols a check can apply to
+const (
+	TCP = "tcp"
+	UDP = "udp"
+)
+
+// Check tests an open port
+type Check interface {
+	// Info names the check and declares the ports it applies to
+	Info() Info
+
+	// Run tests target and returns what it found. ctx carries the check's
+	// deadline; Run must return once it is done. An error means the check
+	// could not complete, not that the target passed.
+	Run(ctx context.Context, target *Target) ([]models.Finding, error)
+}
+
+// Info describes a check. A check applies to an open port of its protocol
+// when the port is one of Ports, or when the service, banner or Match
+// condition selects it on any port.
+type Info struct {
+	Name        string // unique, e.g. "redis-no-auth"
+	Description string
+	Protocol    string        // TCP (default) or UDP
+	Timeout     time.Duration // overrides RunOptions.Timeout when set
+
+	Ports    []int                     // default ports of the service
+	Services []string                  // ScanResult.Service values
+	Banner   *regexp.Regexp            // matched against ScanResult.Banner
+	Match    func(target *Target) bool // any other condition
+}
+
+// Target is an open port handed to a check
+type Target struct {
+	Host       string // as given to the scanner
+	Address    string // host:port to connect to
+	Port       int
+	Protocol   string
+	TLS        bool   // the port speaks TLS from the first byte
+	ServerName string // name to send as SNI
+
+	// Result is what the scan found on the port so far: banner, service,
+	// products, certificate. Checks must not modify it.
+	Result *models.ScanResult
+}
+
+// Matches reports whether a check with this info applies to target
+func (info Info) Matches(target *Target) bool {
+	protocol := info.Protocol
+	if protocol == "" {
+		protocol = TCP
+	}
+	if protocol != target.Protocol {
+		return false
+	}
+
+	for _, port := range info.Ports {
+		if port == target.Port {
+			return true
+		}
+	}
+	if target.Result != nil {
+		for _, service := range info.Services {
+			if service == target.Result.Service {
+				return true
+			}
+		}
+		if info.Banner != nil && target.Result.Banner != "" && info.Banner.MatchString(target.Result.Banner) {
+			return true
+		}
+	}
+	return info.Match != nil && info.Match(target)
+}
+
+// RunFunc is the signature of Check.Run
+type RunFunc func(ctx context.Context, target *Target) ([]models.Finding, error)
+
+// Func makes a check from its info and run function
+func Func(info Info, run RunFunc) Check {
+	return &funcCheck{info: info, run: run}
+}
+
+// funcCheck is a check made by Func
+type funcCheck struct {
+	info Info
+	run  RunFunc
+}
+
+func (c *funcCheck) Info() Info { return c.info }
+
+func (c *funcCheck) Run(ctx context.Context, target *Target) ([]models.Finding, error) {
+	return c.run(ctx, target)
+}
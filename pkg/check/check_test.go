@@ -0,0 +1,58 @@
+package check
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+func TestInfoMatches(t *testing.T) {
+	target := func(port int, protocol, service, banner string) *Target {
+		return &Target{
+			Port:     port,
+			Protocol: protocol,
+			Result:   &models.ScanResult{Port: port, Protocol: protocol, Service: service, Banner: banner},
+		}
+	}
+	redis := Info{
+		Name:     "redis",
+		Ports:    []int{6379},
+		Services: []string{"redis"},
+		Banner:   regexp.MustCompile(`^-NOAUTH`),
+	}
+
+	tests := []struct {
+		name   string
+		info   Info
+		target *Target
+		want   bool
+	}{
+		{"port", redis, target(6379, TCP, "", ""), true},
+		{"service on another port", redis, target(7000, TCP, "redis", ""), true},
+		{"banner on another port", redis, target(7000, TCP, "", "-NOAUTH Authentication required."), true},
+		{"nothing matches", redis, target(7000, TCP, "http", "HTTP/1.1 200 OK"), false},
+		{"protocol defaults to TCP", redis, target(6379, UDP, "", ""), false},
+		{"UDP check", Info{Protocol: UDP, Ports: []int{161}}, target(161, UDP, "", ""), true},
+		{"UDP check on TCP", Info{Protocol: UDP, Ports: []int{161}}, target(161, TCP, "", ""), false},
+		{"no result", Info{Services: []string{"redis"}}, &Target{Port: 7000, Protocol: TCP}, false},
+		{"empty banner", Info{Banner: regexp.MustCompile(`.*`)}, target(7000, TCP, "", ""), false},
+		{
+			"match function",
+			Info{Match: func(t *Target) bool { return t.TLS }},
+			&Target{Port: 8443, Protocol: TCP, TLS: true},
+			true,
+		},
+		{
+			"match function declines",
+			Info{Match: func(t *Target) bool { return t.TLS }},
+			&Target{Port: 8443, Protocol: TCP},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.info.Matches(tt.target); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
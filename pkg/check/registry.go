@@ -0,0 +1,169 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// Defaults for RunOptions
+const (
+	DefaultTimeout     = 5 * time.Second
+	DefaultConcurrency = 4
+)
+
+// Registry holds checks by name
+type Registry struct {
+	mu     sync.RWMutex
+	checks []Check
+	names  map[string]bool
+}
+
+// Default is the registry that Register adds to and the scanner runs
+var Default = NewRegistry()
+
+// Register adds a check to the default registry. It panics if the check
+// is invalid or its name is taken, since both are programming errors.
+func Register(c Check) {
+	if err := Default.Register(c); err != nil {
+		panic(err)
+	}
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// Register adds a check
+func (r *Registry) Register(c Check) error {
+	info := c.Info()
+	if info.Name == "" {
+		return fmt.Errorf("check: empty name")
+	}
+	switch info.Protocol {
+	case "", TCP, UDP:
+	default:
+		return fmt.Errorf("check %s: unknown protocol %q", info.Name, info.Protocol)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[info.Name] {
+		return fmt.Errorf("check %s: already registered", info.Name)
+	}
+	r.names[info.Name] = true
+	r.checks = append(r.checks, c)
+	return nil
+}
+
+// Checks lists the registered checks in registration order
+func (r *Registry) Checks() []Check {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Check(nil), r.checks...)
+}
+
+// Match lists the checks that apply to target
+func (r *Registry) Match(target *Target) []Check {
+	var matched []Check
+	for _, c := range r.Checks() {
+		if c.Info().Matches(target) {
+			matched = append(matched, c)
+		}
+	}
+	return matched
+}
+
+// RunOptions control how checks are run against a target
+type RunOptions struct {
+	Timeout     time.Duration // per check; 0 means DefaultTimeout
+	Concurrency int           // checks run at once; 0 means DefaultConcurrency
+}
+
+// outcome is what one check run produced
+type outcome struct {
+	findings []models.Finding
+	err      error
+}
+
+// Run runs the matching checks against target, several at a time, and
+// returns their findings in registration order, each tagged with its
+// check's name. Checks that fail, panic or overrun their timeout are
+// reported as errors; one that ignores its deadline is abandoned.
+func (r *Registry) Run(ctx context.Context, target *Target, opts RunOptions) ([]models.Finding, []models.CheckError) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+
+	checks := r.Match(target)
+	outcomes := make([]outcome, len(checks))
+	slots := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, c Check) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			timeout := opts.Timeout
+			if t := c.Info().Timeout; t > 0 {
+				timeout = t
+			}
+			outcomes[i] = runOne(ctx, c, target, timeout)
+		}(i, c)
+	}
+	wg.Wait()
+
+	var findings []models.Finding
+	var errs []models.CheckError
+	for i, c := range checks {
+		name := c.Info().Name
+		if outcomes[i].err != nil {
+			errs = append(errs, models.CheckError{Check: name, Error: outcomes[i].err.Error()})
+		}
+		for _, finding := range outcomes[i].findings {
+			if finding.Check == "" {
+				finding.Check = name
+			}
+			findings = append(findings, finding)
+		}
+	}
+	return findings, errs
+}
+
+// runOne runs a check with a timeout, recovering from panics
+func runOne(ctx context.Context, c Check, target *Target, timeout time.Duration) outcome {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				done <- outcome{err: fmt.Errorf("panic: %v", v)}
+			}
+		}()
+		findings, err := c.Run(ctx, target)
+		done <- outcome{findings: findings, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+		// Give a check that honours ctx a moment to return its findings
+		select {
+		case result := <-done:
+			return result
+		case <-time.After(100 * time.Millisecond):
+			return outcome{err: fmt.Errorf("timed out after %v", timeout)}
+		}
+	}
+}
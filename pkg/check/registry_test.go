@@ -0,0 +1,174 @@
+package check
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Sh4Ryuu/go-scan/pkg/models"
+)
+
+// finds is a check on port 80 that reports one finding with the given ID
+func finds(name, id string) Check {
+	return Func(Info{Name: name, Ports: []int{80}}, func(ctx context.Context, target *Target) ([]models.Finding, error) {
+		return []models.Finding{{ID: id}}, nil
+	})
+}
+
+var web = &Target{Host: "example.com", Address: "192.0.2.10:80", Port: 80, Protocol: TCP}
+
+func TestRegister(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(finds("a", "a1")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		check Check
+		want  string
+	}{
+		{finds("a", "a2"), "check a: already registered"},
+		{finds("", "x"), "check: empty name"},
+		{Func(Info{Name: "b", Protocol: "sctp"}, nil), `check b: unknown protocol "sctp"`},
+	}
+	for _, tt := range tests {
+		if err := r.Register(tt.check); err == nil || err.Error() != tt.want {
+			t.Errorf("got error %v, want %q", err, tt.want)
+		}
+	}
+	if n := len(r.Checks()); n != 1 {
+		t.Errorf("%d checks registered, want 1", n)
+	}
+
+	// Register panics on the default registry, as init-time collisions are bugs
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a duplicate did not panic")
+		}
+	}()
+	Register(finds("check-test-duplicate", "x"))
+	Register(finds("check-test-duplicate", "x"))
+}
+
+func TestMatch(t *testing.T) {
+	r := NewRegistry()
+	for _, c := range []Check{
+		finds("web", "w"),
+		Func(Info{Name: "ssh", Ports: []int{22}}, nil),
+		Func(Info{Name: "any", Match: func(*Target) bool { return true }}, nil),
+	} {
+		if err := r.Register(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var names []string
+	for _, c := range r.Match(web) {
+		names = append(names, c.Info().Name)
+	}
+	if strings.Join(names, " ") != "web any" {
+		t.Errorf("matched %v", names)
+	}
+}
+
+func TestRunOrder(t *testing.T) {
+	r := NewRegistry()
+	// The first check finishes last, yet its findings come first
+	r.Register(Func(Info{Name: "slow", Ports: []int{80}}, func(ctx context.Context, target *Target) ([]models.Finding, error) {
+		time.Sleep(50 * time.Millisecond)
+		return []models.Finding{{ID: "slow-1"}, {ID: "slow-2"}}, nil
+	}))
+	r.Register(finds("fast", "fast-1"))
+	r.Register(Func(Info{Name: "tagged", Ports: []int{80}}, func(ctx context.Context, target *Target) ([]models.Finding, error) {
+		return []models.Finding{{ID: "tagged-1", Check: "plugin:tagged"}}, nil
+	}))
+	r.Register(Func(Info{Name: "failed", Ports: []int{80}}, func(ctx context.Context, target *Target) ([]models.Finding, error) {
+		return []models.Finding{{ID: "partial"}}, errors.New("connection reset")
+	}))
+	r.Register(Func(Info{Name: "ssh", Ports: []int{22}}, func(ctx context.Context, target *Target) ([]models.Finding, error) {
+		return []models.Finding{{ID: "never"}}, nil
+	}))
+
+	findings, errs := r.Run(context.Background(), web, RunOptions{})
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.Check+"/"+f.ID)
+	}
+	want := "slow/slow-1 slow/slow-2 fast/fast-1 plugin:tagged/tagged-1 failed/partial"
+	if strings.Join(got, " ") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+	if len(errs) != 1 || errs[0] != (models.CheckError{Check: "failed", Error: "connection reset"}) {
+		t.Errorf("errors %+v", errs)
+	}
+}
+
+func TestRunFailures(t *testing.T) {
+	r := NewRegistry()
+	r.Register(Func(Info{Name: "panics", Ports: []int{80}}, func(ctx context.Context, target *Target) ([]models.Finding, error) {
+		panic("nil map")
+	}))
+	r.Register(Func(Info{Name: "honours-deadline", Ports: []int{80}}, func(ctx context.Context, target *Target) ([]models.Finding, error) {
+		<-ctx.Done()
+		return []models.Finding{{ID: "late"}}, nil
+	}))
+	stuck := make(chan struct{})
+	defer close(stuck)
+	r.Register(Func(Info{Name: "ignores-deadline", Ports: []int{80}}, func(ctx context.Context, target *Target) ([]models.Finding, error) {
+		<-stuck
+		return nil, nil
+	}))
+	r.Register(Func(Info{Name: "own-timeout", Ports: []int{80}, Timeout: 10 * time.Millisecond}, func(ctx context.Context, target *Target) ([]models.Finding, error) {
+		deadline, _ := ctx.Deadline()
+		if time.Until(deadline) > 10*time.Millisecond {
+			return nil, errors.New("got the run timeout")
+		}
+		return nil, nil
+	}))
+
+	start := time.Now()
+	findings, errs := r.Run(context.Background(), web, RunOptions{Timeout: 50 * time.Millisecond})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("run took %v", elapsed)
+	}
+	if len(findings) != 1 || findings[0].ID != "late" || findings[0].Check != "honours-deadline" {
+		t.Errorf("findings %+v", findings)
+	}
+	want := []models.CheckError{
+		{Check: "panics", Error: "panic: nil map"},
+		{Check: "ignores-deadline", Error: "timed out after 50ms"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("errors %+v, want %+v", errs, want)
+	}
+	for i := range want {
+		if errs[i] != want[i] {
+			t.Errorf("error %d: got %+v, want %+v", i, errs[i], want[i])
+		}
+	}
+}
+
+func TestRunConcurrency(t *testing.T) {
+	r := NewRegistry()
+	var running, peak atomic.Int32
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		r.Register(Func(Info{Name: name, Ports: []int{80}}, func(ctx context.Context, target *Target) ([]models.Finding, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return nil, nil
+		}))
+	}
+	r.Run(context.Background(), web, RunOptions{Concurrency: 2})
+	if p := peak.Load(); p != 2 {
+		t.Errorf("%d checks ran at once, want 2", p)
+	}
+}
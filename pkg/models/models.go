@@ -6,16 +6,154 @@ import (
 
 // ScanResult represents a single port scan result
 type ScanResult struct {
-	Host        string       `json:"host"`
-	Port        int          `json:"port"`
-	Protocol    string       `json:"protocol"` // "tcp" or "udp"
-	Status      string       `json:"status"`   // "open", "closed", "filtered"
-	Service     string       `json:"service,omitempty"`
-	Banner      string       `json:"banner,omitempty"`
-	IsSSL       bool         `json:"is_ssl"`
-	SSLInfo     *SSLCertInfo `json:"ssl_info,omitempty"`
-	Geolocation *GeoLocation `json:"geolocation,omitempty"`
-	Severity    string       `json:"severity,omitempty"`
+	Host              string             `json:"host"`
+	Port              int                `json:"port"`
+	Protocol          string             `json:"protocol"` // "tcp" or "udp"
+	Status            string             `json:"status"`   // "open", "closed", "filtered"
+	Service           string             `json:"service,omitempty"`
+	Banner            string             `json:"banner,omitempty"`               // printable, other bytes escaped as \xNN
+	BannerRaw         []byte             `json:"banner_raw,omitempty"`           // as received, base64 in JSON
+	BannerProbe       string             `json:"banner_probe,omitempty"`         // active probe that drew the banner, if any
+	BannerFirstByteMs float64            `json:"banner_first_byte_ms,omitempty"` // time to the first banner byte
+	IsSSL             bool               `json:"is_ssl"`
+	SSLInfo           *SSLCertInfo       `json:"ssl_info,omitempty"`
+	Geolocation       *GeoLocation       `json:"geolocation,omitempty"`
+	Severity          string             `json:"severity,omitempty"` // highest finding severity
+	NmapResults       []NmapScriptResult `json:"nmap_results,omitempty"`
+	TLS               *TLSEnumeration    `json:"tls,omitempty"`
+	HTTP              *HTTPInfo          `json:"http,omitempty"`
+	SSH               *SSHInfo           `json:"ssh,omitempty"`
+	SMB               *SMBInfo           `json:"smb,omitempty"`
+	Products          []Product          `json:"products,omitempty"`
+	Vulnerabilities   []Vulnerability    `json:"vulnerabilities,omitempty"`
+	Findings          []Finding          `json:"findings,omitempty"`
+	CheckErrors       []CheckError       `json:"check_errors,omitempty"`
+}
+
+// HTTPInfo describes the web service on a port
+type HTTPInfo struct {
+	URL           string         `json:"url"`
+	StatusCode    int            `json:"status_code,omitempty"`
+	Title         string         `json:"title,omitempty"`
+	Server        string         `json:"server,omitempty"`
+	PoweredBy     string         `json:"powered_by,omitempty"` // X-Powered-By
+	ContentType   string         `json:"content_type,omitempty"`
+	ContentLength int64          `json:"content_length"`
+	Redirects     []HTTPRedirect `json:"redirects,omitempty"`
+	FaviconURL    string         `json:"favicon_url,omitempty"`
+	FaviconHash   *int32         `json:"favicon_hash,omitempty"` // MurmurHash3, as used by Shodan
+	Methods       []string       `json:"methods,omitempty"`      // from the OPTIONS Allow header
+	Error         string         `json:"error,omitempty"`
+}
+
+// HTTPRedirect is one hop of a redirect chain
+type HTTPRedirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+}
+
+// SSHInfo describes an SSH server: its identification string, the
+// algorithms its KEXINIT offers, and its host keys
+type SSHInfo struct {
+	VersionString   string `json:"version_string,omitempty"` // e.g. "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13"
+	ProtocolVersion string `json:"protocol_version,omitempty"`
+	Software        string `json:"software,omitempty"`
+	Comments        string `json:"comments,omitempty"`
+
+	KexAlgorithms             []string `json:"kex_algorithms,omitempty"`
+	HostKeyAlgorithms         []string `json:"host_key_algorithms,omitempty"`
+	CiphersClientToServer     []string `json:"ciphers_client_to_server,omitempty"`
+	CiphersServerToClient     []string `json:"ciphers_server_to_client,omitempty"`
+	MACsClientToServer        []string `json:"macs_client_to_server,omitempty"`
+	MACsServerToClient        []string `json:"macs_server_to_client,omitempty"`
+	CompressionClientToServer []string `json:"compression_client_to_server,omitempty"`
+	CompressionServerToClient []string `json:"compression_server_to_client,omitempty"`
+
+	HostKeys []SSHHostKey `json:"host_keys,omitempty"`
+	Error    string       `json:"error,omitempty"`
+}
+
+// SSHHostKey is a host key taken from a key exchange
+type SSHHostKey struct {
+	Type              string `json:"type"` // ssh-ed25519, ecdsa-sha2-nistp256, ssh-rsa, ...
+	Bits              int    `json:"bits,omitempty"`
+	FingerprintSHA256 string `json:"fingerprint_sha256"` // as printed by ssh-keygen -l
+	FingerprintMD5    string `json:"fingerprint_md5"`
+}
+
+// SMBInfo describes an SMB server from its NEGOTIATE replies and the
+// NTLMSSP challenge of an anonymous session setup
+type SMBInfo struct {
+	Dialects        []string   `json:"dialects,omitempty"` // SMB2/3 dialects accepted, e.g. "3.1.1"
+	Dialect         string     `json:"dialect,omitempty"`  // highest, chosen when all are offered
+	SMB1            bool       `json:"smb1"`               // the NT LM 0.12 dialect is accepted
+	SigningEnabled  bool       `json:"signing_enabled"`
+	SigningRequired bool       `json:"signing_required"`
+	ServerGUID      string     `json:"server_guid,omitempty"`
+	Capabilities    []string   `json:"capabilities,omitempty"`
+	Cipher          string     `json:"cipher,omitempty"` // SMB 3.1.1 encryption cipher
+	SystemTime      *time.Time `json:"system_time,omitempty"`
+
+	OS                  string `json:"os,omitempty"`         // e.g. "Windows 10 / Server 2016 or later"
+	OSVersion           string `json:"os_version,omitempty"` // major.minor.build from NTLMSSP
+	NativeOS            string `json:"native_os,omitempty"`  // SMB1 session setup only
+	NativeLanManager    string `json:"native_lan_manager,omitempty"`
+	NetBIOSComputerName string `json:"netbios_computer_name,omitempty"`
+	NetBIOSDomainName   string `json:"netbios_domain_name,omitempty"`
+	DNSComputerName     string `json:"dns_computer_name,omitempty"`
+	DNSDomainName       string `json:"dns_domain_name,omitempty"`
+	DNSTreeName         string `json:"dns_tree_name,omitempty"` // forest
+	Error               string `json:"error,omitempty"`
+}
+
+// Product is software identified on a port
+type Product struct {
+	Name    string `json:"name"` // display name, e.g. "OpenSSH"
+	Vendor  string `json:"vendor"`
+	Product string `json:"product"`
+	Version string `json:"version,omitempty"`
+	Service string `json:"service,omitempty"`
+	CPE     string `json:"cpe"`    // CPE 2.3 formatted string
+	Source  string `json:"source"` // what it was identified from: banner, http_server, ...
+}
+
+// Vulnerability is a CVE that likely affects an identified product
+type Vulnerability struct {
+	ID          string  `json:"id"`      // e.g. "CVE-2024-6387"
+	Product     string  `json:"product"` // name and version, e.g. "OpenSSH 9.6p1"
+	CPE         string  `json:"cpe"`
+	CVSS        float64 `json:"cvss,omitempty"` // base score
+	CVSSVersion string  `json:"cvss_version,omitempty"`
+	Severity    string  `json:"severity"`
+	Summary     string  `json:"summary,omitempty"`
+}
+
+// Finding is a weakness detected on a port
+type Finding struct {
+	ID       string `json:"id"` // stable identifier, e.g. "tls-weak-key"
+	Title    string `json:"title"`
+	Severity string `json:"severity"`
+	Detail   string `json:"detail,omitempty"`
+	Evidence string `json:"evidence,omitempty"` // what the check saw, e.g. a directory listing
+	Check    string `json:"check,omitempty"`    // plugin check that reported it
+}
+
+// CheckError records a plugin check that failed or timed out on a port
+type CheckError struct {
+	Check string `json:"check"`
+	Error string `json:"error"`
+}
+
+// AddFindings records findings and raises the result severity to the
+// highest finding severity
+func (r *ScanResult) AddFindings(findings ...Finding) {
+	for _, finding := range findings {
+		r.Findings = append(r.Findings, finding)
+		if SeverityRank(finding.Severity) > SeverityRank(r.Severity) {
+			r.Severity = finding.Severity
+		}
+	}
 }
 
 // SSLCertInfo contains SSL/TLS certificate information
@@ -27,8 +165,117 @@ type SSLCertInfo struct {
 	DNSNames           []string  `json:"dns_names,omitempty"`
 	IsExpired          bool      `json:"is_expired"`
 	Fingerprint        string    `json:"fingerprint"`
+	PublicKeyHash      string    `json:"public_key_hash,omitempty"` // SHA-256 of the SubjectPublicKeyInfo
 	PublicKeyBits      int       `json:"public_key_bits"`
+	PublicKeyAlgorithm string    `json:"public_key_algorithm,omitempty"` // RSA, ECDSA, Ed25519
+	SelfSigned         bool      `json:"self_signed"`
 	SignatureAlgorithm string    `json:"signature_algorithm"`
+	TLSVersion         string    `json:"tls_version,omitempty"`  // negotiated, e.g. "TLS 1.3"
+	CipherSuite        string    `json:"cipher_suite,omitempty"` // negotiated, IANA name
+	ALPN               string    `json:"alpn,omitempty"`         // negotiated application protocol, e.g. "h2"
+	StartTLS           string    `json:"starttls,omitempty"`     // protocol used to upgrade, if any
+	JARM               string    `json:"jarm,omitempty"`
+	JARMLabel          string    `json:"jarm_label,omitempty"` // known software for the JARM fingerprint
+
+	// Chain holds every certificate the server presented, leaf first
+	Chain      []ChainCert     `json:"chain,omitempty"`
+	Validation *CertValidation `json:"validation,omitempty"`
+
+	ServerName string      `json:"server_name,omitempty"` // SNI sent, if any
+	VHosts     []VHostCert `json:"vhosts,omitempty"`
+
+	// Revocation and Certificate Transparency metadata, parsed offline
+	OCSPServers           []string    `json:"ocsp_servers,omitempty"`
+	CRLDistributionPoints []string    `json:"crl_distribution_points,omitempty"`
+	IssuingCertificateURL []string    `json:"issuing_certificate_url,omitempty"`
+	MustStaple            bool        `json:"must_staple,omitempty"` // TLS feature extension requires a staple
+	OCSPStapled           bool        `json:"ocsp_stapled"`
+	OCSPStaple            *OCSPStaple `json:"ocsp_staple,omitempty"`
+	SCTs                  []SCT       `json:"scts,omitempty"`
+}
+
+// OCSPStaple is a parsed stapled OCSP response. The responder signature is
+// not checked.
+type OCSPStaple struct {
+	ResponseStatus   string     `json:"response_status"`       // successful, tryLater, ...
+	CertStatus       string     `json:"cert_status,omitempty"` // good, revoked or unknown
+	ProducedAt       time.Time  `json:"produced_at,omitempty"`
+	ThisUpdate       time.Time  `json:"this_update,omitempty"`
+	NextUpdate       *time.Time `json:"next_update,omitempty"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevocationReason string     `json:"revocation_reason,omitempty"`
+	Error            string     `json:"error,omitempty"` // set when the response could not be parsed
+}
+
+// SCT is a Certificate Transparency signed certificate timestamp
+type SCT struct {
+	Source    string    `json:"source"`  // tls_extension, certificate or ocsp
+	Version   int       `json:"version"` // 1 for RFC 6962 SCTs
+	LogID     string    `json:"log_id"`  // base64, as CT logs publish it
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// VHostCert is the certificate returned when a name is sent as SNI
+type VHostCert struct {
+	Name        string `json:"name"`
+	Subject     string `json:"subject,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Different   bool   `json:"different"`    // differs from the default certificate
+	NameMatches bool   `json:"name_matches"` // certificate is valid for the name
+	Error       string `json:"error,omitempty"`
+}
+
+// ChainCert summarizes one certificate of a presented chain
+type ChainCert struct {
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	Fingerprint string    `json:"fingerprint"`
+	ValidTo     time.Time `json:"valid_to"`
+	IsCA        bool      `json:"is_ca"`
+	Raw         []byte    `json:"raw,omitempty"` // DER encoding
+}
+
+// Certificate validation verdicts
+const (
+	CertVerdictOK                  = "ok"
+	CertVerdictUntrustedRoot       = "untrusted_root"
+	CertVerdictMissingIntermediate = "missing_intermediate"
+	CertVerdictHostnameMismatch    = "hostname_mismatch"
+	CertVerdictExpired             = "expired"
+	CertVerdictNotYetValid         = "not_yet_valid"
+	CertVerdictInvalid             = "invalid"
+)
+
+// CertValidation is the outcome of verifying a presented chain against
+// the trusted roots and the scanned hostname
+type CertValidation struct {
+	Trusted bool   `json:"trusted"`
+	Verdict string `json:"verdict"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// TLSEnumeration lists the protocol versions, cipher suites and key
+// exchange groups a TLS server accepts
+type TLSEnumeration struct {
+	Versions []TLSVersionInfo `json:"versions"`
+	Groups   []string         `json:"groups,omitempty"`
+	Grade    string           `json:"grade"` // A (best) to F
+	Warnings []string         `json:"warnings,omitempty"`
+}
+
+// TLSVersionInfo lists the cipher suites accepted for one version, in the
+// server's preference order when ServerPreference is set
+type TLSVersionInfo struct {
+	Version          string      `json:"version"`
+	Ciphers          []TLSCipher `json:"ciphers"`
+	ServerPreference bool        `json:"server_preference"`
+}
+
+// TLSCipher is an accepted cipher suite
+type TLSCipher struct {
+	ID     uint16 `json:"id"`
+	Name   string `json:"name"`
+	Rating string `json:"rating"` // strong, legacy, weak or insecure
 }
 
 // ScanStats contains scan statistics
@@ -65,7 +312,53 @@ type NmapScriptResult struct {
 	Port     int           `json:"port"`
 	Protocol string        `json:"protocol"`
 	Output   string        `json:"output"`
-	Status   string        `json:"status"` // "success" or "error"
+	Status   string        `json:"status"` // "success", "error" or "skipped"
 	Error    string        `json:"error,omitempty"`
 	Duration time.Duration `json:"duration"`
 }
+
+// ScanReport bundles the results and statistics of a single scan run
+type ScanReport struct {
+	ID      string       `json:"id,omitempty"`
+	Host    string       `json:"host"`
+	Results []ScanResult `json:"results"`
+	Stats   *ScanStats   `json:"stats,omitempty"`
+}
+
+// OpenResults returns the results whose status is "open"
+func (r *ScanReport) OpenResults() []ScanResult {
+	var open []ScanResult
+	for _, result := range r.Results {
+		if result.Status == "open" {
+			open = append(open, result)
+		}
+	}
+	return open
+}
+
+// Severity levels, lowest first
+const (
+	SeverityInfo     = "info"
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// SeverityRank orders severity levels; unknown or empty levels rank lowest
+func SeverityRank(severity string) int {
+	switch severity {
+	case SeverityInfo:
+		return 1
+	case SeverityLow:
+		return 2
+	case SeverityMedium:
+		return 3
+	case SeverityHigh:
+		return 4
+	case SeverityCritical:
+		return 5
+	default:
+		return 0
+	}
+}